@@ -0,0 +1,445 @@
+package framework
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/river-now/river/kit/htmlutil"
+)
+
+const (
+	RiverCriticalCSSJSONFileName = "river_critical_css.json"
+	RouteCriticalCSSElementID    = "river-critical-css"
+)
+
+// Rules are the kept rules of the non-critical bundle, in source order, each
+// re-wrapped in its enclosing conditional group rules (e.g., "@media (...)
+// {.a{...}}"). Each pattern maps to the indices of the rules its route uses.
+type routeCriticalCSS struct {
+	Rules     []string         `json:"rules"`
+	ByPattern map[string][]int `json:"byPattern"`
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// BUILD TIME
+/////////////////////////////////////////////////////////////////////
+
+// River doesn't prerender route HTML, so a route's "content" is everything
+// its markup can come from: the root HTML template, the client entry and its
+// deps, and the route's own module and deps (all from the stage two paths
+// file). A rule is kept for a route if any of its selectors only references
+// classes, IDs, and element names that appear somewhere in that content.
+func (h *River) extractRouteCriticalCSS(pf *PathsFile) error {
+	cssPath, err := h.Wave.GetStyleSheetPathBuildtime()
+	if err != nil {
+		return fmt.Errorf("error getting stylesheet path: %w", err)
+	}
+	if cssPath == "" {
+		Log.Warn("ExtractCriticalCSS is enabled, but there is no non-critical CSS entry")
+		return nil
+	}
+	css, err := os.ReadFile(cssPath)
+	if err != nil {
+		return fmt.Errorf("error reading stylesheet: %w", err)
+	}
+
+	templateContent, err := os.ReadFile(path.Join(h.Wave.GetPrivateStaticDir(), h.Wave.GetRiverHTMLTemplateLocation()))
+	if err != nil {
+		return fmt.Errorf("error reading HTML template file: %w", err)
+	}
+
+	publicOutDir := h.Wave.GetStaticPublicOutDir()
+	readPublicFiles := func(names ...string) ([]byte, error) {
+		var content []byte
+		for _, name := range names {
+			if name == "" {
+				continue
+			}
+			b, err := os.ReadFile(filepath.Join(publicOutDir, name))
+			if err != nil {
+				return nil, err
+			}
+			content = append(content, b...)
+			content = append(content, '\n')
+		}
+		return content, nil
+	}
+
+	baseContent, err := readPublicFiles(append([]string{pf.ClientEntryOut}, pf.ClientEntryDeps...)...)
+	if err != nil {
+		return fmt.Errorf("error reading client entry: %w", err)
+	}
+	baseContent = append(baseContent, templateContent...)
+
+	tokensByPattern := make(map[string]map[string]struct{}, len(pf.Paths))
+	for pattern, p := range pf.Paths {
+		routeContent, err := readPublicFiles(append([]string{p.OutPath}, p.Deps...)...)
+		if err != nil {
+			return fmt.Errorf("error reading modules for route %s: %w", pattern, err)
+		}
+		tokensByPattern[pattern] = get_content_tokens(append(routeContent, baseContent...))
+	}
+	result := select_route_critical_css(flatten_css_rules(string(css), ""), tokensByPattern)
+
+	asJSON, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("error marshalling route critical CSS: %w", err)
+	}
+	out := filepath.Join(h.Wave.GetStaticPrivateOutDir(), "river_out", RiverCriticalCSSJSONFileName)
+	if err := os.WriteFile(out, asJSON, os.ModePerm); err != nil {
+		return fmt.Errorf("error writing route critical CSS: %w", err)
+	}
+
+	Log.Info("Extracted route critical CSS", "routes", len(pf.Paths), "rules", len(result.Rules))
+	return nil
+}
+
+// Only the rules that at least one route uses are kept, and the per-pattern
+// indices point into that reduced list.
+func select_route_critical_css(rules []cssLeafRule, tokensByPattern map[string]map[string]struct{}) routeCriticalCSS {
+	result := routeCriticalCSS{ByPattern: make(map[string][]int, len(tokensByPattern))}
+	used := make([]bool, len(rules))
+	byPattern := make(map[string][]int, len(tokensByPattern))
+
+	for pattern, tokens := range tokensByPattern {
+		for i, rule := range rules {
+			if rule.is_used(tokens) {
+				byPattern[pattern] = append(byPattern[pattern], i)
+				used[i] = true
+			}
+		}
+	}
+
+	newIdx := make([]int, len(rules))
+	for i, rule := range rules {
+		if used[i] {
+			newIdx[i] = len(result.Rules)
+			result.Rules = append(result.Rules, rule.text)
+		}
+	}
+	for pattern, idxs := range byPattern {
+		for j, i := range idxs {
+			idxs[j] = newIdx[i]
+		}
+		result.ByPattern[pattern] = idxs
+	}
+	return result
+}
+
+var (
+	content_word_re  = regexp.MustCompile(`[A-Za-z0-9_-]+`)
+	content_chunk_re = regexp.MustCompile("[^\\s\"'`<>=,;{}()\\[\\]]+")
+)
+
+// Collects both word-ish tokens ("btn", "primary") and longer chunks, so that
+// class names with special characters (e.g., "md:flex", "w-1/2") still match.
+func get_content_tokens(content []byte) map[string]struct{} {
+	tokens := make(map[string]struct{})
+	for _, re := range []*regexp.Regexp{content_word_re, content_chunk_re} {
+		for _, m := range re.FindAll(content, -1) {
+			tokens[string(m)] = struct{}{}
+		}
+	}
+	return tokens
+}
+
+type cssLeafRule struct {
+	text     string // including any enclosing group rules
+	prelude  string // empty for statement at-rules
+	isAtRule bool
+}
+
+// Statement at-rules (e.g., "@layer a, b;") are always kept, and style rules
+// are kept if any of their selectors is used. Other block at-rules
+// (@font-face, @keyframes, etc.) are left to the full stylesheet.
+func (r cssLeafRule) is_used(tokens map[string]struct{}) bool {
+	if r.isAtRule {
+		return r.prelude == ""
+	}
+	for _, selector := range split_css_selectors(r.prelude) {
+		if css_selector_is_used(selector, tokens) {
+			return true
+		}
+	}
+	return false
+}
+
+var css_group_at_rules = []string{"@media", "@supports", "@container", "@layer"}
+
+// Splits a stylesheet into leaf rules, descending into conditional group
+// rules (@media, @supports, @container, and @layer blocks).
+func flatten_css_rules(css, wrapper string) []cssLeafRule {
+	var leaves []cssLeafRule
+	for _, rule := range split_css_rules(css) {
+		open := strings.IndexByte(rule, '{')
+		if open < 0 {
+			leaves = append(leaves, cssLeafRule{text: wrap_css_rule(wrapper, rule), isAtRule: true})
+			continue
+		}
+		prelude := strings.TrimSpace(rule[:open])
+		isAtRule := strings.HasPrefix(prelude, "@")
+		if isAtRule && slices.ContainsFunc(css_group_at_rules, func(at string) bool { return strings.HasPrefix(prelude, at) }) {
+			leaves = append(leaves, flatten_css_rules(rule[open+1:len(rule)-1], wrap_css_rule(wrapper, prelude+"{\x00}"))...)
+			continue
+		}
+		leaves = append(leaves, cssLeafRule{text: wrap_css_rule(wrapper, rule), prelude: prelude, isAtRule: isAtRule})
+	}
+	return leaves
+}
+
+// wrapper is either empty or contains a single "\x00" placeholder.
+func wrap_css_rule(wrapper, rule string) string {
+	if wrapper == "" {
+		return rule
+	}
+	return strings.Replace(wrapper, "\x00", rule, 1)
+}
+
+// Splits a block of CSS into its top-level rules.
+func split_css_rules(css string) []string {
+	var rules []string
+	start, depth := 0, 0
+	for i := 0; i < len(css); i++ {
+		switch c := css[i]; {
+		case c == '"' || c == '\'':
+			i = skip_css_quoted(css, i) - 1
+		case strings.HasPrefix(css[i:], "/*"):
+			end := strings.Index(css[i+2:], "*/")
+			if end < 0 {
+				i = len(css)
+				continue
+			}
+			// Drop comments between rules (e.g., esbuild's "/*! legal */"
+			// comments), so they don't end up in the next rule's text
+			if depth == 0 && strings.TrimSpace(css[start:i]) == "" {
+				start = i + 2 + end + 2
+			}
+			i += 2 + end + 1
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				rules = append(rules, strings.TrimSpace(css[start:i+1]))
+				start = i + 1
+			}
+		case c == ';' && depth == 0:
+			rules = append(rules, strings.TrimSpace(css[start:i+1]))
+			start = i + 1
+		}
+	}
+	return slices.DeleteFunc(rules, func(r string) bool { return r == "" })
+}
+
+func split_css_selectors(prelude string) []string {
+	var selectors []string
+	start, depth := 0, 0
+	for i := 0; i < len(prelude); i++ {
+		switch prelude[i] {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case '\\':
+			i++
+		case ',':
+			if depth == 0 {
+				selectors = append(selectors, strings.TrimSpace(prelude[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(selectors, strings.TrimSpace(prelude[start:]))
+}
+
+// Pseudo-classes, pseudo-elements, and attribute selectors are ignored (a
+// selector like ":root" or "*" with no class, ID, or element name always
+// counts as used).
+func css_selector_is_used(selector string, tokens map[string]struct{}) bool {
+	s := selector
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '[':
+			for i < len(s) && s[i] != ']' {
+				i++
+			}
+		case c == ':':
+			for i+1 < len(s) && (s[i+1] == ':' || is_css_name_char(s[i+1])) {
+				i++
+			}
+			if i+1 < len(s) && s[i+1] == '(' {
+				depth := 0
+				for i+1 < len(s) {
+					i++
+					if s[i] == '(' {
+						depth++
+					} else if s[i] == ')' {
+						depth--
+						if depth == 0 {
+							break
+						}
+					}
+				}
+			}
+		case c == '.' || c == '#' || is_css_name_char(c):
+			j := i
+			if c == '.' || c == '#' {
+				j++
+			}
+			name, end := read_css_name(s, j)
+			i = end - 1
+			if name == "" {
+				continue
+			}
+			// Element names are case-insensitive and html/body are always there
+			if c != '.' && c != '#' {
+				name = strings.ToLower(name)
+				if name == "html" || name == "body" {
+					continue
+				}
+			}
+			if _, ok := tokens[name]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Reads an identifier starting at i, unescaping backslash escapes (e.g.,
+// "md\:flex" -> "md:flex"). Returns the name and the index just past it.
+func read_css_name(s string, i int) (string, int) {
+	var sb strings.Builder
+	for i < len(s) {
+		if s[i] == '\\' && i+1 < len(s) {
+			sb.WriteByte(s[i+1])
+			i += 2
+			continue
+		}
+		if !is_css_name_char(s[i]) {
+			break
+		}
+		sb.WriteByte(s[i])
+		i++
+	}
+	return sb.String(), i
+}
+
+func is_css_name_char(b byte) bool {
+	return b == '_' || b == '-' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9') || b >= 0x80
+}
+
+func skip_css_quoted(s string, i int) int {
+	quote := s[i]
+	for j := i + 1; j < len(s); j++ {
+		if s[j] == '\\' {
+			j++
+			continue
+		}
+		if s[j] == quote {
+			return j + 1
+		}
+	}
+	return len(s)
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// RUNTIME
+/////////////////////////////////////////////////////////////////////
+
+type criticalCSSElement struct {
+	el         template.HTML
+	sha256Hash string
+}
+
+// Only used in prod. Returns nil if extraction is disabled or the file is
+// missing.
+func (h *River) loadRouteCriticalCSS() (*routeCriticalCSS, error) {
+	if h._isDev || !h.Wave.GetRiverExtractCriticalCSS() {
+		return nil, nil
+	}
+	content, err := fs.ReadFile(h._privateFS, path.Join("river_out", RiverCriticalCSSJSONFileName))
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", RiverCriticalCSSJSONFileName, err)
+	}
+	var result routeCriticalCSS
+	if err := json.Unmarshal(content, &result); err != nil {
+		return nil, fmt.Errorf("could not decode %s: %w", RiverCriticalCSSJSONFileName, err)
+	}
+	// The rules come from the non-critical bundle, whose url()s are relative
+	// to the public root, but they get inlined into pages
+	for i, rule := range result.Rules {
+		result.Rules[i] = h.Wave.ResolveCSSURLs(rule)
+	}
+	return &result, nil
+}
+
+// Returns the critical CSS extracted at build time for the given route
+// pattern (e.g., "/dashboard/:id"). Returns an empty string if
+// River.ExtractCriticalCSS is not enabled, in dev, or if the pattern is
+// unknown.
+func (h *River) GetRouteCriticalCSS(pattern string) string {
+	return h.getRouteCriticalCSSForPatterns([]string{pattern})
+}
+
+func (h *River) getRouteCriticalCSSForPatterns(patterns []string) string {
+	if h._routeCriticalCSS == nil {
+		return ""
+	}
+	var idxs []int
+	for _, p := range patterns {
+		idxs = append(idxs, h._routeCriticalCSS.ByPattern[p]...)
+	}
+	slices.Sort(idxs)
+	idxs = slices.Compact(idxs)
+	var sb strings.Builder
+	for _, i := range idxs {
+		sb.WriteString(h._routeCriticalCSS.Rules[i])
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// Returns a <style> element with the combined critical CSS for all of the
+// given (matched) patterns, plus its sha256 hash for your CSP. Both are empty
+// if there is nothing to inline.
+func (h *River) GetRouteCriticalCSSStyleElement(patterns []string) (template.HTML, string) {
+	if h._routeCriticalCSS == nil || len(patterns) == 0 {
+		return "", ""
+	}
+	key := strings.Join(patterns, "\x00")
+	if cached, ok := h._routeCriticalCSSEls.Load(key); ok {
+		x := cached.(*criticalCSSElement)
+		return x.el, x.sha256Hash
+	}
+	css := h.getRouteCriticalCSSForPatterns(patterns)
+	if css == "" {
+		return "", ""
+	}
+	el := htmlutil.Element{
+		Tag:                 "style",
+		AttributesKnownSafe: map[string]string{"id": RouteCriticalCSSElementID},
+		DangerousInnerHTML:  "\n" + css,
+	}
+	sha256Hash, err := htmlutil.AddSha256HashInline(&el)
+	if err != nil {
+		Log.Error(fmt.Sprintf("error hashing route critical CSS: %v", err))
+		return "", ""
+	}
+	rendered, err := htmlutil.RenderElement(&el)
+	if err != nil {
+		Log.Error(fmt.Sprintf("error rendering route critical CSS: %v", err))
+		return "", ""
+	}
+	h._routeCriticalCSSEls.Store(key, &criticalCSSElement{el: rendered, sha256Hash: sha256Hash})
+	return rendered, sha256Hash
+}
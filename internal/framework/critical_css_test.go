@@ -0,0 +1,189 @@
+package framework
+
+import (
+	"reflect"
+	"testing"
+)
+
+func tokenSet(tokens ...string) map[string]struct{} {
+	m := make(map[string]struct{}, len(tokens))
+	for _, t := range tokens {
+		m[t] = struct{}{}
+	}
+	return m
+}
+
+func TestFlattenCSSRules(t *testing.T) {
+	tests := []struct {
+		name string
+		css  string
+		want []cssLeafRule
+	}{
+		{
+			name: "StyleRulesAndComments",
+			css:  `/*! legal */.a{color:red}/* .c{} */ .b , p{color:blue}`,
+			want: []cssLeafRule{
+				{text: `.a{color:red}`, prelude: `.a`},
+				{text: `.b , p{color:blue}`, prelude: `.b , p`},
+			},
+		},
+		{
+			name: "BracesInStrings",
+			css:  `.a::after{content:"}"}.b{color:red}`,
+			want: []cssLeafRule{
+				{text: `.a::after{content:"}"}`, prelude: `.a::after`},
+				{text: `.b{color:red}`, prelude: `.b`},
+			},
+		},
+		{
+			name: "NestedGroupRules",
+			css:  `@media (min-width:1px){@supports (display:grid){.a{color:red}}.b{color:blue}}`,
+			want: []cssLeafRule{
+				{text: `@media (min-width:1px){@supports (display:grid){.a{color:red}}}`, prelude: `.a`},
+				{text: `@media (min-width:1px){.b{color:blue}}`, prelude: `.b`},
+			},
+		},
+		{
+			name: "StatementLayer",
+			css:  `@layer base, components;@layer base{.a{color:red}}`,
+			want: []cssLeafRule{
+				{text: `@layer base, components;`, isAtRule: true},
+				{text: `@layer base{.a{color:red}}`, prelude: `.a`},
+			},
+		},
+		{
+			name: "OtherAtRules",
+			css:  `@font-face{font-family:x}@keyframes spin{to{rotate:1turn}}`,
+			want: []cssLeafRule{
+				{text: `@font-face{font-family:x}`, prelude: `@font-face`, isAtRule: true},
+				{text: `@keyframes spin{to{rotate:1turn}}`, prelude: `@keyframes spin`, isAtRule: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := flatten_css_rules(tt.css, ""); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("flatten_css_rules() =\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitCSSSelectors(t *testing.T) {
+	tests := map[string][]string{
+		`.a`:                  {`.a`},
+		`.a, .b > p`:          {`.a`, `.b > p`},
+		`.a:is(.b, .c), .d`:   {`.a:is(.b, .c)`, `.d`},
+		`.a:not(.b,.c)`:       {`.a:not(.b,.c)`},
+		`[data-x="a,b"], .c`:  {`[data-x="a,b"]`, `.c`},
+		`.x\,y, .z`:           {`.x\,y`, `.z`},
+		`.md\:flex,.w-1\/2`:   {`.md\:flex`, `.w-1\/2`},
+		`:where(.a, .b) .c,p`: {`:where(.a, .b) .c`, `p`},
+	}
+	for prelude, want := range tests {
+		if got := split_css_selectors(prelude); !reflect.DeepEqual(got, want) {
+			t.Errorf("split_css_selectors(%q) = %q, want %q", prelude, got, want)
+		}
+	}
+}
+
+func TestCSSSelectorIsUsed(t *testing.T) {
+	tokens := tokenSet("btn", "primary", "md:flex", "w-1/2", "nav", "main", "div")
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{".btn", true},
+		{".btn.primary", true},
+		{".btn.secondary", false},
+		{"#main", true},
+		{"#missing", false},
+		{"div .btn", true},
+		{"DIV .btn", true},
+		{"span .btn", false},
+		{"html body .btn", true},
+		{`.md\:flex`, true},
+		{`.md\:grid`, false},
+		{`.w-1\/2`, true},
+		{".btn:hover", true},
+		{".btn::before", true},
+		{".btn:is(.missing, .other)", true},
+		{".btn:not(.missing)", true},
+		{".missing:not(.btn)", false},
+		{":is(.missing) .btn", true},
+		{".btn:nth-child(2n+1)", true},
+		{`.btn[data-x="missing"]`, true},
+		{":root", true},
+		{"*", true},
+	}
+	for _, tt := range tests {
+		if got := css_selector_is_used(tt.selector, tokens); got != tt.want {
+			t.Errorf("css_selector_is_used(%q) = %v, want %v", tt.selector, got, tt.want)
+		}
+	}
+}
+
+func TestGetContentTokens(t *testing.T) {
+	tokens := get_content_tokens([]byte(`<div class="md:flex w-1/2 btn">`))
+	for _, want := range []string{"div", "md:flex", "md", "flex", "w-1/2", "btn"} {
+		if _, ok := tokens[want]; !ok {
+			t.Errorf("expected token %q in %v", want, tokens)
+		}
+	}
+}
+
+func TestSelectRouteCriticalCSS(t *testing.T) {
+	rules := flatten_css_rules(`@layer a, b;.unused{color:red}.home{color:red}@font-face{font-family:x}@media (min-width:1px){.about{color:blue}}.shared{color:green}`, "")
+	got := select_route_critical_css(rules, map[string]map[string]struct{}{
+		"/":      tokenSet("home", "shared"),
+		"/about": tokenSet("about", "shared"),
+	})
+	want := routeCriticalCSS{
+		Rules: []string{
+			`@layer a, b;`,
+			`.home{color:red}`,
+			`@media (min-width:1px){.about{color:blue}}`,
+			`.shared{color:green}`,
+		},
+		ByPattern: map[string][]int{
+			"/":      {0, 1, 3},
+			"/about": {0, 2, 3},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("select_route_critical_css() =\n%#v\nwant\n%#v", got, want)
+	}
+}
+
+func TestGetRouteCriticalCSSForPatterns(t *testing.T) {
+	h := &River{}
+	if got := h.getRouteCriticalCSSForPatterns([]string{"/"}); got != "" {
+		t.Errorf("expected no CSS without extraction, got %q", got)
+	}
+
+	h._routeCriticalCSS = &routeCriticalCSS{
+		Rules: []string{".a{}", ".b{}", ".c{}"},
+		ByPattern: map[string][]int{
+			"/":        {0, 2},
+			"/about":   {1, 2},
+			"/nothing": nil,
+		},
+	}
+	tests := []struct {
+		name     string
+		patterns []string
+		want     string
+	}{
+		{"Single", []string{"/"}, ".a{}\n.c{}\n"},
+		{"MergedInSourceOrderWithoutDuplicates", []string{"/about", "/"}, ".a{}\n.b{}\n.c{}\n"},
+		{"UnknownPattern", []string{"/missing"}, ""},
+		{"NoRules", []string{"/nothing"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.getRouteCriticalCSSForPatterns(tt.patterns); got != tt.want {
+				t.Errorf("getRouteCriticalCSSForPatterns(%q) = %q, want %q", tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 
+	"github.com/river-now/river/kit/csp"
 	"github.com/river-now/river/kit/headels"
 	"github.com/river-now/river/kit/mux"
 	"github.com/river-now/river/kit/response"
@@ -85,6 +86,7 @@ func (h *River) GetUIHandler(nestedRouter *mux.NestedRouter) mux.TasksCtxRequire
 		var ssrScript *template.HTML
 		var ssrScriptSha256Hash string
 		var headElements template.HTML
+		var routeCriticalCSSSha256Hash string
 
 		eg.Go(func() error {
 			he, err := headElsInstance.Render(uiRouteData.state_2_final.SortedAndPreEscapedHeadEls)
@@ -93,6 +95,11 @@ func (h *River) GetUIHandler(nestedRouter *mux.NestedRouter) mux.TasksCtxRequire
 			}
 			headElements = he
 			headElements += "\n" + h.Wave.GetCriticalCSSStyleElement()
+			routeCriticalCSSEl, sha256Hash := h.GetRouteCriticalCSSStyleElement(routeData.MatchedPatterns)
+			if routeCriticalCSSEl != "" {
+				headElements += "\n" + routeCriticalCSSEl
+				routeCriticalCSSSha256Hash = sha256Hash
+			}
 			headElements += "\n" + h.Wave.GetStyleSheetLinkElement()
 
 			return nil
@@ -127,27 +134,59 @@ func (h *River) GetUIHandler(nestedRouter *mux.NestedRouter) mux.TasksCtxRequire
 			return
 		}
 
+		if policy := csp.FromContext(r.Context()); policy != nil {
+			policy.AddScriptHash(ssrScriptSha256Hash)
+			policy.AddScriptHash(h.Wave.GetPublicFileMapScriptSha256Hash())
+			policy.AddStyleHash(h.Wave.GetCriticalCSSStyleElementSha256Hash())
+			policy.AddStyleHash(routeCriticalCSSSha256Hash)
+			if h._isDev {
+				// Vite's HMR injects <style> tags without a nonce, which no
+				// strict policy allows, so only report violations in dev.
+				policy.SetReportOnly()
+				policy.AddScriptHash(h.Wave.GetRefreshScriptSha256Hash())
+				for directive, sources := range viteutil.ToDevCSPSources(h.getViteVariant()) {
+					for _, source := range sources {
+						policy.AddSource(directive, source)
+					}
+				}
+				for _, origin := range h.Wave.GetRefreshServerOrigins() {
+					policy.AddSource("connect-src", origin)
+				}
+			}
+			// Public assets served from a CDN (see Core.AssetBaseURL)
+			if u, err := url.Parse(h.Wave.GetPublicURLBase()); err == nil && u.Host != "" {
+				origin := u.Scheme + "://" + u.Host
+				for _, directive := range []string{"script-src", "style-src", "img-src", "font-src"} {
+					policy.AddSource(directive, origin)
+				}
+			}
+			rootTemplateData["RiverCSPNonce"] = policy.Nonce()
+		}
+
 		rootTemplateData["RiverHeadEls"] = headElements
 		rootTemplateData["RiverSSRScript"] = ssrScript
 		rootTemplateData["RiverSSRScriptSha256Hash"] = ssrScriptSha256Hash
+		rootTemplateData["RiverCriticalCSSSha256Hash"] = routeCriticalCSSSha256Hash
 		rootTemplateData["RiverRootID"] = "river-root"
 
 		if !h._isDev {
+			// Like the modulepreload links in the SSR script, so that a
+			// tampered entry (e.g., on a CDN) is refused
+			var integrityAttrs string
+			if sri := h.Wave.GetPublicSRI(h._clientEntryOut); sri != "" {
+				integrityAttrs = fmt.Sprintf(` integrity="%s" crossorigin="anonymous"`, sri)
+			}
 			rootTemplateData["RiverBodyScripts"] = template.HTML(
 				fmt.Sprintf(
-					`<script type="module" src="%s%s"></script>`,
-					h.Wave.GetPublicPathPrefix(), h._clientEntryOut,
+					`<script type="module" src="%s%s"%s></script>`,
+					h.Wave.GetPublicURLBase(), h._clientEntryOut, integrityAttrs,
 				),
 			)
 		} else {
-			opts := viteutil.ToDevScriptsOptions{ClientEntry: h._clientEntrySrc}
-			if UIVariant(h.Wave.GetRiverUIVariant()) == UIVariants.React {
-				opts.Variant = viteutil.Variants.React
-			} else {
-				opts.Variant = viteutil.Variants.Other
-			}
-
-			devScripts, err := viteutil.ToDevScripts(opts)
+			devScripts, err := viteutil.ToDevScripts(viteutil.ToDevScriptsOptions{
+				ClientEntry: h._clientEntrySrc,
+				Variant:     h.getViteVariant(),
+			})
 			if err != nil {
 				Log.Error(fmt.Sprintf("Error getting dev scripts: %v\n", err))
 				res.InternalServerError()
@@ -189,3 +228,10 @@ func (h *River) GetActionsHandler(router *mux.Router) mux.TasksCtxRequirerFunc {
 		router.ServeHTTP(w, r)
 	})
 }
+
+func (h *River) getViteVariant() viteutil.Variant {
+	if UIVariant(h.Wave.GetRiverUIVariant()) == UIVariants.React {
+		return viteutil.Variants.React
+	}
+	return viteutil.Variants.Other
+}
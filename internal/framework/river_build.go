@@ -103,7 +103,10 @@ export function riverVitePlugin(): Plugin {
 
 			return {
 				...c,
-				base: isDev ? "/" : "{{.PublicPathPrefix}}",
+				// Relative, so the built chunks load each other (and
+				// their assets) from wherever the entry was loaded from,
+				// which keeps the public URL base a runtime setting.
+				base: isDev ? "/" : "./",
 				build: {
 					target: "es2022",
 					...c.build,
@@ -157,7 +160,7 @@ export function riverVitePlugin(): Plugin {
 				(_, __, assetPath) => {
 					const hashed = (staticPublicAssetMap as Record<string, string>)[assetPath];
 					if (!hashed) return {{.Tick}}"${assetPath}"{{.Tick}};
-					return {{.Tick}}"{{.PublicPathPrefix}}${hashed}"{{.Tick}};
+					return {{.Tick}}((globalThis[Symbol.for("{{.RiverSymbolStr}}")]?.publicPathPrefix ?? "{{.PublicPathPrefix}}") + "${hashed}"){{.Tick}};
 				},
 			);
 			if (replacedCode === code) return null;
@@ -216,16 +219,23 @@ func (h *River) toRollupOptions(entrypoints []string, fileMap map[string]string)
 
 	sb.Return()
 
+	// The base as of the build; the page's actual base is read at runtime.
 	sb.Line(fmt.Sprintf(
 		"export const publicPathPrefix = \"%s\";",
-		h.Wave.GetPublicPathPrefix(),
+		h.Wave.GetPublicURLBase(),
 	))
 
 	sb.Return()
 
+	sb.Line(fmt.Sprintf(`export function publicURLBase(): string {
+	return (globalThis as any)[Symbol.for("%s")]?.publicPathPrefix ?? publicPathPrefix;
+}`, RiverSymbolStr))
+
+	sb.Return()
+
 	sb.Line(`export function waveRuntimeURL(originalPublicURL: keyof typeof staticPublicAssetMap) {
 	const url = staticPublicAssetMap[originalPublicURL] ?? originalPublicURL;
-	return publicPathPrefix + url;
+	return publicURLBase() + url;
 }`)
 
 	tick := "`"
@@ -268,7 +278,8 @@ func (h *River) toRollupOptions(entrypoints []string, fileMap map[string]string)
 
 	err = vitePluginTemplate.Execute(&buf, map[string]any{
 		"FuncName":         h.Wave.GetRiverBuildtimePublicURLFuncName(),
-		"PublicPathPrefix": h.Wave.GetPublicPathPrefix(),
+		"PublicPathPrefix": h.Wave.GetPublicURLBase(),
+		"RiverSymbolStr":   RiverSymbolStr,
 		"Tick":             tick,
 		"IgnoredList":      template.HTML(stringifiedIgnore),
 		"DedupeList":       template.HTML(stringifiedDedupeBytes),
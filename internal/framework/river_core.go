@@ -75,4 +75,7 @@ type River struct {
 	_depToCSSBundleMap map[string]string
 	_rootTemplate      *template.Template
 	_privateFS         fs.FS
+
+	_routeCriticalCSS    *routeCriticalCSS
+	_routeCriticalCSSEls sync.Map // joined matched patterns -> *criticalCSSElement
 }
@@ -120,6 +120,9 @@ func (h *River) GenerateTypeScript(opts *TSGenOptions) (string, error) {
 				"phantomInputType":  {TypeInstance: action.I()},
 				"phantomOutputType": {TypeInstance: action.O()},
 			}
+			if authInfo := action.AuthInfo(); authInfo != nil {
+				item.ArbitraryProperties["auth"] = authInfo
+			}
 		}
 		collection = append(collection, item)
 	}
@@ -191,6 +194,7 @@ export type BaseMutationPropsWithInput<P extends RiverMutationPattern> = BaseMut
 	extraTSToUse += "type RiverFunction = " + tsgen.TypeUnion(fTypeIn) + ";\n"
 	extraTSToUse += "type RiverPattern = " + tsgen.TypeUnion(pTypeIn) + ";\n"
 	extraTSToUse += `export type RiverRouteParams<T extends RiverPattern> = (Extract<RiverFunction, { pattern: T }>["params"])[number];` + "\n"
+	extraTSToUse += `export type RiverAuthRequiredPattern = Extract<RiverFunction, { auth: { requiresLogin: true } }>["pattern"];` + "\n"
 
 	if opts.ExtraTSCode != "" {
 		extraTSToUse += "\n" + opts.ExtraTSCode
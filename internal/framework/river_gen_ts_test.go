@@ -0,0 +1,42 @@
+package framework
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/river-now/river/kit/mux"
+)
+
+func TestGenerateTypeScriptAuth(t *testing.T) {
+	auth := mux.NewAuth(mux.AuthConfig[string]{
+		ResolvePrincipal: func(*http.Request) (string, error) { return "", nil },
+		GetRoles:         func(string) []string { return nil },
+	})
+	actions := mux.NewRouter(nil)
+	ok := mux.TaskHandlerFromFunc(func(*mux.ReqData[mux.None]) (mux.None, error) { return mux.None{}, nil })
+	mux.RegisterTaskHandler(actions, http.MethodGet, "/public", ok)
+	admin := mux.RegisterTaskHandler(actions, http.MethodPost, "/admin", ok)
+	mux.SetPatternLevelAuthRequirements(admin, auth, mux.AuthRequirements[string]{Roles: []string{"admin"}})
+
+	h := &River{}
+	ts, err := h.GenerateTypeScript(&TSGenOptions{
+		LoadersRouter: mux.NewNestedRouter(nil),
+		ActionsRouter: actions,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`auth: {"requiresLogin":true,"roles":["admin"]},`,
+		`export type RiverAuthRequiredPattern = Extract<RiverFunction, { auth: { requiresLogin: true } }>["pattern"];`,
+	} {
+		if !strings.Contains(ts, want) {
+			t.Errorf("generated TypeScript does not contain %q:\n%s", want, ts)
+		}
+	}
+	if n := strings.Count(ts, `auth: {"`); n != 1 {
+		t.Errorf("expected auth on exactly one route, got %d", n)
+	}
+}
@@ -88,6 +88,12 @@ func (h *River) initInner(isDev bool) error {
 		return fmt.Errorf("error parsing root template: %w", err)
 	}
 	h._rootTemplate = tmpl
+	routeCriticalCSS, err := h.loadRouteCriticalCSS()
+	if err != nil {
+		return fmt.Errorf("error loading route critical CSS: %w", err)
+	}
+	h._routeCriticalCSS = routeCriticalCSS
+	h._routeCriticalCSSEls.Clear()
 	if h.GetHeadElUniqueRules != nil {
 		headElsInstance.InitUniqueRules(h.GetHeadElUniqueRules())
 	} else {
@@ -19,6 +19,8 @@ type SSRInnerHTMLInput struct {
 	*ui_data_core
 
 	CSSBundles []string
+	// Asset path -> SRI value, for deps and CSS bundles
+	Integrity map[string]string
 }
 
 // Sadly, must include the script tags so html/template parses this correctly.
@@ -44,11 +46,19 @@ x.hasRootData = {{.HasRootData}};
 x.params = {{.Params}};
 x.splatValues = {{.SplatValues}};
 if (!x.isDev) {
+	const integrity = {{.Integrity}};
+	const setIntegrity = (link, y) => {
+		if (integrity[y]) {
+			link.integrity = integrity[y];
+			link.crossOrigin = "anonymous";
+		}
+	};
 	const deps = {{.Deps}};
 	deps.forEach((y) => {
 		const link = document.createElement("link");
 		link.rel = "modulepreload";
 		link.href = x.publicPathPrefix + y;
+		setIntegrity(link, y);
 		document.head.appendChild(link);
 	});
 	const cssBundles = {{.CSSBundles}};
@@ -57,6 +67,7 @@ if (!x.isDev) {
 		link.rel = "stylesheet";
 		link.href = x.publicPathPrefix + y;
 		link.setAttribute("data-river-css-bundle", y);
+		setIntegrity(link, y);
 		document.head.appendChild(link);
 	});
 }
@@ -78,11 +89,12 @@ func (h *River) GetSSRInnerHTML(routeData *final_ui_data) (*GetSSRInnerHTMLOutpu
 		IsDev:            h._isDev,
 		ViteDevURL:       routeData.ViteDevURL,
 		BuildID:          h._buildID,
-		PublicPathPrefix: h.Wave.GetPublicPathPrefix(),
+		PublicPathPrefix: h.Wave.GetPublicURLBase(),
 
 		ui_data_core: routeData.ui_data_core,
 
 		CSSBundles: routeData.CSSBundles,
+		Integrity:  h.getIntegrity(routeData.Deps, routeData.CSSBundles),
 	}
 	if err := ssrInnerTmpl.Execute(&htmlBuilder, dto); err != nil {
 		wrapped := fmt.Errorf("could not execute SSR inner HTML template: %w", err)
@@ -116,3 +128,18 @@ func (h *River) GetSSRInnerHTML(routeData *final_ui_data) (*GetSSRInnerHTMLOutpu
 
 	return &GetSSRInnerHTMLOutput{Script: &renderedEl, Sha256Hash: sha256Hash}, nil
 }
+
+func (h *River) getIntegrity(deps, cssBundles []string) map[string]string {
+	integrity := make(map[string]string, len(deps)+len(cssBundles))
+	if h._isDev {
+		return integrity
+	}
+	for _, list := range [][]string{deps, cssBundles} {
+		for _, y := range list {
+			if sri := h.Wave.GetPublicSRI(y); sri != "" {
+				integrity[y] = sri
+			}
+		}
+	}
+	return integrity
+}
@@ -33,5 +33,12 @@ func (h *River) PostViteProdBuild() error {
 		return err
 	}
 
+	if h.Wave.GetRiverExtractCriticalCSS() {
+		if err := h.extractRouteCriticalCSS(pf); err != nil {
+			Log.Error(fmt.Sprintf("error extracting route critical CSS: %s", err))
+			return err
+		}
+	}
+
 	return nil
 }
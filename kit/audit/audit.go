@@ -0,0 +1,222 @@
+// Package audit records who changed what through a mux.Router. Enable
+// observes every mutation request to the router and writes a Record (the
+// principal, route pattern, params, redacted input, outcome status, and
+// duration) to a pluggable Sink, so handlers don't have to remember to.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/river-now/river/kit/colorlog"
+	"github.com/river-now/river/kit/mux"
+)
+
+var auditLog = colorlog.New("audit")
+
+// Replaces the values of struct fields tagged `audit:"redact"`.
+const Redacted = "[REDACTED]"
+
+type Record struct {
+	Time      time.Time         `json:"time"` // When the request started
+	Principal string            `json:"principal,omitempty"`
+	Method    string            `json:"method"`
+	Pattern   string            `json:"pattern"`
+	Params    map[string]string `json:"params,omitempty"`
+	// The route's parsed input, redacted (see Redact) and JSON-encoded. Empty
+	// for HTTP handlers, and when the input fails to parse.
+	Input    json.RawMessage `json:"input,omitempty"`
+	Status   int             `json:"status"`
+	Duration time.Duration   `json:"durationNs"`
+}
+
+// Sinks must be safe for concurrent use.
+type Sink interface {
+	Write(ctx context.Context, rec *Record) error
+}
+
+type Config struct {
+	Sink Sink // REQUIRED
+	// Optional. Returns an identifier for the request's principal (e.g., a
+	// user ID), or an empty string for anonymous requests. Typically wraps
+	// mux.Auth.Principal.
+	GetPrincipal func(r *http.Request) string
+	// HTTP methods to audit. Defaults to POST, PUT, PATCH, and DELETE.
+	Methods []string
+	// Optional. Called when a record can't be encoded or written. Defaults
+	// to logging the error.
+	OnError func(err error, rec *Record)
+}
+
+// Enable audits router's mutation requests (see Config.Methods), including
+// those rejected by middleware. Records are written synchronously, after the
+// response, so use a fast sink. Panics if Sink is nil.
+func Enable(router *mux.Router, cfg Config) {
+	if cfg.Sink == nil {
+		panic("audit: Config.Sink is required")
+	}
+	if cfg.Methods == nil {
+		cfg.Methods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = func(err error, rec *Record) {
+			auditLog.Error("Failed to write audit record", "error", err, "method", rec.Method, "pattern", rec.Pattern)
+		}
+	}
+	mux.SetGlobalRequestObserver(router, func(info *mux.RequestInfo) {
+		method := info.Route.Method()
+		if !slices.Contains(cfg.Methods, method) {
+			return
+		}
+		rec := &Record{
+			Time:     time.Now().Add(-info.Duration),
+			Method:   method,
+			Pattern:  info.Route.OriginalPattern(),
+			Status:   info.Status,
+			Duration: info.Duration,
+		}
+		if len(info.Params) > 0 {
+			rec.Params = info.Params
+		}
+		if cfg.GetPrincipal != nil {
+			rec.Principal = cfg.GetPrincipal(info.Request)
+		}
+		if info.Input != nil {
+			input, err := json.Marshal(Redact(info.Input))
+			if err != nil {
+				cfg.OnError(err, rec)
+			} else {
+				rec.Input = input
+			}
+		}
+		// The request may already be canceled, but the record should still
+		// be written.
+		ctx := context.WithoutCancel(info.Request.Context())
+		if err := cfg.Sink.Write(ctx, rec); err != nil {
+			cfg.OnError(err, rec)
+		}
+	})
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// REDACTION
+/////////////////////////////////////////////////////////////////////
+
+var jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+
+// Redact returns a copy of v, suitable for encoding/json, in which every
+// struct field tagged `audit:"redact"` (at any depth) has its value replaced
+// with Redacted. Structs become maps keyed by their JSON field names, and
+// types that implement json.Marshaler are kept as is.
+func Redact(v any) any {
+	return redactValue(reflect.ValueOf(v))
+}
+
+func redactValue(v reflect.Value) any {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Implements(jsonMarshalerType) {
+			return v.Interface()
+		}
+		return redactValue(v.Elem())
+	case reflect.Struct:
+		if v.Type().Implements(jsonMarshalerType) || reflect.PointerTo(v.Type()).Implements(jsonMarshalerType) {
+			return v.Interface()
+		}
+		m := make(map[string]any, v.NumField())
+		redactStructInto(m, v)
+		return m
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Key().Kind() != reflect.String {
+			return v.Interface()
+		}
+		m := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[iter.Key().String()] = redactValue(iter.Value())
+		}
+		return m
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface() // Bytes encode as base64
+		}
+		s := make([]any, v.Len())
+		for i := range v.Len() {
+			s[i] = redactValue(v.Index(i))
+		}
+		return s
+	default:
+		return v.Interface()
+	}
+}
+
+func redactStructInto(m map[string]any, v reflect.Value) {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fv := v.Field(i)
+		// Promote the fields of untagged embedded structs, like encoding/json
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					continue
+				}
+				fv, ft = fv.Elem(), ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				redactStructInto(m, fv)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if slices.Contains(strings.Split(opts, ","), "omitempty") && isEmptyValue(fv) {
+			continue
+		}
+		if field.Tag.Get("audit") == "redact" {
+			m[name] = Redacted
+			continue
+		}
+		m[name] = redactValue(fv)
+	}
+}
+
+// Matches encoding/json's definition of empty, for omitempty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/river-now/river/kit/mux"
+)
+
+type memorySink struct {
+	mu      sync.Mutex
+	records []*Record
+	err     error
+}
+
+func (s *memorySink) Write(_ context.Context, rec *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, rec)
+	return s.err
+}
+
+type changePasswordInput struct {
+	Username    string `json:"username"`
+	OldPassword string `json:"oldPassword" audit:"redact"`
+	NewPassword string `json:"newPassword" audit:"redact"`
+}
+
+func newTestRouter(t *testing.T, sink Sink, onError func(error, *Record)) *mux.Router {
+	t.Helper()
+	r := mux.NewRouter(&mux.Options{
+		MarshalInput: func(r *http.Request, iPtr any) error {
+			return json.NewDecoder(r.Body).Decode(iPtr)
+		},
+	})
+	Enable(r, Config{
+		Sink:         sink,
+		GetPrincipal: func(r *http.Request) string { return r.Header.Get("X-User") },
+		OnError:      onError,
+	})
+	mux.RegisterTaskHandler(r, http.MethodPost, "/users/:id/password", mux.TaskHandlerFromFunc(
+		func(rd *mux.ReqData[changePasswordInput]) (mux.None, error) {
+			if rd.Input().OldPassword != "hunter2" {
+				rd.ResponseProxy().SetStatus(http.StatusForbidden)
+			}
+			return mux.None{}, nil
+		},
+	))
+	mux.RegisterTaskHandler(r, http.MethodGet, "/users/:id", mux.TaskHandlerFromFunc(
+		func(rd *mux.ReqData[mux.None]) (string, error) { return "user", nil },
+	))
+	mux.RegisterHandlerFunc(r, http.MethodDelete, "/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return r
+}
+
+func serve(r http.Handler, method, path, body string) int {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("X-User", "admin-1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestEnable(t *testing.T) {
+	t.Run("RecordsTaskMutations", func(t *testing.T) {
+		sink := &memorySink{}
+		r := newTestRouter(t, sink, nil)
+		before := time.Now()
+		serve(r, http.MethodPost, "/users/42/password",
+			`{"username":"bob","oldPassword":"hunter2","newPassword":"correct horse"}`,
+		)
+		if len(sink.records) != 1 {
+			t.Fatalf("expected 1 record, got %d", len(sink.records))
+		}
+		rec := sink.records[0]
+		if rec.Principal != "admin-1" || rec.Method != http.MethodPost || rec.Pattern != "/users/:id/password" {
+			t.Errorf("unexpected record: %+v", rec)
+		}
+		if rec.Params["id"] != "42" || rec.Status != http.StatusOK {
+			t.Errorf("unexpected params or status: %+v", rec)
+		}
+		if rec.Time.Before(before.Add(-time.Second)) || rec.Duration < 0 {
+			t.Errorf("unexpected time or duration: %v, %v", rec.Time, rec.Duration)
+		}
+		want := `{"newPassword":"[REDACTED]","oldPassword":"[REDACTED]","username":"bob"}`
+		if string(rec.Input) != want {
+			t.Errorf("expected input %s, got %s", want, rec.Input)
+		}
+	})
+
+	t.Run("RecordsOutcomeStatus", func(t *testing.T) {
+		sink := &memorySink{}
+		r := newTestRouter(t, sink, nil)
+		serve(r, http.MethodPost, "/users/42/password", `{"oldPassword":"wrong"}`)
+		serve(r, http.MethodPost, "/users/42/password", `not json`)
+		serve(r, http.MethodDelete, "/users/42", "")
+		if len(sink.records) != 3 {
+			t.Fatalf("expected 3 records, got %d", len(sink.records))
+		}
+		if got := sink.records[0].Status; got != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", got)
+		}
+		if rec := sink.records[1]; rec.Status != http.StatusInternalServerError || rec.Input != nil {
+			t.Errorf("expected a 500 without input for unparseable input, got %+v", rec)
+		}
+		if rec := sink.records[2]; rec.Status != http.StatusNoContent || rec.Method != http.MethodDelete || rec.Input != nil {
+			t.Errorf("unexpected record for HTTP handler: %+v", rec)
+		}
+	})
+
+	t.Run("SkipsQueries", func(t *testing.T) {
+		sink := &memorySink{}
+		r := newTestRouter(t, sink, nil)
+		serve(r, http.MethodGet, "/users/42", "")
+		serve(r, http.MethodPost, "/nope", "")
+		if len(sink.records) != 0 {
+			t.Errorf("expected no records, got %d", len(sink.records))
+		}
+	})
+
+	t.Run("RecordsRejectedRequests", func(t *testing.T) {
+		sink := &memorySink{}
+		r := newTestRouter(t, sink, nil)
+		auth := mux.NewAuth(mux.AuthConfig[string]{
+			ResolvePrincipal: func(*http.Request) (string, error) { return "", nil },
+		})
+		route := mux.RegisterTaskHandler(r, http.MethodPost, "/admin", mux.TaskHandlerFromFunc(
+			func(rd *mux.ReqData[mux.None]) (mux.None, error) { return mux.None{}, nil },
+		))
+		mux.SetPatternLevelAuthRequirements(route, auth, mux.AuthRequirements[string]{})
+		if got := serve(r, http.MethodPost, "/admin", ""); got != http.StatusUnauthorized {
+			t.Fatalf("expected 401, got %d", got)
+		}
+		if len(sink.records) != 1 || sink.records[0].Status != http.StatusUnauthorized {
+			t.Errorf("expected a 401 record, got %+v", sink.records)
+		}
+	})
+
+	t.Run("SinkErrors", func(t *testing.T) {
+		sink := &memorySink{err: errors.New("disk full")}
+		var gotErr error
+		r := newTestRouter(t, sink, func(err error, rec *Record) { gotErr = err })
+		if got := serve(r, http.MethodDelete, "/users/42", ""); got != http.StatusNoContent {
+			t.Errorf("expected the response to be unaffected, got %d", got)
+		}
+		if gotErr == nil || gotErr.Error() != "disk full" {
+			t.Errorf("expected OnError to be called with the sink error, got %v", gotErr)
+		}
+	})
+}
+
+func TestRedact(t *testing.T) {
+	type Card struct {
+		Number string `json:"number" audit:"redact"`
+		Last4  string `json:"last4"`
+	}
+	type Base struct {
+		Token string `audit:"redact"`
+	}
+	type Input struct {
+		Base
+		Name    string            `json:"name"`
+		Cards   []Card            `json:"cards"`
+		Primary *Card             `json:"primary,omitempty"`
+		Backup  *Card             `json:"backup,omitempty"`
+		Meta    map[string]any    `json:"meta"`
+		When    time.Time         `json:"when"`
+		Ignored string            `json:"-"`
+		Tags    map[string]string `json:"tags,omitempty"`
+	}
+	in := Input{
+		Base:    Base{Token: "secret-token"},
+		Name:    "bob",
+		Cards:   []Card{{Number: "4111111111111111", Last4: "1111"}},
+		Primary: &Card{Number: "5555555555554444", Last4: "4444"},
+		Meta:    map[string]any{"card": Card{Number: "1", Last4: "1"}},
+		When:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Ignored: "ignored",
+	}
+
+	got, err := json.Marshal(Redact(&in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"Token":"[REDACTED]","cards":[{"last4":"1111","number":"[REDACTED]"}],` +
+		`"meta":{"card":{"last4":"1","number":"[REDACTED]"}},"name":"bob",` +
+		`"primary":{"last4":"4444","number":"[REDACTED]"},"when":"2026-01-02T03:04:05Z"}`
+	if string(got) != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, got)
+	}
+	if strings.Contains(string(got), "4111") || strings.Contains(string(got), "secret-token") {
+		t.Error("secret leaked")
+	}
+	if Redact(nil) != nil {
+		t.Error("expected nil for nil")
+	}
+}
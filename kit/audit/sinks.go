@@ -0,0 +1,145 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/river-now/river/kit/sqlutil"
+)
+
+/////////////////////////////////////////////////////////////////////
+/////// JSON LINES SINK
+/////////////////////////////////////////////////////////////////////
+
+// Writes each record as a single line of JSON.
+type JSONLinesSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewJSONLinesSink(w io.Writer) *JSONLinesSink {
+	return &JSONLinesSink{w: w}
+}
+
+// OpenJSONLinesFile opens (or creates) path for appending, readable and
+// writable only by the owner. Call Close when done.
+func OpenJSONLinesFile(path string) (*JSONLinesSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("audit: failed to open %s: %w", path, err)
+	}
+	return NewJSONLinesSink(f), nil
+}
+
+func (s *JSONLinesSink) Write(_ context.Context, rec *Record) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(line)
+	return err
+}
+
+// Closes the underlying writer, if it is an io.Closer.
+func (s *JSONLinesSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// SQL SINK
+/////////////////////////////////////////////////////////////////////
+
+type SQLSinkConfig struct {
+	DB      *sql.DB         // Required.
+	Dialect sqlutil.Dialect // Defaults to sqlutil.DialectSQLite.
+	// Defaults to "audit_log". Must be a plain SQL identifier.
+	TableName string
+}
+
+// A Sink backed by a SQL database. Use SchemaSQL to create its table. Times
+// are stored as Unix milliseconds, and params and input as JSON text.
+type SQLSink struct {
+	db      *sql.DB
+	dialect sqlutil.Dialect
+	table   string
+}
+
+// Panics if you fail to provide a DB or provide an invalid TableName.
+func NewSQLSink(cfg SQLSinkConfig) *SQLSink {
+	if cfg.DB == nil {
+		panic("audit: SQLSinkConfig.DB is required")
+	}
+	if cfg.TableName == "" {
+		cfg.TableName = "audit_log"
+	}
+	if !sqlutil.IsPlainIdentifier(cfg.TableName) {
+		panic(fmt.Sprintf("audit: invalid table name %q", cfg.TableName))
+	}
+	return &SQLSink{db: cfg.DB, dialect: cfg.Dialect, table: cfg.TableName}
+}
+
+// Returns the statements that create the sink's table and indexes, if they
+// don't already exist.
+func (s *SQLSink) SchemaSQL() []string {
+	if s.dialect == sqlutil.DialectMySQL {
+		return []string{fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	occurred_at BIGINT NOT NULL,
+	principal VARCHAR(255) NOT NULL,
+	method VARCHAR(16) NOT NULL,
+	pattern TEXT NOT NULL,
+	params TEXT NOT NULL,
+	input LONGTEXT NOT NULL,
+	status INT NOT NULL,
+	duration_ns BIGINT NOT NULL,
+	INDEX %s_principal_idx (principal),
+	INDEX %s_occurred_at_idx (occurred_at)
+)`, s.table, s.table, s.table)}
+	}
+	return []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	occurred_at BIGINT NOT NULL,
+	principal TEXT NOT NULL,
+	method TEXT NOT NULL,
+	pattern TEXT NOT NULL,
+	params TEXT NOT NULL,
+	input TEXT NOT NULL,
+	status INTEGER NOT NULL,
+	duration_ns BIGINT NOT NULL
+)`, s.table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_principal_idx ON %s (principal)`, s.table, s.table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_occurred_at_idx ON %s (occurred_at)`, s.table, s.table),
+	}
+}
+
+func (s *SQLSink) Write(ctx context.Context, rec *Record) error {
+	params, err := json.Marshal(rec.Params)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, s.q(fmt.Sprintf(
+		`INSERT INTO %s (occurred_at, principal, method, pattern, params, input, status, duration_ns) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.table,
+	)),
+		rec.Time.UnixMilli(), rec.Principal, rec.Method, rec.Pattern,
+		string(params), string(rec.Input), rec.Status, int64(rec.Duration),
+	)
+	return err
+}
+
+// Rewrites "?" placeholders for the sink's dialect.
+func (s *SQLSink) q(query string) string {
+	return sqlutil.Rebind(s.dialect, query)
+}
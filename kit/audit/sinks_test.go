@@ -0,0 +1,162 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/river-now/river/kit/sqlutil"
+	_ "modernc.org/sqlite"
+)
+
+func TestJSONLinesSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := OpenJSONLinesFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs := []*Record{
+		{Time: time.UnixMilli(1000).UTC(), Principal: "u1", Method: "POST", Pattern: "/a", Status: 200, Input: json.RawMessage(`{"x":1}`)},
+		{Time: time.UnixMilli(2000).UTC(), Method: "DELETE", Pattern: "/b/:id", Params: map[string]string{"id": "7"}, Status: 401},
+	}
+	for _, rec := range recs {
+		if err := sink.Write(context.Background(), rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("expected mode 0600, got %o", perm)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	var got []Record
+	for scanner.Scan() {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		got = append(got, rec)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(got))
+	}
+	if got[0].Principal != "u1" || string(got[0].Input) != `{"x":1}` || got[1].Params["id"] != "7" || got[1].Status != 401 {
+		t.Errorf("unexpected records: %+v", got)
+	}
+}
+
+func TestSQLSinkWrite(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1) // each connection would get its own in-memory database
+	t.Cleanup(func() { db.Close() })
+	sink := NewSQLSink(SQLSinkConfig{DB: db})
+	for _, stmt := range sink.SchemaSQL() {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("failed to create schema: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	recs := []*Record{
+		{
+			Time: time.UnixMilli(1000), Principal: "u1", Method: "POST", Pattern: "/a",
+			Input: json.RawMessage(`{"x":1}`), Status: 200, Duration: 3 * time.Millisecond,
+		},
+		{Time: time.UnixMilli(2000), Method: "DELETE", Pattern: "/b/:id", Params: map[string]string{"id": "7"}, Status: 401},
+	}
+	for _, rec := range recs {
+		if err := sink.Write(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rows, err := db.QueryContext(ctx,
+		`SELECT occurred_at, principal, method, pattern, params, input, status, duration_ns FROM audit_log ORDER BY occurred_at`,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []Record
+	for rows.Next() {
+		var rec Record
+		var occurredAt, durationNs int64
+		var params, input string
+		if err := rows.Scan(&occurredAt, &rec.Principal, &rec.Method, &rec.Pattern, &params, &input, &rec.Status, &durationNs); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal([]byte(params), &rec.Params); err != nil {
+			t.Fatalf("invalid params %q: %v", params, err)
+		}
+		rec.Time = time.UnixMilli(occurredAt)
+		rec.Input = json.RawMessage(input)
+		rec.Duration = time.Duration(durationNs)
+		got = append(got, rec)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(got))
+	}
+	if r := got[0]; !r.Time.Equal(recs[0].Time) || r.Principal != "u1" || r.Method != "POST" ||
+		string(r.Input) != `{"x":1}` || r.Status != 200 || r.Duration != 3*time.Millisecond || r.Params != nil {
+		t.Errorf("unexpected first row: %+v", r)
+	}
+	if r := got[1]; r.Principal != "" || r.Pattern != "/b/:id" || r.Params["id"] != "7" || r.Status != 401 || len(r.Input) != 0 {
+		t.Errorf("unexpected second row: %+v", r)
+	}
+}
+
+func TestSQLSinkPlaceholders(t *testing.T) {
+	s := &SQLSink{dialect: sqlutil.DialectPostgres, table: "audit_log"}
+	if got, want := s.q(`INSERT INTO audit_log (a, b) VALUES (?, ?)`), `INSERT INTO audit_log (a, b) VALUES ($1, $2)`; got != want {
+		t.Errorf("q() = %q, want %q", got, want)
+	}
+}
+
+func TestSQLSinkSchemaSQL(t *testing.T) {
+	t.Run("SQLite", func(t *testing.T) {
+		stmts := (&SQLSink{dialect: sqlutil.DialectSQLite, table: "app_audit"}).SchemaSQL()
+		if len(stmts) != 3 || !strings.Contains(stmts[0], "app_audit") || !strings.Contains(stmts[1], "app_audit_principal_idx") {
+			t.Errorf("unexpected schema: %v", stmts)
+		}
+	})
+
+	t.Run("MySQL", func(t *testing.T) {
+		stmts := (&SQLSink{dialect: sqlutil.DialectMySQL, table: "audit_log"}).SchemaSQL()
+		if len(stmts) != 1 || !strings.Contains(stmts[0], "INDEX audit_log_occurred_at_idx") {
+			t.Errorf("unexpected schema: %v", stmts)
+		}
+	})
+
+	t.Run("InvalidTableNamePanics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic")
+			}
+		}()
+		NewSQLSink(SQLSinkConfig{DB: new(sql.DB), TableName: "audit; DROP TABLE users"})
+	})
+}
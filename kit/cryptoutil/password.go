@@ -0,0 +1,282 @@
+package cryptoutil
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+/////////////////////////////////////////////////////////////////////
+/////// PASSWORD HASHING
+/////////////////////////////////////////////////////////////////////
+
+type PasswordAlgorithm string
+
+const (
+	PasswordAlgorithmArgon2id PasswordAlgorithm = "argon2id"
+	PasswordAlgorithmBcrypt   PasswordAlgorithm = "bcrypt"
+)
+
+var (
+	ErrPasswordHashInvalid     = errors.New("password hash is invalid")
+	ErrPasswordHashUnsupported = errors.New("password hash algorithm is unsupported")
+	ErrPasswordTooLong         = errors.New("password is too long")
+)
+
+// Argon2idParams are the tunable parameters of Argon2id. The zero value
+// of each field means "use the default", which follows the OWASP Password
+// Storage Cheat Sheet.
+type Argon2idParams struct {
+	MemoryKiB   uint32 // Defaults to 19456 (19 MiB)
+	Iterations  uint32 // Defaults to 2
+	Parallelism uint8  // Defaults to 1
+	SaltLength  uint32 // Defaults to 16
+	KeyLength   uint32 // Defaults to 32
+}
+
+const (
+	DefaultBcryptCost = 12
+	// Passwords longer than this are rejected rather than hashed, to
+	// bound the cost of hashing attacker-controlled input.
+	MaxPasswordLength = 1024
+	argon2idVersion   = argon2.Version
+)
+
+type PasswordHasherConfig struct {
+	// Algorithm for new hashes. Defaults to PasswordAlgorithmArgon2id.
+	// Either algorithm can always be verified.
+	Algorithm      PasswordAlgorithm
+	Argon2idParams Argon2idParams
+	BcryptCost     int // Defaults to DefaultBcryptCost
+	// Optional. Returns latest-first pepper keys, which are mixed into
+	// every password (via HMAC-SHA-256) before hashing, so that a leaked
+	// database alone is not enough to crack passwords. New hashes use the
+	// first pepper. Typically keyset.AppKeyset.HKDFKeys("password_pepper").
+	// Note that enabling peppering invalidates existing unpeppered hashes.
+	GetPeppers func() []Key32
+}
+
+// PasswordHasher hashes and verifies passwords, encoding hashes as
+// self-describing strings: PHC strings for Argon2id (e.g.,
+// "$argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>") and standard
+// modular crypt strings for bcrypt (e.g., "$2a$12$...").
+type PasswordHasher struct {
+	algorithm  PasswordAlgorithm
+	argon2id   Argon2idParams
+	bcryptCost int
+	getPeppers func() []Key32
+}
+
+// Panics if the config is invalid.
+func NewPasswordHasher(cfg PasswordHasherConfig) *PasswordHasher {
+	h := &PasswordHasher{
+		algorithm:  cfg.Algorithm,
+		argon2id:   cfg.Argon2idParams,
+		bcryptCost: cfg.BcryptCost,
+		getPeppers: cfg.GetPeppers,
+	}
+	if h.algorithm == "" {
+		h.algorithm = PasswordAlgorithmArgon2id
+	}
+	if h.algorithm != PasswordAlgorithmArgon2id && h.algorithm != PasswordAlgorithmBcrypt {
+		panic(fmt.Sprintf("cryptoutil: unsupported password algorithm %q", h.algorithm))
+	}
+	if h.argon2id.MemoryKiB == 0 {
+		h.argon2id.MemoryKiB = 19 * 1024
+	}
+	if h.argon2id.Iterations == 0 {
+		h.argon2id.Iterations = 2
+	}
+	if h.argon2id.Parallelism == 0 {
+		h.argon2id.Parallelism = 1
+	}
+	if h.argon2id.SaltLength == 0 {
+		h.argon2id.SaltLength = 16
+	}
+	if h.argon2id.KeyLength == 0 {
+		h.argon2id.KeyLength = 32
+	}
+	if h.bcryptCost == 0 {
+		h.bcryptCost = DefaultBcryptCost
+	}
+	if h.bcryptCost < bcrypt.MinCost || h.bcryptCost > bcrypt.MaxCost {
+		panic(fmt.Sprintf("cryptoutil: bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost))
+	}
+	return h
+}
+
+// Hash hashes password with the configured algorithm, parameters, and
+// (if any) latest pepper.
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	if len(password) > MaxPasswordLength {
+		return "", ErrPasswordTooLong
+	}
+	var pepper Key32
+	if peppers := h.peppers(); len(peppers) > 0 {
+		pepper = peppers[0]
+	}
+	input, err := pepperPassword(password, pepper)
+	if err != nil {
+		return "", err
+	}
+	if h.algorithm == PasswordAlgorithmBcrypt {
+		hash, err := bcrypt.GenerateFromPassword(input, h.bcryptCost)
+		if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+			return "", ErrPasswordTooLong
+		}
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	}
+	salt, err := RandomBytes(int(h.argon2id.SaltLength))
+	if err != nil {
+		return "", err
+	}
+	p := h.argon2id
+	key := argon2.IDKey(input, salt, p.Iterations, p.MemoryKiB, p.Parallelism, p.KeyLength)
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idVersion, p.MemoryKiB, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify reports whether password matches encodedHash, in constant time
+// with respect to the hash. If it matches, needsRehash reports whether
+// the hash should be replaced with a fresh Hash(password) (because it
+// uses an outdated algorithm, parameters, or pepper). A mismatch is not
+// an error; errors mean the hash is malformed or unsupported.
+func (h *PasswordHasher) Verify(password, encodedHash string) (ok, needsRehash bool, err error) {
+	if len(password) > MaxPasswordLength {
+		return false, false, nil
+	}
+	peppers := h.peppers()
+	if len(peppers) == 0 {
+		peppers = []Key32{nil}
+	}
+	for i, pepper := range peppers {
+		input, err := pepperPassword(password, pepper)
+		if err != nil {
+			return false, false, err
+		}
+		ok, err := verifyPasswordHash(input, encodedHash)
+		if err != nil {
+			return false, false, err
+		}
+		if ok {
+			return true, i > 0 || h.NeedsRehash(encodedHash), nil
+		}
+	}
+	return false, false, nil
+}
+
+// NeedsRehash reports whether encodedHash was produced with a different
+// algorithm or different parameters than the hasher is configured to
+// use. It can't detect an outdated pepper (see Verify). Malformed or
+// unsupported hashes always need a rehash.
+func (h *PasswordHasher) NeedsRehash(encodedHash string) bool {
+	if h.algorithm == PasswordAlgorithmBcrypt {
+		cost, err := bcrypt.Cost([]byte(encodedHash))
+		return err != nil || cost != h.bcryptCost
+	}
+	ph, err := parseArgon2idHash(encodedHash)
+	if err != nil {
+		return true
+	}
+	p := h.argon2id
+	return ph.version != argon2idVersion ||
+		ph.memoryKiB != p.MemoryKiB ||
+		ph.iterations != p.Iterations ||
+		ph.parallelism != p.Parallelism ||
+		uint32(len(ph.salt)) != p.SaltLength ||
+		uint32(len(ph.key)) != p.KeyLength
+}
+
+func (h *PasswordHasher) peppers() []Key32 {
+	if h.getPeppers == nil {
+		return nil
+	}
+	return h.getPeppers()
+}
+
+// Peppered passwords are HMAC-SHA-256'd and base64-encoded, which also
+// keeps them well under bcrypt's 72-byte limit.
+func pepperPassword(password string, pepper Key32) ([]byte, error) {
+	if pepper == nil {
+		return []byte(password), nil
+	}
+	mac, err := HmacSha256([]byte(password), pepper[:])
+	if err != nil {
+		return nil, err
+	}
+	return []byte(base64.RawStdEncoding.EncodeToString(mac)), nil
+}
+
+func verifyPasswordHash(input []byte, encodedHash string) (bool, error) {
+	switch {
+	case strings.HasPrefix(encodedHash, "$argon2id$"):
+		ph, err := parseArgon2idHash(encodedHash)
+		if err != nil {
+			return false, err
+		}
+		key := argon2.IDKey(input, ph.salt, ph.iterations, ph.memoryKiB, ph.parallelism, uint32(len(ph.key)))
+		return subtle.ConstantTimeCompare(key, ph.key) == 1, nil
+	case strings.HasPrefix(encodedHash, "$2a$"),
+		strings.HasPrefix(encodedHash, "$2b$"),
+		strings.HasPrefix(encodedHash, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(encodedHash), input)
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) || errors.Is(err, bcrypt.ErrPasswordTooLong) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("%w: %w", ErrPasswordHashInvalid, err)
+		}
+		return true, nil
+	default:
+		return false, ErrPasswordHashUnsupported
+	}
+}
+
+type argon2idHash struct {
+	version     int
+	memoryKiB   uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func parseArgon2idHash(encodedHash string) (*argon2idHash, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return nil, ErrPasswordHashInvalid
+	}
+	var ph argon2idHash
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &ph.version); err != nil {
+		return nil, ErrPasswordHashInvalid
+	}
+	if ph.version != argon2idVersion {
+		return nil, fmt.Errorf("%w: argon2id version %d", ErrPasswordHashUnsupported, ph.version)
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &ph.memoryKiB, &ph.iterations, &ph.parallelism); err != nil {
+		return nil, ErrPasswordHashInvalid
+	}
+	if ph.memoryKiB == 0 || ph.iterations == 0 || ph.parallelism == 0 {
+		return nil, ErrPasswordHashInvalid
+	}
+	var err error
+	if ph.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil || len(ph.salt) == 0 {
+		return nil, ErrPasswordHashInvalid
+	}
+	if ph.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(ph.key) == 0 {
+		return nil, ErrPasswordHashInvalid
+	}
+	return &ph, nil
+}
@@ -0,0 +1,163 @@
+package cryptoutil
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Cheap parameters to keep tests fast
+var testArgon2idParams = Argon2idParams{MemoryKiB: 64, Iterations: 1}
+
+func TestPasswordHasher(t *testing.T) {
+	hashers := map[string]*PasswordHasher{
+		"Argon2id": NewPasswordHasher(PasswordHasherConfig{Argon2idParams: testArgon2idParams}),
+		"Bcrypt":   NewPasswordHasher(PasswordHasherConfig{Algorithm: PasswordAlgorithmBcrypt, BcryptCost: 4}),
+	}
+	prefixes := map[string]string{"Argon2id": "$argon2id$v=19$m=64,t=1,p=1$", "Bcrypt": "$2a$04$"}
+
+	for name, h := range hashers {
+		t.Run(name, func(t *testing.T) {
+			hash, err := h.Hash("correct horse battery staple")
+			if err != nil {
+				t.Fatalf("Hash() error = %v", err)
+			}
+			if !strings.HasPrefix(hash, prefixes[name]) {
+				t.Errorf("Hash() = %q, want prefix %q", hash, prefixes[name])
+			}
+			if hash2, _ := h.Hash("correct horse battery staple"); hash2 == hash {
+				t.Error("hashes of the same password should be salted")
+			}
+
+			ok, needsRehash, err := h.Verify("correct horse battery staple", hash)
+			if err != nil || !ok || needsRehash {
+				t.Errorf("Verify(correct) = %v, %v, %v; want true, false, nil", ok, needsRehash, err)
+			}
+			ok, _, err = h.Verify("wrong", hash)
+			if err != nil || ok {
+				t.Errorf("Verify(wrong) = %v, %v; want false, nil", ok, err)
+			}
+			if h.NeedsRehash(hash) {
+				t.Error("NeedsRehash() should be false for a current hash")
+			}
+		})
+	}
+
+	t.Run("VerifiesEitherAlgorithm", func(t *testing.T) {
+		bcryptHash, _ := hashers["Bcrypt"].Hash("pw")
+		ok, needsRehash, err := hashers["Argon2id"].Verify("pw", bcryptHash)
+		if err != nil || !ok || !needsRehash {
+			t.Errorf("Verify() = %v, %v, %v; want true, true, nil", ok, needsRehash, err)
+		}
+	})
+
+	t.Run("NeedsRehashOnParamChange", func(t *testing.T) {
+		hash, _ := hashers["Argon2id"].Hash("pw")
+		stronger := NewPasswordHasher(PasswordHasherConfig{Argon2idParams: Argon2idParams{MemoryKiB: 128, Iterations: 1}})
+		if !stronger.NeedsRehash(hash) {
+			t.Error("NeedsRehash() should be true after raising memory cost")
+		}
+		if ok, needsRehash, _ := stronger.Verify("pw", hash); !ok || !needsRehash {
+			t.Errorf("Verify() = %v, %v; want true, true", ok, needsRehash)
+		}
+
+		bcryptHash, _ := hashers["Bcrypt"].Hash("pw")
+		if !NewPasswordHasher(PasswordHasherConfig{Algorithm: PasswordAlgorithmBcrypt, BcryptCost: 5}).NeedsRehash(bcryptHash) {
+			t.Error("NeedsRehash() should be true after raising bcrypt cost")
+		}
+	})
+
+	t.Run("InvalidHashes", func(t *testing.T) {
+		h := hashers["Argon2id"]
+		for _, hash := range []string{
+			"",
+			"plaintext",
+			"$argon2id$v=19$m=64,t=1,p=1$c2FsdA",
+			"$argon2id$v=18$m=64,t=1,p=1$c2FsdA$a2V5",
+			"$argon2id$v=19$m=0,t=1,p=1$c2FsdA$a2V5",
+			"$argon2id$v=19$m=64,t=1,p=1$!!!$a2V5",
+			"$2a$04$tooshort",
+		} {
+			ok, _, err := h.Verify("pw", hash)
+			if ok || err == nil {
+				t.Errorf("Verify(%q) = %v, %v; want false and an error", hash, ok, err)
+			}
+			if !h.NeedsRehash(hash) {
+				t.Errorf("NeedsRehash(%q) should be true", hash)
+			}
+		}
+		if _, _, err := h.Verify("pw", "$scrypt$ln=16$x$y"); !errors.Is(err, ErrPasswordHashUnsupported) {
+			t.Errorf("expected ErrPasswordHashUnsupported, got %v", err)
+		}
+	})
+
+	t.Run("TooLong", func(t *testing.T) {
+		long := strings.Repeat("a", MaxPasswordLength+1)
+		if _, err := hashers["Argon2id"].Hash(long); !errors.Is(err, ErrPasswordTooLong) {
+			t.Errorf("expected ErrPasswordTooLong, got %v", err)
+		}
+		if _, err := hashers["Bcrypt"].Hash(strings.Repeat("a", 73)); !errors.Is(err, ErrPasswordTooLong) {
+			t.Errorf("expected ErrPasswordTooLong from bcrypt, got %v", err)
+		}
+	})
+
+	t.Run("InvalidConfig", func(t *testing.T) {
+		for _, cfg := range []PasswordHasherConfig{
+			{Algorithm: "md5"},
+			{Algorithm: PasswordAlgorithmBcrypt, BcryptCost: 1},
+		} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("expected panic for %+v", cfg)
+					}
+				}()
+				NewPasswordHasher(cfg)
+			}()
+		}
+	})
+}
+
+func TestPasswordHasherPepper(t *testing.T) {
+	oldPepper, newPepper := new32(), new32()
+	newPepper[0] = 99
+	peppers := []Key32{oldPepper}
+
+	for _, algorithm := range []PasswordAlgorithm{PasswordAlgorithmArgon2id, PasswordAlgorithmBcrypt} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			peppers = []Key32{oldPepper}
+			h := NewPasswordHasher(PasswordHasherConfig{
+				Algorithm:      algorithm,
+				Argon2idParams: testArgon2idParams,
+				BcryptCost:     4,
+				GetPeppers:     func() []Key32 { return peppers },
+			})
+			// Longer than bcrypt's 72-byte limit, which peppering sidesteps
+			password := strings.Repeat("p", 100)
+			hash, err := h.Hash(password)
+			if err != nil {
+				t.Fatalf("Hash() error = %v", err)
+			}
+
+			unpeppered := NewPasswordHasher(PasswordHasherConfig{Algorithm: algorithm, Argon2idParams: testArgon2idParams, BcryptCost: 4})
+			if ok, _, _ := unpeppered.Verify(password, hash); ok {
+				t.Error("peppered hash should not verify without the pepper")
+			}
+
+			// Rotate: new pepper first, old pepper still accepted
+			peppers = []Key32{newPepper, oldPepper}
+			ok, needsRehash, err := h.Verify(password, hash)
+			if err != nil || !ok || !needsRehash {
+				t.Errorf("Verify() with old pepper = %v, %v, %v; want true, true, nil", ok, needsRehash, err)
+			}
+			rehashed, _ := h.Hash(password)
+			ok, needsRehash, _ = h.Verify(password, rehashed)
+			if !ok || needsRehash {
+				t.Errorf("Verify() with new pepper = %v, %v; want true, false", ok, needsRehash)
+			}
+			if ok, _, _ := h.Verify("wrong", rehashed); ok {
+				t.Error("wrong password should not verify")
+			}
+		})
+	}
+}
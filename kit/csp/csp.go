@@ -0,0 +1,280 @@
+// Package csp builds Content-Security-Policy headers per request. Middleware
+// generates a nonce for each request and attaches a Policy to the request
+// context, to which handlers (River does this automatically) add the hashes
+// of any inline scripts and styles they render. The header is written just
+// before the response is, so everything added by then is included.
+package csp
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/river-now/river/kit/bytesutil"
+	"github.com/river-now/river/kit/contextutil"
+	"github.com/river-now/river/kit/cryptoutil"
+	"github.com/river-now/river/kit/response"
+)
+
+const (
+	HeaderName           = "Content-Security-Policy"
+	ReportOnlyHeaderName = "Content-Security-Policy-Report-Only"
+	// The Reporting API endpoint name used when Config.ReportURI is set.
+	ReportToGroup = "csp-endpoint"
+
+	nonceSize = 16 // Size, in bytes, of per-request nonces.
+)
+
+// DefaultDirectives returns a strict baseline policy: everything from the
+// same origin only, no plugins, no framing, and no <base> hijacking. Inline
+// scripts and styles are allowed only by hash or nonce.
+func DefaultDirectives() map[string][]string {
+	return map[string][]string{
+		"default-src":     {"'self'"},
+		"script-src":      {"'self'"},
+		"style-src":       {"'self'"},
+		"img-src":         {"'self'", "data:"},
+		"object-src":      {"'none'"},
+		"base-uri":        {"'self'"},
+		"frame-ancestors": {"'none'"},
+		"form-action":     {"'self'"},
+	}
+}
+
+type Config struct {
+	// The base policy, as a map of directive names to sources. Defaults to
+	// DefaultDirectives().
+	Directives map[string][]string
+	// If true, sends Content-Security-Policy-Report-Only instead, so
+	// violations are reported but not enforced. Useful for rolling out a
+	// policy, and in development. See also Policy.SetReportOnly.
+	ReportOnly bool
+	// Optional. Adds report-uri and report-to directives (plus a matching
+	// Reporting-Endpoints header) pointing here. Serve ReportHandler at
+	// this path.
+	ReportURI string
+	// Directives that get the per-request nonce. Defaults to script-src and
+	// style-src.
+	NonceDirectives []string
+}
+
+type Builder struct {
+	cfg Config
+}
+
+// Panics if ReportURI contains characters that would break the header.
+func NewBuilder(cfg Config) *Builder {
+	if cfg.Directives == nil {
+		cfg.Directives = DefaultDirectives()
+	}
+	if cfg.NonceDirectives == nil {
+		cfg.NonceDirectives = []string{"script-src", "style-src"}
+	}
+	if strings.ContainsAny(cfg.ReportURI, " ;,\"\r\n") {
+		panic(fmt.Sprintf("csp: invalid ReportURI %q", cfg.ReportURI))
+	}
+	return &Builder{cfg: cfg}
+}
+
+var contextStore = contextutil.NewStore[*Policy]("csp")
+
+// Middleware attaches a fresh Policy (with a new nonce) to each request's
+// context, and writes the policy header when the response is written.
+func (b *Builder) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := b.NewPolicy()
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		hw := response.NewHookWriter(w, func(int) { b.SetHeaders(w, p) })
+		next.ServeHTTP(hw, contextStore.GetRequestWithContext(r, p))
+		if !hw.WroteHeader() {
+			b.SetHeaders(w, p)
+		}
+	})
+}
+
+// FrameAncestors returns a middleware that replaces the frame-ancestors
+// directive for the requests it wraps, so that a route can be embedded by
+// the given origins. Register it at the pattern level (with
+// mux.SetPatternLevelHTTPMiddleware), so it runs after Middleware, and
+// omit X-Frame-Options for the same route (see secureheaders' Override),
+// since browsers enforce both. Example:
+//
+//	mux.SetPatternLevelHTTPMiddleware(embedRoute, csp.FrameAncestors("https://partner.example.com"))
+//	mux.SetPatternLevelHTTPMiddleware(embedRoute, headers.Override(secureheaders.Config{FrameOptions: secureheaders.Omit}))
+func FrameAncestors(sources ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			FromContext(r.Context()).SetSources("frame-ancestors", sources...)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// NewPolicy returns a fresh Policy with a new nonce. Middleware calls this
+// for you.
+func (b *Builder) NewPolicy() (*Policy, error) {
+	nonceBytes, err := cryptoutil.RandomBytes(nonceSize)
+	if err != nil {
+		return nil, fmt.Errorf("csp: failed to generate nonce: %w", err)
+	}
+	p := &Policy{
+		directives: make(map[string][]string, len(b.cfg.Directives)),
+		nonce:      bytesutil.ToBase64(nonceBytes),
+	}
+	for name, sources := range b.cfg.Directives {
+		p.directives[name] = slices.Clone(sources)
+	}
+	for _, name := range b.cfg.NonceDirectives {
+		p.add(name, "'nonce-"+p.nonce+"'")
+	}
+	if b.cfg.ReportURI != "" {
+		p.directives["report-uri"] = []string{b.cfg.ReportURI}
+		p.directives["report-to"] = []string{ReportToGroup}
+	}
+	return p, nil
+}
+
+// Applies the policy headers to w.
+func (b *Builder) SetHeaders(w http.ResponseWriter, p *Policy) {
+	name := HeaderName
+	if b.cfg.ReportOnly || p.isReportOnly() {
+		name = ReportOnlyHeaderName
+	}
+	w.Header().Set(name, p.String())
+	if b.cfg.ReportURI != "" {
+		w.Header().Set("Reporting-Endpoints", fmt.Sprintf(`%s="%s"`, ReportToGroup, b.cfg.ReportURI))
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// POLICY
+/////////////////////////////////////////////////////////////////////
+
+// Policy is a single request's policy. It is safe for concurrent use, and
+// its methods are no-ops on a nil Policy, so you can always call
+// FromContext(ctx).AddScriptHash(...) and friends, whether or not the
+// request went through Middleware.
+type Policy struct {
+	mu         sync.Mutex
+	directives map[string][]string
+	nonce      string
+	reportOnly bool
+}
+
+// FromContext returns the request's Policy, or nil if the request didn't go
+// through Middleware.
+func FromContext(ctx context.Context) *Policy {
+	return contextStore.GetValueFromContext(ctx)
+}
+
+// Nonce returns the request's nonce, for use in nonce attributes on inline
+// <script> and <style> elements. Returns an empty string on a nil Policy.
+func (p *Policy) Nonce() string {
+	if p == nil {
+		return ""
+	}
+	return p.nonce
+}
+
+// AddScriptHash allows an inline script by the base64-encoded SHA-256 hash
+// of its contents. Empty hashes are ignored.
+func (p *Policy) AddScriptHash(sha256Base64 string) {
+	if sha256Base64 != "" {
+		p.AddSource("script-src", "'sha256-"+sha256Base64+"'")
+	}
+}
+
+// AddStyleHash allows an inline style by the base64-encoded SHA-256 hash of
+// its contents. Empty hashes are ignored.
+func (p *Policy) AddStyleHash(sha256Base64 string) {
+	if sha256Base64 != "" {
+		p.AddSource("style-src", "'sha256-"+sha256Base64+"'")
+	}
+}
+
+// AddSource adds a source to a directive for this request only (e.g.,
+// AddSource("img-src", "https://cdn.example.com")). Duplicates are ignored.
+func (p *Policy) AddSource(directive, source string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.add(directive, source)
+}
+
+// SetSources replaces a directive's sources for this request only (e.g.,
+// SetSources("frame-ancestors", "https://partner.example.com")).
+func (p *Policy) SetSources(directive string, sources ...string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.directives[directive] = slices.Clone(sources)
+}
+
+// SetReportOnly sends this request's policy report-only, regardless of
+// Config.ReportOnly. River does this in dev, where Vite injects styles that
+// no strict policy allows.
+func (p *Policy) SetReportOnly() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reportOnly = true
+}
+
+func (p *Policy) isReportOnly() bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.reportOnly
+}
+
+func (p *Policy) add(directive, source string) {
+	// Directives fall back to default-src only when absent, so seed new
+	// fetch directives from it to avoid loosening or tightening anything
+	// by accident.
+	sources, ok := p.directives[directive]
+	if !ok && strings.HasSuffix(directive, "-src") {
+		sources = slices.Clone(p.directives["default-src"])
+	}
+	// 'none' can't be combined with other sources, so replace it.
+	if len(sources) == 1 && sources[0] == "'none'" {
+		sources = nil
+	}
+	if !slices.Contains(sources, source) {
+		sources = append(sources, source)
+	}
+	p.directives[directive] = sources
+}
+
+// String serializes the policy, with directives sorted by name. Returns an
+// empty string on a nil Policy.
+func (p *Policy) String() string {
+	if p == nil {
+		return ""
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	parts := make([]string, 0, len(p.directives))
+	for _, name := range slices.Sorted(maps.Keys(p.directives)) {
+		if sources := p.directives[name]; len(sources) > 0 {
+			parts = append(parts, name+" "+strings.Join(sources, " "))
+		} else {
+			parts = append(parts, name)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,206 @@
+package csp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	b := NewBuilder(Config{})
+
+	var nonce string
+	handler := b.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := FromContext(r.Context())
+		nonce = p.Nonce()
+		p.AddScriptHash("c2NyaXB0")
+		p.AddScriptHash("c2NyaXB0")
+		p.AddScriptHash("")
+		p.AddStyleHash("c3R5bGU=")
+		p.AddSource("connect-src", "https://api.example.com")
+		w.Write([]byte("ok"))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if nonce == "" {
+		t.Fatal("expected a nonce")
+	}
+	got := w.Header().Get(HeaderName)
+	for _, want := range []string{
+		"script-src 'self' 'nonce-" + nonce + "' 'sha256-c2NyaXB0'; ",
+		"style-src 'self' 'nonce-" + nonce + "' 'sha256-c3R5bGU='",
+		"connect-src 'self' https://api.example.com; ",
+		"object-src 'none'; ",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("header %q does not contain %q", got, want)
+		}
+	}
+	if !strings.HasPrefix(got, "base-uri 'self'; connect-src") {
+		t.Errorf("directives should be sorted, got %q", got)
+	}
+
+	t.Run("FreshNoncePerRequest", func(t *testing.T) {
+		first := nonce
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		if nonce == first {
+			t.Error("expected a new nonce for each request")
+		}
+	})
+
+	t.Run("NoWrite", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		b.Middleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).
+			ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Header().Get(HeaderName) == "" {
+			t.Error("expected header even if the handler writes nothing")
+		}
+	})
+}
+
+func TestReportOnlyAndReportURI(t *testing.T) {
+	b := NewBuilder(Config{
+		Directives: map[string][]string{"default-src": {"'self'"}, "upgrade-insecure-requests": nil},
+		ReportOnly: true,
+		ReportURI:  "/csp-report",
+	})
+	w := httptest.NewRecorder()
+	b.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Header().Get(HeaderName) != "" {
+		t.Error("report-only mode should not send an enforcing header")
+	}
+	got := w.Header().Get(ReportOnlyHeaderName)
+	// Nonce directives absent from the base policy are seeded from default-src
+	for _, want := range []string{"report-to csp-endpoint", "report-uri /csp-report", "script-src 'self' 'nonce-", "; upgrade-insecure-requests"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("header %q does not contain %q", got, want)
+		}
+	}
+	if got := w.Header().Get("Reporting-Endpoints"); got != `csp-endpoint="/csp-report"` {
+		t.Errorf("Reporting-Endpoints = %q", got)
+	}
+
+	t.Run("InvalidReportURI", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic")
+			}
+		}()
+		NewBuilder(Config{ReportURI: "/x; script-src *"})
+	})
+}
+
+func TestAddSourceReplacesNone(t *testing.T) {
+	p, err := NewBuilder(Config{}).NewPolicy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.AddSource("frame-ancestors", "https://embed.example.com")
+	p.AddSource("object-src", "'none'")
+	got := p.String()
+	for _, want := range []string{"frame-ancestors https://embed.example.com; ", "object-src 'none'; "} {
+		if !strings.Contains(got, want) {
+			t.Errorf("policy %q does not contain %q", got, want)
+		}
+	}
+}
+
+func TestPolicySetReportOnly(t *testing.T) {
+	b := NewBuilder(Config{})
+	handler := b.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/dev" {
+			FromContext(r.Context()).SetReportOnly()
+		}
+	}))
+	for path, wantHeader := range map[string]string{"/": HeaderName, "/dev": ReportOnlyHeaderName} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Header().Get(wantHeader) == "" || len(w.Header()) != 1 {
+			t.Errorf("%s: headers = %v, want only %s", path, w.Header(), wantHeader)
+		}
+	}
+}
+
+func TestMiddlewareFlush(t *testing.T) {
+	w := httptest.NewRecorder()
+	NewBuilder(Config{}).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+	})).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !w.Flushed || w.Header().Get(HeaderName) == "" {
+		t.Errorf("flushed = %v, headers = %v", w.Flushed, w.Header())
+	}
+}
+
+func TestNilPolicy(t *testing.T) {
+	var p *Policy
+	p.AddScriptHash("abc")
+	p.AddSource("img-src", "*")
+	p.SetReportOnly()
+	p.SetSources("frame-ancestors", "*")
+	if p.Nonce() != "" || p.String() != "" {
+		t.Error("nil Policy should be a no-op")
+	}
+	if FromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context()) != nil {
+		t.Error("expected nil Policy without Middleware")
+	}
+}
+
+func TestReportHandler(t *testing.T) {
+	var reports []Report
+	handler := ReportHandler(func(r *http.Request, report Report) {
+		reports = append(reports, report)
+	})
+	post := func(contentType, body string) int {
+		r := httptest.NewRequest(http.MethodPost, "/csp-report", strings.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	t.Run("Legacy", func(t *testing.T) {
+		reports = nil
+		code := post("application/csp-report", `{"csp-report":{"document-uri":"https://example.com/","blocked-uri":"inline","violated-directive":"script-src-elem","line-number":3}}`)
+		if code != http.StatusNoContent || len(reports) != 1 {
+			t.Fatalf("code = %d, reports = %+v", code, reports)
+		}
+		want := Report{DocumentURL: "https://example.com/", BlockedURL: "inline", EffectiveDirective: "script-src-elem", LineNumber: 3}
+		if reports[0] != want {
+			t.Errorf("report = %+v, want %+v", reports[0], want)
+		}
+	})
+
+	t.Run("ReportingAPI", func(t *testing.T) {
+		reports = nil
+		code := post("application/reports+json", `[
+			{"type":"csp-violation","body":{"documentURL":"https://example.com/","effectiveDirective":"style-src-elem","disposition":"report"}},
+			{"type":"deprecation","body":{"id":"x"}}
+		]`)
+		if code != http.StatusNoContent || len(reports) != 1 {
+			t.Fatalf("code = %d, reports = %+v", code, reports)
+		}
+		if reports[0].EffectiveDirective != "style-src-elem" || reports[0].Disposition != "report" {
+			t.Errorf("report = %+v", reports[0])
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		if code := post("application/csp-report", `{}`); code != http.StatusBadRequest {
+			t.Errorf("empty report: code = %d, want 400", code)
+		}
+		if code := post("application/reports+json", `not json`); code != http.StatusBadRequest {
+			t.Errorf("malformed report: code = %d, want 400", code)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/csp-report", nil))
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("GET: code = %d, want 405", w.Code)
+		}
+	})
+}
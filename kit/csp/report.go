@@ -0,0 +1,127 @@
+package csp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+)
+
+/////////////////////////////////////////////////////////////////////
+/////// REPORTS
+/////////////////////////////////////////////////////////////////////
+
+const maxReportBodySize = 64 << 10 // 64KB
+
+// Report is a CSP violation report, normalized from either the legacy
+// report-uri format or the Reporting API (report-to) format.
+type Report struct {
+	DocumentURL        string `json:"documentURL"`
+	Referrer           string `json:"referrer,omitempty"`
+	BlockedURL         string `json:"blockedURL,omitempty"`
+	EffectiveDirective string `json:"effectiveDirective"`
+	OriginalPolicy     string `json:"originalPolicy,omitempty"`
+	Disposition        string `json:"disposition,omitempty"` // "enforce" or "report"
+	SourceFile         string `json:"sourceFile,omitempty"`
+	LineNumber         int    `json:"lineNumber,omitempty"`
+	ColumnNumber       int    `json:"columnNumber,omitempty"`
+	Sample             string `json:"sample,omitempty"`
+	StatusCode         int    `json:"statusCode,omitempty"`
+}
+
+// application/csp-report
+type legacyReport struct {
+	CSPReport struct {
+		DocumentURI        string `json:"document-uri"`
+		Referrer           string `json:"referrer"`
+		BlockedURI         string `json:"blocked-uri"`
+		ViolatedDirective  string `json:"violated-directive"`
+		EffectiveDirective string `json:"effective-directive"`
+		OriginalPolicy     string `json:"original-policy"`
+		Disposition        string `json:"disposition"`
+		SourceFile         string `json:"source-file"`
+		LineNumber         int    `json:"line-number"`
+		ColumnNumber       int    `json:"column-number"`
+		ScriptSample       string `json:"script-sample"`
+		StatusCode         int    `json:"status-code"`
+	} `json:"csp-report"`
+}
+
+// application/reports+json
+type reportingAPIReport struct {
+	Type string `json:"type"`
+	Body Report `json:"body"`
+}
+
+// ReportHandler returns a handler for CSP violation reports (see
+// Config.ReportURI), which accepts both the legacy report-uri format and the
+// Reporting API format, and calls onReport with each report. Malformed
+// reports are rejected with a 400. onReport should return quickly (e.g., by
+// logging), since browsers may send many reports.
+func ReportHandler(onReport func(r *http.Request, report Report)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxReportBodySize))
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		reports, err := parseReports(r.Header.Get("Content-Type"), body)
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		for _, report := range reports {
+			onReport(r, report)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func parseReports(contentType string, body []byte) ([]Report, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/reports+json" {
+		var batch []reportingAPIReport
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, err
+		}
+		reports := make([]Report, 0, len(batch))
+		for _, item := range batch {
+			if item.Type == "csp-violation" {
+				reports = append(reports, item.Body)
+			}
+		}
+		return reports, nil
+	}
+
+	var legacy legacyReport
+	if err := json.Unmarshal(body, &legacy); err != nil {
+		return nil, err
+	}
+	l := legacy.CSPReport
+	if l.DocumentURI == "" {
+		return nil, errors.New("missing csp-report")
+	}
+	effectiveDirective := l.EffectiveDirective
+	if effectiveDirective == "" {
+		effectiveDirective = l.ViolatedDirective
+	}
+	return []Report{{
+		DocumentURL:        l.DocumentURI,
+		Referrer:           l.Referrer,
+		BlockedURL:         l.BlockedURI,
+		EffectiveDirective: effectiveDirective,
+		OriginalPolicy:     l.OriginalPolicy,
+		Disposition:        l.Disposition,
+		SourceFile:         l.SourceFile,
+		LineNumber:         l.LineNumber,
+		ColumnNumber:       l.ColumnNumber,
+		Sample:             l.ScriptSample,
+		StatusCode:         l.StatusCode,
+	}}, nil
+}
@@ -1,10 +1,13 @@
 package executil
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sync"
 )
 
 func MakeCmdRunner(commands ...string) func() error {
@@ -30,3 +33,31 @@ func GetExecutableDir() (string, error) {
 func RunCmd(commands ...string) error {
 	return MakeCmdRunner(commands...)()
 }
+
+// Same as RunCmd, but also returns the command's combined stdout and stderr,
+// which are still streamed to the terminal as usual.
+func RunCmdCapturingOutput(commands ...string) (string, error) {
+	if len(commands) == 0 {
+		return "", fmt.Errorf("no commands provided")
+	}
+	var output bytes.Buffer
+	// One writer for both streams, so os/exec shares a single pipe between
+	// them and lines aren't interleaved mid-write.
+	w := &lockedWriter{w: io.MultiWriter(os.Stdout, &output)}
+	cmd := exec.Command(commands[0], commands[1:]...)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	err := cmd.Run()
+	return output.String(), err
+}
+
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	return lw.w.Write(p)
+}
@@ -2,6 +2,7 @@ package executil
 
 import (
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -19,6 +20,28 @@ func TestMakeCmdRunner(t *testing.T) {
 	}
 }
 
+func TestRunCmdCapturingOutput(t *testing.T) {
+	output, err := RunCmdCapturingOutput("sh", "-c", "echo out; echo err >&2")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(output, "out\n") || !strings.Contains(output, "err\n") {
+		t.Errorf("expected stdout and stderr in output, got %q", output)
+	}
+
+	output, err = RunCmdCapturingOutput("sh", "-c", "echo failing; exit 1")
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	if !strings.Contains(output, "failing") {
+		t.Errorf("expected output of failed command, got %q", output)
+	}
+
+	if _, err := RunCmdCapturingOutput(); err == nil {
+		t.Fatalf("expected error for no commands, got nil")
+	}
+}
+
 func TestGetExecutableDir(t *testing.T) {
 	// Get the current executable's directory
 	execDir, err := GetExecutableDir()
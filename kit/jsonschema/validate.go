@@ -0,0 +1,191 @@
+package jsonschema
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type ValidationError struct {
+	Pointer string // JSON pointer (RFC 6901), e.g., "/Watch/Include/0/Pattern"
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	pointer := e.Pointer
+	if pointer == "" {
+		pointer = "/"
+	}
+	return pointer + ": " + e.Message
+}
+
+// Validates data (as decoded by encoding/json into an any) against the
+// subset of JSON schema this package produces: types, required children,
+// properties (unknown properties are reported), array items, and enums.
+// AllOf conditions are not evaluated. Returns every problem found, in a
+// stable order.
+func Validate(schema Entry, data any) []ValidationError {
+	var errs []ValidationError
+	validate(schema, data, "", &errs)
+	return errs
+}
+
+func validate(schema Entry, data any, pointer string, errs *[]ValidationError) {
+	add := func(format string, args ...any) {
+		*errs = append(*errs, ValidationError{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if schema.Type != "" && !isType(schema.Type, data) {
+		add("expected %s, got %s", schema.Type, typeName(data))
+		return
+	}
+
+	if len(schema.Enum) > 0 {
+		if s, ok := data.(string); ok && !slices.Contains(schema.Enum, s) {
+			add("must be one of %s, got %q", toOxfordList(schema.Enum, "or"), s)
+		}
+	}
+
+	switch v := data.(type) {
+	case map[string]any:
+		props := propertiesOf(schema)
+		for _, name := range schema.Required {
+			if _, ok := v[name]; !ok {
+				add("missing required property %q", name)
+			}
+		}
+		if props == nil {
+			return
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			childPointer := pointer + "/" + EscapePointerToken(k)
+			child, ok := props[k]
+			if !ok {
+				if k == "$schema" && pointer == "" {
+					continue
+				}
+				msg := "unknown property"
+				if suggestion := closestName(k, props); suggestion != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+				}
+				*errs = append(*errs, ValidationError{Pointer: childPointer, Message: msg})
+				continue
+			}
+			validate(child, v[k], childPointer, errs)
+		}
+	case []any:
+		items, ok := schema.Items.(Entry)
+		if !ok || items.Type == "" {
+			return
+		}
+		for i, item := range v {
+			validate(items, item, pointer+"/"+strconv.Itoa(i), errs)
+		}
+	}
+}
+
+// Escapes a single JSON pointer reference token ("~" -> "~0", "/" -> "~1").
+func EscapePointerToken(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+func isType(t string, data any) bool {
+	switch t {
+	case TypeObject:
+		_, ok := data.(map[string]any)
+		return ok
+	case TypeString:
+		_, ok := data.(string)
+		return ok
+	case TypeBoolean:
+		_, ok := data.(bool)
+		return ok
+	case TypeArray:
+		_, ok := data.([]any)
+		return ok
+	case TypeNumber:
+		_, ok := data.(float64)
+		return ok
+	}
+	return true
+}
+
+func typeName(data any) string {
+	switch data.(type) {
+	case map[string]any:
+		return TypeObject
+	case string:
+		return TypeString
+	case bool:
+		return TypeBoolean
+	case []any:
+		return TypeArray
+	case float64:
+		return TypeNumber
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", data)
+}
+
+// Properties are defined as (anonymous) structs with Entry fields, keyed by
+// field name.
+func propertiesOf(schema Entry) map[string]Entry {
+	if schema.Properties == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(schema.Properties)
+	if rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	props := make(map[string]Entry, rv.NumField())
+	for i := range rv.NumField() {
+		if entry, ok := rv.Field(i).Interface().(Entry); ok {
+			props[rv.Type().Field(i).Name] = entry
+		}
+	}
+	return props
+}
+
+// Returns the property name closest to name (case-insensitive edit
+// distance of at most 2), or an empty string.
+func closestName(name string, props map[string]Entry) string {
+	best, bestDist := "", 3
+	for candidate := range props {
+		d := levenshtein(strings.ToLower(name), strings.ToLower(candidate))
+		if d < bestDist || (d == bestDist && candidate < best) {
+			best, bestDist = candidate, d
+		}
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,161 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+var testSchema = Entry{
+	Type:     TypeObject,
+	Required: []string{"Name", "Watch"},
+	Properties: struct {
+		Name    Entry
+		Mode    Entry
+		Enabled Entry
+		Port    Entry
+		Watch   Entry
+	}{
+		Name:    RequiredString(Def{}),
+		Mode:    OptionalString(Def{Enum: []string{"dev", "prod"}}),
+		Enabled: OptionalBoolean(Def{}),
+		Port:    OptionalNumber(Def{}),
+		Watch: RequiredObject(Def{
+			RequiredChildren: []string{"Include"},
+			Properties: struct {
+				Include Entry
+			}{
+				Include: RequiredArray(Def{
+					Items: ToJSONSchema(Def{
+						Type:             TypeObject,
+						RequiredChildren: []string{"Pattern"},
+						Properties: struct {
+							Pattern Entry
+						}{},
+					}),
+				}),
+			},
+		}),
+	},
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []ValidationError
+	}{
+		{
+			name: "Valid",
+			data: `{"$schema":"x","Name":"app","Mode":"dev","Enabled":true,"Port":3000,"Watch":{"Include":[{"Pattern":"**/*.go"}]}}`,
+		},
+		{
+			name: "TypeMismatches",
+			data: `{"Name":1,"Enabled":"yes","Port":"80","Watch":[]}`,
+			want: []ValidationError{
+				{"/Enabled", "expected boolean, got string"},
+				{"/Name", "expected string, got number"},
+				{"/Port", "expected number, got string"},
+				{"/Watch", "expected object, got array"},
+			},
+		},
+		{
+			name: "NullIsATypeMismatch",
+			data: `{"Name":null,"Watch":{"Include":[]}}`,
+			want: []ValidationError{{"/Name", "expected string, got null"}},
+		},
+		{
+			name: "RootTypeMismatch",
+			data: `[]`,
+			want: []ValidationError{{"", "expected object, got array"}},
+		},
+		{
+			name: "RequiredFields",
+			data: `{"Watch":{}}`,
+			want: []ValidationError{
+				{"", `missing required property "Name"`},
+				{"/Watch", `missing required property "Include"`},
+			},
+		},
+		{
+			name: "Enum",
+			data: `{"Name":"app","Mode":"staging","Watch":{"Include":[]}}`,
+			want: []ValidationError{{"/Mode", `must be one of "dev" or "prod", got "staging"`}},
+		},
+		{
+			name: "UnknownPropertyWithSuggestion",
+			data: `{"Name":"app","Enabeld":true,"Watch":{"Include":[]}}`,
+			want: []ValidationError{{"/Enabeld", `unknown property (did you mean "Enabled"?)`}},
+		},
+		{
+			name: "UnknownPropertyWithoutSuggestion",
+			data: `{"Name":"app","Totally":1,"Watch":{"Include":[]}}`,
+			want: []ValidationError{{"/Totally", "unknown property"}},
+		},
+		{
+			name: "SchemaKeyOnlyAllowedAtRoot",
+			data: `{"Name":"app","Watch":{"$schema":"x","Include":[]}}`,
+			want: []ValidationError{{"/Watch/$schema", "unknown property"}},
+		},
+		{
+			name: "ArrayItemPointers",
+			data: `{"Name":"app","Watch":{"Include":[{"Pattern":"a"},{},"oops"]}}`,
+			want: []ValidationError{
+				{"/Watch/Include/1", `missing required property "Pattern"`},
+				{"/Watch/Include/2", "expected object, got string"},
+			},
+		},
+		{
+			name: "PointerTokensAreEscaped",
+			data: `{"Name":"app","Watch":{"Include":[{"Pattern":"a","x/y~z":1}]}}`,
+			want: []ValidationError{{"/Watch/Include/0/x~1y~0z", "unknown property"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data any
+			if err := json.Unmarshal([]byte(tt.data), &data); err != nil {
+				t.Fatalf("bad test data: %v", err)
+			}
+			got := Validate(testSchema, data)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() =\n  %v\nwant\n  %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationErrorString(t *testing.T) {
+	if got := (ValidationError{Pointer: "/A/0", Message: "bad"}).Error(); got != "/A/0: bad" {
+		t.Errorf("Error() = %q", got)
+	}
+	if got := (ValidationError{Message: "bad"}).Error(); got != "/: bad" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestEscapePointerToken(t *testing.T) {
+	if got := EscapePointerToken("a/b~c"); got != "a~1b~0c" {
+		t.Errorf("EscapePointerToken() = %q, want %q", got, "a~1b~0c")
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"Enabeld", "Enabled", 2},
+		{"same", "same", 0},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+// Command keygen prints new random base64-encoded 32-byte root secrets,
+// one per line, for use with the keyset package.
+//
+// Usage:
+//
+//	keygen [-n 1]
+//
+// To rotate, prepend the new secret to your latest-first list of root
+// secrets (e.g., set CURRENT_SECRET to it and move the old value to
+// PREVIOUS_SECRET).
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/river-now/river/kit/keyset"
+)
+
+func main() {
+	n := flag.Int("n", 1, "number of secrets to generate")
+	flag.Parse()
+
+	for range *n {
+		secret, err := keyset.GenerateRootSecret()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error generating root secret: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(secret)
+	}
+}
@@ -1,6 +1,7 @@
 package keyset
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -11,11 +12,13 @@ import (
 )
 
 // Base64-encoded 32-byte root secret.
-// To generate new root secrets, run `openssl rand -base64 32`.
+// To generate new root secrets, run `openssl rand -base64 32` or
+// `go run github.com/river-now/river/kit/keyset/cmd/keygen`.
 type RootSecret = string
 
 // Latest-first slice of base64-encoded 32-byte root secrets.
-// To generate new root secrets, run `openssl rand -base64 32`.
+// To generate new root secrets, run `openssl rand -base64 32` or
+// `go run github.com/river-now/river/kit/keyset/cmd/keygen`.
 type RootSecrets []RootSecret
 
 // Latest-first slice of size 32 byte array pointers
@@ -25,7 +28,12 @@ type UnwrappedKeyset []cryptoutil.Key32
 /////// KEYSET WRAPPER
 /////////////////////////////////////////////////////////////////////
 
-type Keyset struct{ uks UnwrappedKeyset }
+type Keyset struct {
+	uks UnwrappedKeyset
+	// The HKDF info string this keyset was derived with, if any. Reported
+	// in FallbackEvents.
+	purpose string
+}
 
 func FromUnwrapped(uks UnwrappedKeyset) (*Keyset, error) {
 	ks := &Keyset{uks: uks}
@@ -81,25 +89,38 @@ func (wk *Keyset) First() (cryptoutil.Key32, error) {
 // until either (i) an attempt does not return an error (meaning
 // it succeeded) or (ii) all keys have been attempted. This is
 // useful when you want to fallback to a prior key if the current
-// key fails due to a recent rotation.
+// key fails due to a recent rotation. Successes are recorded in
+// KeyUsage, and successes with a non-primary key are reported to
+// the hook set with SetFallbackHook.
 func Attempt[R any](ks *Keyset, f func(cryptoutil.Key32) (R, error)) (R, error) {
+	result, _, err := AttemptWithKeyIndex(ks, f)
+	return result, err
+}
+
+// AttemptWithKeyIndex is like Attempt, but also returns the index of
+// the key that succeeded. An index greater than 0 means the result
+// was produced with a non-primary key, and should be re-encrypted or
+// re-signed with the primary key if you want to be able to drop the
+// older key.
+func AttemptWithKeyIndex[R any](ks *Keyset, f func(cryptoutil.Key32) (R, error)) (R, int, error) {
 	var zeroR R
 	uks := ks.Unwrap()
 	if len(uks) == 0 {
-		return zeroR, fmt.Errorf("keyset is empty")
+		return zeroR, -1, fmt.Errorf("keyset is empty")
 	}
 	var errs []error
 	for i, k := range uks {
 		if k == nil {
-			return zeroR, fmt.Errorf("key %d is nil", i)
+			return zeroR, -1, fmt.Errorf("key %d is nil", i)
 		}
 		result, err := f(k)
 		if err == nil {
-			return result, nil
+			recordKeyUse(ks.purpose, i)
+			return result, i, nil
 		}
 		errs = append(errs, fmt.Errorf("key %d: %w", i, err))
 	}
-	return zeroR, errors.Join(errs...)
+	return zeroR, -1, errors.Join(errs...)
 }
 
 /////////////////////////////////////////////////////////////////////
@@ -122,7 +143,7 @@ func (ks *Keyset) HKDF(salt []byte, info string) (*Keyset, error) {
 		}
 		derivedKeys = append(derivedKeys, dk)
 	}
-	return &Keyset{uks: derivedKeys}, nil
+	return &Keyset{uks: derivedKeys, purpose: info}, nil
 }
 
 // Pass in a latest-first slice of environment variable names pointing
@@ -195,7 +216,11 @@ type AppKeysetConfig struct {
 	// Provide a latest-first slice of environment variable names pointing
 	// to base64-encoded 32-byte root secrets.
 	// Example: []string{"CURRENT_SECRET", "PREVIOUS_SECRET"}
+	// Exactly one of LatestFirstEnvVarNames and Source must be set.
 	LatestFirstEnvVarNames []string
+	// Loads root secrets from somewhere other than environment variables
+	// (e.g., FileSource, CommandSource, or EnvelopeSource).
+	Source KeySource
 	// Passed into the salt parameter of downstream HKDF functions.
 	// Once set, do not change this unless you want and entirely new keyset.
 	ApplicationName string
@@ -209,16 +234,31 @@ type AppKeyset struct {
 func (ak *AppKeyset) Root() *Keyset                      { return ak.rootFn() }
 func (ak *AppKeyset) HKDF(purpose string) func() *Keyset { return ak.hkdfFnMaker(purpose) }
 
+// HKDFKeys is like HKDF, but its function returns the derived keys
+// directly (latest-first). Suitable for
+// cryptoutil.PasswordHasherConfig.GetPeppers.
+func (ak *AppKeyset) HKDFKeys(purpose string) func() []cryptoutil.Key32 {
+	hkdfFn := ak.hkdfFnMaker(purpose)
+	return func() []cryptoutil.Key32 { return hkdfFn().Unwrap() }
+}
+
 // Panics if anything is misconfigured.
 func MustAppKeyset(cfg AppKeysetConfig) *AppKeyset {
-	if len(cfg.LatestFirstEnvVarNames) == 0 {
-		panic("at least 1 env var key is required for AppKeysetConfig.LatestFirstEnvVarNames")
+	if cfg.Source != nil && len(cfg.LatestFirstEnvVarNames) > 0 {
+		panic("AppKeysetConfig.LatestFirstEnvVarNames and AppKeysetConfig.Source are mutually exclusive")
+	}
+	if cfg.Source == nil && len(cfg.LatestFirstEnvVarNames) == 0 {
+		panic("at least 1 env var key is required for AppKeysetConfig.LatestFirstEnvVarNames (or set AppKeysetConfig.Source)")
 	}
 	if cfg.ApplicationName == "" {
 		panic("AppKeysetConfig.ApplicationName cannot be empty")
 	}
+	source := cfg.Source
+	if source == nil {
+		source = EnvSource(cfg.LatestFirstEnvVarNames...)
+	}
 	rootFn := lazyget.New(func() *Keyset {
-		rootKeyset, err := LoadRootKeyset(cfg.LatestFirstEnvVarNames...)
+		rootKeyset, err := LoadRootKeysetFromSource(context.Background(), source)
 		if err != nil {
 			panic(fmt.Sprintf("error loading root keyset: %v", err))
 		}
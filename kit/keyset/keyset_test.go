@@ -752,3 +752,18 @@ func TestAppKeyset_LazyLoadingConsistency(t *testing.T) {
 		t.Error("expected same keyset content from HKDF with same purpose")
 	}
 }
+
+func TestAppKeyset_HKDFKeys(t *testing.T) {
+	os.Setenv("TEST_HKDF_KEYS_SECRET", generateTestSecret())
+	defer os.Unsetenv("TEST_HKDF_KEYS_SECRET")
+
+	appKeyset := MustAppKeyset(AppKeysetConfig{
+		LatestFirstEnvVarNames: []string{"TEST_HKDF_KEYS_SECRET"},
+		ApplicationName:        "test-app",
+	})
+	keys := appKeyset.HKDFKeys("password_pepper")()
+	derived := appKeyset.HKDF("password_pepper")().Unwrap()
+	if len(keys) != 1 || !bytes.Equal(keys[0][:], derived[0][:]) {
+		t.Errorf("HKDFKeys() = %v, want the keys of HKDF()", keys)
+	}
+}
@@ -0,0 +1,164 @@
+package keyset
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/river-now/river/kit/bytesutil"
+	"github.com/river-now/river/kit/cryptoutil"
+)
+
+/////////////////////////////////////////////////////////////////////
+/////// ROTATION
+/////////////////////////////////////////////////////////////////////
+
+// To rotate keys:
+//  1. Generate a new root secret (see GenerateRootSecret).
+//  2. Prepend it to your latest-first list of root secrets and deploy.
+//  3. Upgrade stored values as they are read (see securebytes.ParseAndUpgrade,
+//     securestring.ParseAndUpgrade, and
+//     signedcookie.SignedCookie.VerifyAndReadCookieValueAndUpgrade), or in a
+//     batch job.
+//  4. Watch KeyUsage or KeyUsageByPurpose (or a SetFallbackHook hook).
+//     Once the older keys stop being used for long enough to cover your
+//     longest-lived data, drop them.
+
+// GenerateRootSecret returns a new random base64-encoded 32-byte
+// root secret.
+func GenerateRootSecret() (RootSecret, error) {
+	b, err := cryptoutil.RandomBytes(cryptoutil.KeySize)
+	if err != nil {
+		return "", err
+	}
+	return RootSecret(bytesutil.ToBase64(b)), nil
+}
+
+// FallbackEvent describes a successful Attempt that used a
+// non-primary key.
+type FallbackEvent struct {
+	// The HKDF info string (e.g., the AppKeyset purpose) the keyset
+	// was derived with, or empty for a root keyset.
+	Purpose string
+	// Index of the key that succeeded. Matches the index of the root
+	// secret (and env var) it was loaded or derived from.
+	KeyIndex int
+}
+
+var fallbackHook atomic.Pointer[func(FallbackEvent)]
+
+// SetFallbackHook sets a function to call (synchronously) whenever
+// an Attempt succeeds with a non-primary key. Pass nil to remove
+// the hook. Useful for logging or exporting metrics. Hooks must be
+// safe for concurrent use and should return quickly.
+func SetFallbackHook(hook func(FallbackEvent)) {
+	if hook == nil {
+		fallbackHook.Store(nil)
+		return
+	}
+	fallbackHook.Store(&hook)
+}
+
+// Success counters, per purpose (see FallbackEvent.Purpose) and key index.
+// The hot path is lock-free: purposes live in a sync.Map, and each purpose's
+// counters are swapped out copy-on-grow, which only happens the first time a
+// keyset with more keys succeeds with one of its extra keys.
+var keyUsage sync.Map // purpose -> *purposeKeyUsage
+
+type purposeKeyUsage struct {
+	mu     sync.Mutex // serializes growth
+	counts atomic.Pointer[[]*atomic.Uint64]
+}
+
+func (u *purposeKeyUsage) counter(keyIndex int) *atomic.Uint64 {
+	if counts := u.counts.Load(); counts != nil && keyIndex < len(*counts) {
+		return (*counts)[keyIndex]
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var grown []*atomic.Uint64
+	if counts := u.counts.Load(); counts != nil {
+		if keyIndex < len(*counts) {
+			return (*counts)[keyIndex]
+		}
+		grown = slices.Clone(*counts)
+	}
+	for len(grown) <= keyIndex {
+		grown = append(grown, new(atomic.Uint64))
+	}
+	u.counts.Store(&grown)
+	return grown[keyIndex]
+}
+
+// Calls f for each non-zero count.
+func (u *purposeKeyUsage) each(f func(keyIndex int, n uint64)) {
+	counts := u.counts.Load()
+	if counts == nil {
+		return
+	}
+	for i, c := range *counts {
+		if n := c.Load(); n > 0 {
+			f(i, n)
+		}
+	}
+}
+
+// KeyUsage returns the number of successful Attempts by key index
+// (across all keysets) since the process started or ResetKeyUsage
+// was last called. When every index greater than 0 stays at zero
+// for long enough, the older keys are safe to drop.
+func KeyUsage() map[int]uint64 {
+	out := make(map[int]uint64)
+	keyUsage.Range(func(_, u any) bool {
+		u.(*purposeKeyUsage).each(func(i int, n uint64) { out[i] += n })
+		return true
+	})
+	return out
+}
+
+// KeyUsageByPurpose is like KeyUsage, but broken down by the purpose
+// (HKDF info string) of the keyset used, with an empty purpose for
+// root keysets. Purposes with no recorded use are omitted.
+func KeyUsageByPurpose() map[string]map[int]uint64 {
+	out := make(map[string]map[int]uint64)
+	keyUsage.Range(func(purpose, u any) bool {
+		u.(*purposeKeyUsage).each(func(i int, n uint64) {
+			counts := out[purpose.(string)]
+			if counts == nil {
+				counts = make(map[int]uint64)
+				out[purpose.(string)] = counts
+			}
+			counts[i] = n
+		})
+		return true
+	})
+	return out
+}
+
+// ResetKeyUsage zeroes the counts returned by KeyUsage and
+// KeyUsageByPurpose.
+func ResetKeyUsage() {
+	keyUsage.Range(func(_, u any) bool {
+		if counts := u.(*purposeKeyUsage).counts.Load(); counts != nil {
+			for _, c := range *counts {
+				c.Store(0)
+			}
+		}
+		return true
+	})
+}
+
+func recordKeyUse(purpose string, keyIndex int) {
+	u, ok := keyUsage.Load(purpose)
+	if !ok {
+		u, _ = keyUsage.LoadOrStore(purpose, new(purposeKeyUsage))
+	}
+	u.(*purposeKeyUsage).counter(keyIndex).Add(1)
+
+	if keyIndex == 0 {
+		return
+	}
+	if hook := fallbackHook.Load(); hook != nil {
+		(*hook)(FallbackEvent{Purpose: purpose, KeyIndex: keyIndex})
+	}
+}
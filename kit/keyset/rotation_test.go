@@ -0,0 +1,129 @@
+package keyset
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/river-now/river/kit/cryptoutil"
+)
+
+func TestGenerateRootSecret(t *testing.T) {
+	a, err := GenerateRootSecret()
+	if err != nil {
+		t.Fatalf("GenerateRootSecret() error = %v", err)
+	}
+	b, _ := GenerateRootSecret()
+	if a == b {
+		t.Error("GenerateRootSecret() returned the same secret twice")
+	}
+	if _, err := RootSecretsToRootKeyset(RootSecrets{a, b}); err != nil {
+		t.Errorf("generated secrets should form a valid keyset: %v", err)
+	}
+}
+
+func TestKeyUsageAndFallbackHook(t *testing.T) {
+	newSecret, _ := GenerateRootSecret()
+	oldSecret, _ := GenerateRootSecret()
+	root, err := RootSecretsToRootKeyset(RootSecrets{newSecret, oldSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	derived, err := root.HKDF([]byte("app"), "cookies")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldKey := derived.Unwrap()[1]
+	onlyOldKey := func(k cryptoutil.Key32) (string, error) {
+		if k == oldKey {
+			return "ok", nil
+		}
+		return "", errors.New("wrong key")
+	}
+	anyKey := func(k cryptoutil.Key32) (string, error) { return "ok", nil }
+
+	var events []FallbackEvent
+	SetFallbackHook(func(e FallbackEvent) { events = append(events, e) })
+	defer SetFallbackHook(nil)
+	ResetKeyUsage()
+	defer ResetKeyUsage()
+
+	if _, i, err := AttemptWithKeyIndex(derived, onlyOldKey); err != nil || i != 1 {
+		t.Fatalf("AttemptWithKeyIndex() = %d, %v; want 1, nil", i, err)
+	}
+	if _, err := Attempt(derived, anyKey); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Attempt(derived, anyKey); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 1 || events[0] != (FallbackEvent{Purpose: "cookies", KeyIndex: 1}) {
+		t.Errorf("events = %+v, want a single fallback to key 1 for purpose cookies", events)
+	}
+	if usage := KeyUsage(); usage[0] != 2 || usage[1] != 1 {
+		t.Errorf("KeyUsage() = %v, want map[0:2 1:1]", usage)
+	}
+	if _, err := Attempt(root, anyKey); err != nil {
+		t.Fatal(err)
+	}
+	byPurpose := KeyUsageByPurpose()
+	if want := (map[string]map[int]uint64{"cookies": {0: 2, 1: 1}, "": {0: 1}}); !reflect.DeepEqual(byPurpose, want) {
+		t.Errorf("KeyUsageByPurpose() = %v, want %v", byPurpose, want)
+	}
+	if usage := KeyUsage(); usage[0] != 3 || usage[1] != 1 {
+		t.Errorf("KeyUsage() = %v, want map[0:3 1:1]", usage)
+	}
+
+	t.Run("Reset", func(t *testing.T) {
+		ResetKeyUsage()
+		if usage := KeyUsage(); len(usage) != 0 {
+			t.Errorf("KeyUsage() after reset = %v, want empty", usage)
+		}
+		if usage := KeyUsageByPurpose(); len(usage) != 0 {
+			t.Errorf("KeyUsageByPurpose() after reset = %v, want empty", usage)
+		}
+	})
+
+	t.Run("RemoveHook", func(t *testing.T) {
+		SetFallbackHook(nil)
+		events = nil
+		if _, err := Attempt(derived, onlyOldKey); err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 0 {
+			t.Errorf("removed hook should not be called, got %+v", events)
+		}
+	})
+}
+
+func TestKeyUsageConcurrent(t *testing.T) {
+	ResetKeyUsage()
+	defer ResetKeyUsage()
+
+	const goroutines, perGoroutine = 8, 1000
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perGoroutine {
+				// Spread uses over purposes and indexes, so that counters
+				// grow while others are being incremented.
+				recordKeyUse("concurrent-"+strconv.Itoa(g%2), i%4)
+			}
+		}()
+	}
+	wg.Wait()
+
+	byPurpose := KeyUsageByPurpose()
+	for _, purpose := range []string{"concurrent-0", "concurrent-1"} {
+		for i := range 4 {
+			if got, want := byPurpose[purpose][i], uint64(goroutines/2*perGoroutine/4); got != want {
+				t.Errorf("KeyUsageByPurpose()[%q][%d] = %d, want %d", purpose, i, got, want)
+			}
+		}
+	}
+}
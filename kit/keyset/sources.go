@@ -0,0 +1,246 @@
+package keyset
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/river-now/river/kit/bytesutil"
+	"github.com/river-now/river/kit/cryptoutil"
+)
+
+/////////////////////////////////////////////////////////////////////
+/////// KEY SOURCES
+/////////////////////////////////////////////////////////////////////
+
+// KeySource loads a latest-first slice of base64-encoded 32-byte
+// root secrets from somewhere (environment variables, files, an
+// external command, a KMS-wrapped keyset, etc.).
+type KeySource interface {
+	LoadRootSecrets(ctx context.Context) (RootSecrets, error)
+}
+
+// KeySourceFunc adapts an ordinary function to a KeySource.
+type KeySourceFunc func(ctx context.Context) (RootSecrets, error)
+
+func (f KeySourceFunc) LoadRootSecrets(ctx context.Context) (RootSecrets, error) { return f(ctx) }
+
+// LoadRootKeysetFromSource loads root secrets from the provided
+// source and converts them into a Keyset.
+func LoadRootKeysetFromSource(ctx context.Context, source KeySource) (*Keyset, error) {
+	rootSecrets, err := source.LoadRootSecrets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error loading root secrets: %w", err)
+	}
+	keyset, err := RootSecretsToRootKeyset(rootSecrets)
+	if err != nil {
+		return nil, fmt.Errorf("error converting root secrets to keyset: %w", err)
+	}
+	return keyset, nil
+}
+
+// EnvSource reads one root secret from each of the provided
+// latest-first environment variable names.
+// Example: EnvSource("CURRENT_SECRET", "PREVIOUS_SECRET")
+func EnvSource(latestFirstEnvVarNames ...string) KeySource {
+	return KeySourceFunc(func(context.Context) (RootSecrets, error) {
+		return LoadRootSecrets(latestFirstEnvVarNames...)
+	})
+}
+
+// FileSource reads one root secret from each of the provided
+// latest-first file paths, ignoring surrounding whitespace. Suitable
+// for Docker and Kubernetes secrets mounts.
+// Example: FileSource("/run/secrets/current", "/run/secrets/previous")
+func FileSource(latestFirstPaths ...string) KeySource {
+	return KeySourceFunc(func(context.Context) (RootSecrets, error) {
+		if len(latestFirstPaths) == 0 {
+			return nil, fmt.Errorf("at least 1 file path is required")
+		}
+		rootSecrets := make(RootSecrets, 0, len(latestFirstPaths))
+		for i, path := range latestFirstPaths {
+			if path == "" {
+				return nil, fmt.Errorf("file path at index %d is empty", i)
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("error reading secret file %s: %w", path, err)
+			}
+			secret := strings.TrimSpace(string(b))
+			if secret == "" {
+				return nil, fmt.Errorf("secret file %s is empty", path)
+			}
+			rootSecrets = append(rootSecrets, RootSecret(secret))
+		}
+		return rootSecrets, nil
+	})
+}
+
+// DefaultCommandSourceTimeout bounds how long a CommandSource's command
+// may run unless CommandKeySource.Timeout is set. Keysets are loaded
+// lazily with context.Background(), so without it, a hung secrets CLI
+// would hang the first request that needs a key.
+const DefaultCommandSourceTimeout = 30 * time.Second
+
+// CommandSource runs the provided command and reads latest-first
+// root secrets from its stdout, one per line (blank lines are
+// ignored). Useful for fetching secrets with a secrets manager CLI.
+// The command is killed if it runs longer than the source's Timeout.
+// Example: CommandSource("vault", "kv", "get", "-field=secrets", "secret/app")
+func CommandSource(name string, args ...string) *CommandKeySource {
+	return &CommandKeySource{name: name, args: args}
+}
+
+type CommandKeySource struct {
+	name string
+	args []string
+	// Defaults to DefaultCommandSourceTimeout. Set to a negative number
+	// to rely on the caller's context alone.
+	Timeout time.Duration
+}
+
+func (s *CommandKeySource) LoadRootSecrets(ctx context.Context) (RootSecrets, error) {
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = DefaultCommandSourceTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, s.name, s.args...)
+	cmd.WaitDelay = time.Second // Don't wait on orphaned children holding stdout open
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = fmt.Errorf("%w (%w)", err, ctxErr)
+		}
+		return nil, fmt.Errorf("error running %s: %w: %s", s.name, err, strings.TrimSpace(stderr.String()))
+	}
+	rootSecrets := parseSecretLines(out)
+	if len(rootSecrets) == 0 {
+		return nil, fmt.Errorf("%s printed no secrets", s.name)
+	}
+	return rootSecrets, nil
+}
+
+func parseSecretLines(b []byte) RootSecrets {
+	var rootSecrets RootSecrets
+	for line := range strings.Lines(string(b)) {
+		if line = strings.TrimSpace(line); line != "" {
+			rootSecrets = append(rootSecrets, RootSecret(line))
+		}
+	}
+	return rootSecrets
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// ENVELOPE ENCRYPTION
+/////////////////////////////////////////////////////////////////////
+
+// MasterKeyEncrypter encrypts data with a master key that never
+// leaves its key management service (e.g., a cloud KMS key). Only
+// needed to create wrapped keysets (see WrapRootSecrets).
+type MasterKeyEncrypter interface {
+	Encrypt(ctx context.Context, plaintext []byte) ([]byte, error)
+}
+
+// MasterKeyDecrypter decrypts data encrypted by the corresponding
+// MasterKeyEncrypter.
+type MasterKeyDecrypter interface {
+	Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+// WrapRootSecrets encrypts latest-first root secrets with a master
+// key, returning a base64-encoded wrapped keyset that is safe to
+// store alongside your app (e.g., in a file or config repo). Use
+// EnvelopeSource to load it.
+func WrapRootSecrets(ctx context.Context, encrypter MasterKeyEncrypter, rootSecrets RootSecrets) (string, error) {
+	if _, err := RootSecretsToRootKeyset(rootSecrets); err != nil {
+		return "", fmt.Errorf("invalid root secrets: %w", err)
+	}
+	plaintext := []byte(strings.Join(rootSecrets, "\n"))
+	ciphertext, err := encrypter.Encrypt(ctx, plaintext)
+	if err != nil {
+		return "", fmt.Errorf("error wrapping root secrets: %w", err)
+	}
+	return bytesutil.ToBase64(ciphertext), nil
+}
+
+type EnvelopeSourceConfig struct {
+	// REQUIRED: Decrypts the wrapped keyset.
+	Decrypter MasterKeyDecrypter
+	// Path to a file containing a wrapped keyset, as returned by
+	// WrapRootSecrets. Exactly one of WrappedKeysetPath and
+	// WrappedKeyset must be set.
+	WrappedKeysetPath string
+	// A wrapped keyset, as returned by WrapRootSecrets.
+	WrappedKeyset string
+}
+
+// EnvelopeSource loads root secrets by decrypting a wrapped keyset
+// (see WrapRootSecrets) with a master key. Panics if the config is
+// invalid.
+func EnvelopeSource(cfg EnvelopeSourceConfig) KeySource {
+	if cfg.Decrypter == nil {
+		panic("EnvelopeSourceConfig.Decrypter is required")
+	}
+	if (cfg.WrappedKeysetPath == "") == (cfg.WrappedKeyset == "") {
+		panic("exactly one of EnvelopeSourceConfig.WrappedKeysetPath and EnvelopeSourceConfig.WrappedKeyset must be set")
+	}
+	return KeySourceFunc(func(ctx context.Context) (RootSecrets, error) {
+		wrapped := cfg.WrappedKeyset
+		if cfg.WrappedKeysetPath != "" {
+			b, err := os.ReadFile(cfg.WrappedKeysetPath)
+			if err != nil {
+				return nil, fmt.Errorf("error reading wrapped keyset file: %w", err)
+			}
+			wrapped = string(b)
+		}
+		ciphertext, err := bytesutil.FromBase64(strings.TrimSpace(wrapped))
+		if err != nil {
+			return nil, fmt.Errorf("error decoding wrapped keyset: %w", err)
+		}
+		plaintext, err := cfg.Decrypter.Decrypt(ctx, ciphertext)
+		if err != nil {
+			return nil, fmt.Errorf("error unwrapping keyset: %w", err)
+		}
+		rootSecrets := parseSecretLines(plaintext)
+		if len(rootSecrets) == 0 {
+			return nil, fmt.Errorf("wrapped keyset is empty")
+		}
+		return rootSecrets, nil
+	})
+}
+
+// LocalMasterKey is a MasterKeyEncrypter and MasterKeyDecrypter
+// backed by a local 32-byte key. It stands in for a real key
+// management service in tests and local development. In production,
+// prefer a KMS-backed implementation, so that the master key itself
+// is never exposed to your app.
+type LocalMasterKey struct{ key cryptoutil.Key32 }
+
+// NewLocalMasterKey creates a LocalMasterKey from a base64-encoded
+// 32-byte secret (see GenerateRootSecret).
+func NewLocalMasterKey(secret RootSecret) (*LocalMasterKey, error) {
+	ks, err := RootSecretsToRootKeyset(RootSecrets{secret})
+	if err != nil {
+		return nil, fmt.Errorf("invalid master key: %w", err)
+	}
+	return &LocalMasterKey{key: ks.uks[0]}, nil
+}
+
+func (mk *LocalMasterKey) Encrypt(_ context.Context, plaintext []byte) ([]byte, error) {
+	return cryptoutil.EncryptSymmetricXChaCha20Poly1305(plaintext, mk.key)
+}
+
+func (mk *LocalMasterKey) Decrypt(_ context.Context, ciphertext []byte) ([]byte, error) {
+	return cryptoutil.DecryptSymmetricXChaCha20Poly1305(ciphertext, mk.key)
+}
@@ -0,0 +1,189 @@
+package keyset
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustGenerateRootSecrets(t *testing.T, n int) RootSecrets {
+	t.Helper()
+	out := make(RootSecrets, n)
+	for i := range out {
+		secret, err := GenerateRootSecret()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out[i] = secret
+	}
+	return out
+}
+
+func assertRootSecrets(t *testing.T, source KeySource, want RootSecrets) {
+	t.Helper()
+	got, err := source.LoadRootSecrets(context.Background())
+	if err != nil {
+		t.Fatalf("LoadRootSecrets() error = %v", err)
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("LoadRootSecrets() = %v, want %v", got, want)
+	}
+}
+
+func TestEnvSource(t *testing.T) {
+	secrets := mustGenerateRootSecrets(t, 2)
+	t.Setenv("TEST_ENV_SOURCE_CURRENT", secrets[0])
+	t.Setenv("TEST_ENV_SOURCE_PREVIOUS", secrets[1])
+	assertRootSecrets(t, EnvSource("TEST_ENV_SOURCE_CURRENT", "TEST_ENV_SOURCE_PREVIOUS"), secrets)
+}
+
+func TestFileSource(t *testing.T) {
+	secrets := mustGenerateRootSecrets(t, 2)
+	dir := t.TempDir()
+	current := filepath.Join(dir, "current")
+	previous := filepath.Join(dir, "previous")
+	os.WriteFile(current, []byte(secrets[0]+"\n"), 0600)
+	os.WriteFile(previous, []byte("  "+secrets[1]), 0600)
+
+	assertRootSecrets(t, FileSource(current, previous), secrets)
+
+	t.Run("Errors", func(t *testing.T) {
+		empty := filepath.Join(dir, "empty")
+		os.WriteFile(empty, []byte("\n"), 0600)
+		for _, source := range []KeySource{
+			FileSource(),
+			FileSource(current, ""),
+			FileSource(filepath.Join(dir, "missing")),
+			FileSource(empty),
+		} {
+			if _, err := source.LoadRootSecrets(context.Background()); err == nil {
+				t.Error("expected error")
+			}
+		}
+	})
+}
+
+func TestCommandSource(t *testing.T) {
+	secrets := mustGenerateRootSecrets(t, 2)
+	assertRootSecrets(t, CommandSource("printf", "%s\n\n%s\n", secrets[0], secrets[1]), secrets)
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, source := range []KeySource{
+			CommandSource("false"),
+			CommandSource("printf", ""),
+		} {
+			if _, err := source.LoadRootSecrets(context.Background()); err == nil {
+				t.Error("expected error")
+			}
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		source := CommandSource("sleep", "10")
+		source.Timeout = 50 * time.Millisecond
+		start := time.Now()
+		_, err := source.LoadRootSecrets(context.Background())
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected a deadline error, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("command ran for %v despite the timeout", elapsed)
+		}
+	})
+}
+
+func TestEnvelopeSource(t *testing.T) {
+	ctx := context.Background()
+	masterSecret, _ := GenerateRootSecret()
+	masterKey, err := NewLocalMasterKey(masterSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets := mustGenerateRootSecrets(t, 3)
+	wrapped, err := WrapRootSecrets(ctx, masterKey, secrets)
+	if err != nil {
+		t.Fatalf("WrapRootSecrets() error = %v", err)
+	}
+	if strings.Contains(wrapped, secrets[0]) {
+		t.Fatal("wrapped keyset should not contain plaintext secrets")
+	}
+
+	t.Run("Inline", func(t *testing.T) {
+		assertRootSecrets(t, EnvelopeSource(EnvelopeSourceConfig{Decrypter: masterKey, WrappedKeyset: wrapped}), secrets)
+	})
+
+	t.Run("File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "keyset.wrapped")
+		os.WriteFile(path, []byte(wrapped+"\n"), 0600)
+		assertRootSecrets(t, EnvelopeSource(EnvelopeSourceConfig{Decrypter: masterKey, WrappedKeysetPath: path}), secrets)
+	})
+
+	t.Run("WrongMasterKey", func(t *testing.T) {
+		otherSecret, _ := GenerateRootSecret()
+		otherKey, _ := NewLocalMasterKey(otherSecret)
+		source := EnvelopeSource(EnvelopeSourceConfig{Decrypter: otherKey, WrappedKeyset: wrapped})
+		if _, err := source.LoadRootSecrets(ctx); err == nil {
+			t.Error("expected error unwrapping with the wrong master key")
+		}
+	})
+
+	t.Run("InvalidSecrets", func(t *testing.T) {
+		if _, err := WrapRootSecrets(ctx, masterKey, RootSecrets{"not-a-secret"}); err == nil {
+			t.Error("expected error wrapping invalid secrets")
+		}
+	})
+
+	t.Run("InvalidConfig", func(t *testing.T) {
+		for _, cfg := range []EnvelopeSourceConfig{
+			{WrappedKeyset: wrapped},
+			{Decrypter: masterKey},
+			{Decrypter: masterKey, WrappedKeyset: wrapped, WrappedKeysetPath: "x"},
+		} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("expected panic for %+v", cfg)
+					}
+				}()
+				EnvelopeSource(cfg)
+			}()
+		}
+	})
+}
+
+func TestMustAppKeyset_Source(t *testing.T) {
+	secrets := mustGenerateRootSecrets(t, 2)
+	path := filepath.Join(t.TempDir(), "current")
+	os.WriteFile(path, []byte(secrets[0]), 0600)
+
+	t.Run("FromSource", func(t *testing.T) {
+		appKeyset := MustAppKeyset(AppKeysetConfig{
+			Source:          FileSource(path),
+			ApplicationName: "test-app",
+		})
+		want, _ := RootSecretsToRootKeyset(secrets[:1])
+		if *appKeyset.Root().Unwrap()[0] != *want.Unwrap()[0] {
+			t.Error("root keyset does not match the file's secret")
+		}
+		if appKeyset.HKDF("test-purpose")() == nil {
+			t.Error("expected non-nil derived keyset")
+		}
+	})
+
+	t.Run("PanicOnBothSources", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic when both LatestFirstEnvVarNames and Source are set")
+			}
+		}()
+		MustAppKeyset(AppKeysetConfig{
+			LatestFirstEnvVarNames: []string{"TEST_APP_SECRET"},
+			Source:                 FileSource(path),
+			ApplicationName:        "test-app",
+		})
+	})
+}
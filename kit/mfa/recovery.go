@@ -0,0 +1,70 @@
+package mfa
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/river-now/river/kit/cryptoutil"
+	"github.com/river-now/river/kit/id"
+)
+
+/////////////////////////////////////////////////////////////////////
+/////// RECOVERY CODES
+/////////////////////////////////////////////////////////////////////
+
+const (
+	// Lowercase letters and digits, minus the easily confused 0, 1, i, l,
+	// and o. 31 characters, so each code has about 79 bits of entropy,
+	// which is what makes a fast (unsalted SHA-256) hash safe to store.
+	recoveryCodeCharset = "23456789abcdefghjkmnpqrstuvwxyz"
+	recoveryCodeLen     = 16
+	recoveryCodeGroup   = 4
+)
+
+// GenerateRecoveryCodes returns n new single-use recovery codes to show the
+// user once (formatted like "abcd-efgh-jkmn-pqrs"), along with their hashes
+// to store in their place. Never store the codes themselves.
+func GenerateRecoveryCodes(n uint8) (codes, hashes []string, err error) {
+	raw, err := id.NewMulti(recoveryCodeLen, n, recoveryCodeCharset)
+	if err != nil {
+		return nil, nil, fmt.Errorf("mfa: failed to generate recovery codes: %w", err)
+	}
+	codes = make([]string, len(raw))
+	hashes = make([]string, len(raw))
+	for i, code := range raw {
+		var groups []string
+		for j := 0; j < len(code); j += recoveryCodeGroup {
+			groups = append(groups, code[j:j+recoveryCodeGroup])
+		}
+		codes[i] = strings.Join(groups, "-")
+		hashes[i] = HashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode returns the hash to store for a recovery code. Case,
+// dashes, and spaces are ignored.
+func HashRecoveryCode(code string) string {
+	return hex.EncodeToString(cryptoutil.Sha256Hash([]byte(normalizeRecoveryCode(code))))
+}
+
+// VerifyRecoveryCode checks code against a user's stored recovery code
+// hashes. If it matches, it returns the index of the matching hash, which
+// you must then delete so the code can't be used again. Otherwise it returns
+// -1. Compares against every hash in constant time.
+func VerifyRecoveryCode(code string, hashes []string) int {
+	attempted := []byte(HashRecoveryCode(code))
+	matched := -1
+	for i, h := range hashes {
+		if subtle.ConstantTimeCompare(attempted, []byte(h)) == 1 && matched == -1 {
+			matched = i
+		}
+	}
+	return matched
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+}
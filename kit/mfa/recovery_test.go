@@ -0,0 +1,51 @@
+package mfa
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes() error = %v", err)
+	}
+	if len(codes) != 10 || len(hashes) != 10 {
+		t.Fatalf("got %d codes and %d hashes, want 10 each", len(codes), len(hashes))
+	}
+
+	format := regexp.MustCompile(`^[2-9a-hjkmnp-z]{4}(-[2-9a-hjkmnp-z]{4}){3}$`)
+	seen := map[string]bool{}
+	for i, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("code %q has unexpected format", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate code %q", code)
+		}
+		seen[code] = true
+		if strings.Contains(hashes[i], strings.ReplaceAll(code, "-", "")) {
+			t.Error("hashes must not contain the codes")
+		}
+	}
+
+	t.Run("Verify", func(t *testing.T) {
+		if got := VerifyRecoveryCode(codes[3], hashes); got != 3 {
+			t.Errorf("VerifyRecoveryCode() = %d, want 3", got)
+		}
+		sloppy := " " + strings.ToUpper(strings.ReplaceAll(codes[5], "-", " ")) + " "
+		if got := VerifyRecoveryCode(sloppy, hashes); got != 5 {
+			t.Errorf("VerifyRecoveryCode(%q) = %d, want 5", sloppy, got)
+		}
+		if got := VerifyRecoveryCode("aaaa-bbbb-cccc-dddd", hashes); got != -1 {
+			t.Errorf("VerifyRecoveryCode(unknown) = %d, want -1", got)
+		}
+
+		// Once its hash is deleted, a code no longer verifies
+		remaining := append(hashes[:3:3], hashes[4:]...)
+		if got := VerifyRecoveryCode(codes[3], remaining); got != -1 {
+			t.Errorf("VerifyRecoveryCode(used) = %d, want -1", got)
+		}
+	})
+}
@@ -0,0 +1,264 @@
+// Package mfa provides second-factor primitives: RFC 6238 TOTP generation
+// and verification (with a drift window and replay protection), otpauth URI
+// generation for authenticator apps, and hashed recovery codes. It stores
+// nothing itself, so it can sit alongside other factors (e.g., WebAuthn) in
+// your own user model.
+package mfa
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/river-now/river/kit/cryptoutil"
+)
+
+/////////////////////////////////////////////////////////////////////
+/////// TOTP
+/////////////////////////////////////////////////////////////////////
+
+type Algorithm string
+
+const (
+	AlgorithmSHA1   Algorithm = "SHA1" // The only algorithm every authenticator app supports
+	AlgorithmSHA256 Algorithm = "SHA256"
+	AlgorithmSHA512 Algorithm = "SHA512"
+)
+
+const secretSize = 20 // Size, in bytes, of generated secrets (per RFC 4226).
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a new random base32-encoded (unpadded) TOTP
+// secret. Store it encrypted (e.g., with securestring).
+func GenerateSecret() (string, error) {
+	b, err := cryptoutil.RandomBytes(secretSize)
+	if err != nil {
+		return "", fmt.Errorf("mfa: failed to generate secret: %w", err)
+	}
+	return b32.EncodeToString(b), nil
+}
+
+type TOTPConfig struct {
+	Digits    int           // Defaults to 6
+	Period    time.Duration // Defaults to 30 seconds
+	Algorithm Algorithm     // Defaults to AlgorithmSHA1
+	// Number of periods before and after the current one to also accept,
+	// to allow for clock drift and slow typists. Defaults to 1. Set to -1
+	// to accept only the current period.
+	Skew int
+	// Optional, but strongly recommended. Used to reject codes that have
+	// already been used.
+	ReplayStore ReplayStore
+}
+
+type TOTP struct {
+	cfg TOTPConfig
+	now func() time.Time
+}
+
+// Panics if the config is invalid.
+func NewTOTP(cfg TOTPConfig) *TOTP {
+	if cfg.Digits == 0 {
+		cfg.Digits = 6
+	}
+	if cfg.Digits < 6 || cfg.Digits > 10 {
+		panic("mfa: Digits must be between 6 and 10")
+	}
+	if cfg.Period == 0 {
+		cfg.Period = 30 * time.Second
+	}
+	if cfg.Period < time.Second {
+		panic("mfa: Period must be at least 1 second")
+	}
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = AlgorithmSHA1
+	}
+	if hashFunc(cfg.Algorithm) == nil {
+		panic(fmt.Sprintf("mfa: unsupported algorithm %q", cfg.Algorithm))
+	}
+	if cfg.Skew == 0 {
+		cfg.Skew = 1
+	}
+	if cfg.Skew < 0 {
+		cfg.Skew = 0
+	}
+	return &TOTP{cfg: cfg, now: time.Now}
+}
+
+// Generate returns the code for secret at time t.
+func (t *TOTP) Generate(secret string, at time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return t.code(key, t.step(at)), nil
+}
+
+// Verify reports whether code is valid for secret right now (within the
+// configured skew). If a ReplayStore is configured, each code is accepted
+// at most once per subject (typically a user ID), as is any code from an
+// earlier period than the last accepted one. An invalid code is not an
+// error.
+func (t *TOTP) Verify(ctx context.Context, subject, secret, code string) (bool, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return false, err
+	}
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != t.cfg.Digits {
+		return false, nil
+	}
+
+	current := t.step(t.now())
+	matched, found := int64(0), false
+	for offset := -t.cfg.Skew; offset <= t.cfg.Skew; offset++ {
+		step := current + int64(offset)
+		if step < 0 {
+			continue
+		}
+		// Keep comparing after a match, so timing doesn't reveal the offset
+		if subtle.ConstantTimeCompare([]byte(t.code(key, step)), []byte(code)) == 1 && !found {
+			matched, found = step, true
+		}
+	}
+	if !found {
+		return false, nil
+	}
+
+	if t.cfg.ReplayStore == nil {
+		return true, nil
+	}
+	if subject == "" {
+		return false, errors.New("mfa: subject is required for replay protection")
+	}
+	ok, err := t.cfg.ReplayStore.UseStep(ctx, subject, matched)
+	if err != nil {
+		return false, fmt.Errorf("mfa: failed to record used code: %w", err)
+	}
+	return ok, nil
+}
+
+func (t *TOTP) step(at time.Time) int64 {
+	return at.Unix() / int64(t.cfg.Period/time.Second)
+}
+
+// Per RFC 4226 (HOTP), with the time step as the counter.
+func (t *TOTP) code(key []byte, step int64) string {
+	mac := hmac.New(hashFunc(t.cfg.Algorithm), key)
+	binary.Write(mac, binary.BigEndian, step)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := int64(binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff)
+	mod := int64(1)
+	for range t.cfg.Digits {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", t.cfg.Digits, value%mod)
+}
+
+func hashFunc(a Algorithm) func() hash.Hash {
+	switch a {
+	case AlgorithmSHA1:
+		return sha1.New
+	case AlgorithmSHA256:
+		return sha256.New
+	case AlgorithmSHA512:
+		return sha512.New
+	}
+	return nil
+}
+
+// Accepts lowercase, spaces, and padding, as users sometimes enter
+// secrets by hand.
+func decodeSecret(secret string) ([]byte, error) {
+	normalized := strings.TrimRight(strings.ToUpper(strings.ReplaceAll(secret, " ", "")), "=")
+	key, err := b32.DecodeString(normalized)
+	if err != nil || len(key) == 0 {
+		return nil, errors.New("mfa: secret is not valid base32")
+	}
+	return key, nil
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// OTPAUTH URI
+/////////////////////////////////////////////////////////////////////
+
+type URIConfig struct {
+	Issuer      string // REQUIRED: Your app or company name
+	AccountName string // REQUIRED: Typically the user's email or username
+	Secret      string // REQUIRED: As returned by GenerateSecret
+}
+
+// URI returns an otpauth:// URI for enrolling secret in an
+// authenticator app (typically rendered as a QR code), using the
+// TOTP's digits, period, and algorithm.
+func (t *TOTP) URI(cfg URIConfig) (string, error) {
+	if cfg.Issuer == "" || cfg.AccountName == "" {
+		return "", errors.New("mfa: Issuer and AccountName are required")
+	}
+	if _, err := decodeSecret(cfg.Secret); err != nil {
+		return "", err
+	}
+	q := url.Values{}
+	q.Set("secret", strings.ToUpper(strings.TrimRight(strings.ReplaceAll(cfg.Secret, " ", ""), "=")))
+	q.Set("issuer", cfg.Issuer)
+	q.Set("algorithm", string(t.cfg.Algorithm))
+	q.Set("digits", strconv.Itoa(t.cfg.Digits))
+	q.Set("period", strconv.Itoa(int(t.cfg.Period/time.Second)))
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + cfg.Issuer + ":" + cfg.AccountName,
+		RawQuery: q.Encode(),
+	}
+	return u.String(), nil
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// REPLAY PROTECTION
+/////////////////////////////////////////////////////////////////////
+
+// ReplayStore records, per subject, the last TOTP time step that was
+// successfully used. Implementations must be safe for concurrent use, and
+// UseStep must be atomic (e.g., a conditional UPDATE in SQL).
+type ReplayStore interface {
+	// Records step as used by subject and returns true, unless step is
+	// not after the last step recorded for subject, in which case it
+	// returns false.
+	UseStep(ctx context.Context, subject string, step int64) (bool, error)
+}
+
+// An in-memory ReplayStore, suitable for tests and single-instance
+// deployments.
+type MemoryReplayStore struct {
+	mu    sync.Mutex
+	steps map[string]int64
+}
+
+func NewMemoryReplayStore() *MemoryReplayStore {
+	return &MemoryReplayStore{steps: make(map[string]int64)}
+}
+
+func (s *MemoryReplayStore) UseStep(_ context.Context, subject string, step int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if last, ok := s.steps[subject]; ok && step <= last {
+		return false, nil
+	}
+	s.steps[subject] = step
+	return true, nil
+}
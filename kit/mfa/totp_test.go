@@ -0,0 +1,152 @@
+package mfa
+
+import (
+	"context"
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTP_RFC6238Vectors(t *testing.T) {
+	secrets := map[Algorithm]string{
+		AlgorithmSHA1:   "12345678901234567890",
+		AlgorithmSHA256: "12345678901234567890123456789012",
+		AlgorithmSHA512: "1234567890123456789012345678901234567890123456789012345678901234",
+	}
+	tests := []struct {
+		unix int64
+		want map[Algorithm]string
+	}{
+		{59, map[Algorithm]string{AlgorithmSHA1: "94287082", AlgorithmSHA256: "46119246", AlgorithmSHA512: "90693936"}},
+		{1111111109, map[Algorithm]string{AlgorithmSHA1: "07081804", AlgorithmSHA256: "68084774", AlgorithmSHA512: "25091201"}},
+		{1234567890, map[Algorithm]string{AlgorithmSHA1: "89005924", AlgorithmSHA256: "91819424", AlgorithmSHA512: "93441116"}},
+		{20000000000, map[Algorithm]string{AlgorithmSHA1: "65353130", AlgorithmSHA256: "77737706", AlgorithmSHA512: "47863826"}},
+	}
+	for algorithm, raw := range secrets {
+		totp := NewTOTP(TOTPConfig{Digits: 8, Algorithm: algorithm})
+		secret := base32.StdEncoding.EncodeToString([]byte(raw))
+		for _, tt := range tests {
+			got, err := totp.Generate(secret, time.Unix(tt.unix, 0))
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			if got != tt.want[algorithm] {
+				t.Errorf("%s at %d: Generate() = %s, want %s", algorithm, tt.unix, got, tt.want[algorithm])
+			}
+		}
+	}
+}
+
+func TestTOTP_Verify(t *testing.T) {
+	ctx := context.Background()
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+
+	newTOTP := func(cfg TOTPConfig) *TOTP {
+		totp := NewTOTP(cfg)
+		totp.now = func() time.Time { return now }
+		return totp
+	}
+
+	t.Run("DriftWindow", func(t *testing.T) {
+		totp := newTOTP(TOTPConfig{})
+		for offset, want := range map[time.Duration]bool{
+			0: true, -30 * time.Second: true, 30 * time.Second: true,
+			-60 * time.Second: false, 60 * time.Second: false,
+		} {
+			code, _ := totp.Generate(secret, now.Add(offset))
+			if ok, err := totp.Verify(ctx, "", secret, code); err != nil || ok != want {
+				t.Errorf("offset %v: Verify() = %v, %v; want %v", offset, ok, err, want)
+			}
+		}
+
+		strict := newTOTP(TOTPConfig{Skew: -1})
+		code, _ := strict.Generate(secret, now.Add(-30*time.Second))
+		if ok, _ := strict.Verify(ctx, "", secret, code); ok {
+			t.Error("Skew -1 should accept only the current period")
+		}
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		totp := newTOTP(TOTPConfig{})
+		for _, code := range []string{"", "12345", "1234567", "abcdef"} {
+			if ok, err := totp.Verify(ctx, "", secret, code); ok || err != nil {
+				t.Errorf("Verify(%q) = %v, %v; want false, nil", code, ok, err)
+			}
+		}
+		if _, err := totp.Verify(ctx, "", "not base32!", "123456"); err == nil {
+			t.Error("expected error for invalid secret")
+		}
+	})
+
+	t.Run("ReplayProtection", func(t *testing.T) {
+		totp := newTOTP(TOTPConfig{ReplayStore: NewMemoryReplayStore()})
+		code, _ := totp.Generate(secret, now)
+		spaced := code[:3] + " " + code[3:]
+
+		if ok, err := totp.Verify(ctx, "user-1", secret, spaced); !ok || err != nil {
+			t.Fatalf("first use: Verify() = %v, %v; want true, nil", ok, err)
+		}
+		if ok, _ := totp.Verify(ctx, "user-1", secret, code); ok {
+			t.Error("a code should not be accepted twice")
+		}
+		earlier, _ := totp.Generate(secret, now.Add(-30*time.Second))
+		if ok, _ := totp.Verify(ctx, "user-1", secret, earlier); ok {
+			t.Error("a code from before the last accepted period should be rejected")
+		}
+		if ok, _ := totp.Verify(ctx, "user-2", secret, code); !ok {
+			t.Error("replay protection should be per subject")
+		}
+		if _, err := totp.Verify(ctx, "", secret, code); err == nil {
+			t.Error("expected error for empty subject with a ReplayStore")
+		}
+	})
+
+	t.Run("InvalidConfig", func(t *testing.T) {
+		for _, cfg := range []TOTPConfig{{Digits: 4}, {Period: time.Millisecond}, {Algorithm: "MD5"}} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("expected panic for %+v", cfg)
+					}
+				}()
+				NewTOTP(cfg)
+			}()
+		}
+	})
+}
+
+func TestTOTP_URI(t *testing.T) {
+	totp := NewTOTP(TOTPConfig{})
+	secret := "jbsw y3dp ehpk 3pxp"
+	uri, err := totp.URI(URIConfig{Issuer: "Acme Co", AccountName: "alice@example.com", Secret: secret})
+	if err != nil {
+		t.Fatalf("URI() error = %v", err)
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Acme Co:alice@example.com" {
+		t.Errorf("URI() = %s", uri)
+	}
+	q := u.Query()
+	want := map[string]string{"secret": "JBSWY3DPEHPK3PXP", "issuer": "Acme Co", "algorithm": "SHA1", "digits": "6", "period": "30"}
+	for k, v := range want {
+		if q.Get(k) != v {
+			t.Errorf("query %s = %q, want %q", k, q.Get(k), v)
+		}
+	}
+	if strings.Contains(uri, " ") {
+		t.Errorf("URI() should be escaped: %s", uri)
+	}
+
+	if _, err := totp.URI(URIConfig{AccountName: "alice", Secret: secret}); err == nil {
+		t.Error("expected error for missing Issuer")
+	}
+}
@@ -0,0 +1,409 @@
+package compress
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	Brotli = "br"
+	Zstd   = "zstd"
+	Gzip   = "gzip"
+)
+
+// Server preference order used when Config.Encodings is not set.
+var DefaultEncodings = []string{Brotli, Zstd, Gzip}
+
+type Config struct {
+	// Encodings to offer, in order of server preference. Defaults to
+	// DefaultEncodings. Unknown encodings are ignored.
+	Encodings []string
+	// Responses with bodies smaller than this are sent uncompressed.
+	// Defaults to 1024 bytes. Set to a negative number to always compress.
+	MinSize int
+	// Reports whether responses of the given Content-Type should be
+	// compressed. Defaults to IsCompressibleContentType.
+	ShouldCompress func(contentType string) bool
+	// If set and it returns true, the request's response is passed through
+	// untouched (no negotiation, no Vary header).
+	SkipFunc func(r *http.Request) bool
+}
+
+// Response compression middleware that negotiates an encoding from the
+// request's Accept-Encoding header (br, zstd, and gzip are supported).
+// Responses are left alone if they are too small, already encoded, partial
+// (206), marked "Cache-Control: no-transform", or of a content type that
+// doesn't benefit from compression (images, video, archives, etc.). HEAD
+// requests get the same headers (Content-Encoding, Vary, ETag) that the
+// equivalent GET would, without a body. Their size is taken from the
+// handler's Content-Length, if any.
+//
+// Interop with ETags: if a compressed response carries a strong ETag, the
+// encoding is appended to it (e.g., "abc" becomes "abc-br"), because each
+// encoded representation is a distinct set of bytes. Weak ETags are left as
+// is. On the way in, the suffix is stripped from If-None-Match so that inner
+// middleware (such as etag.Auto) can still match against its own tags, and
+// it is restored on any resulting 304 response. This means compression can
+// sit either inside or outside etag.Auto.
+func Auto(config ...*Config) func(http.Handler) http.Handler {
+	var configToUse *Config
+	if len(config) > 0 && config[0] != nil {
+		configToUse = config[0]
+	} else {
+		configToUse = new(Config)
+	}
+	encodings := make([]string, 0, len(DefaultEncodings))
+	source := configToUse.Encodings
+	if len(source) == 0 {
+		source = DefaultEncodings
+	}
+	for _, e := range source {
+		if _, ok := writerPools[e]; ok {
+			encodings = append(encodings, e)
+		}
+	}
+	minSize := configToUse.MinSize
+	if minSize == 0 {
+		minSize = 1024
+	}
+	shouldCompress := configToUse.ShouldCompress
+	if shouldCompress == nil {
+		shouldCompress = IsCompressibleContentType
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(encodings) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if configToUse.SkipFunc != nil && configToUse.SkipFunc(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			encoding := Negotiate(r.Header.Get("Accept-Encoding"), encodings)
+			r, strippedSuffix := stripETagSuffixes(r, encodings)
+			cw := &compressWriter{
+				w:              w,
+				head:           r.Method == http.MethodHead,
+				encoding:       encoding,
+				strippedSuffix: strippedSuffix,
+				minSize:        minSize,
+				shouldCompress: shouldCompress,
+			}
+			defer cw.Close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// Returns the best of the offered encodings (listed in server preference
+// order) according to the given Accept-Encoding header value, or an empty
+// string if none are acceptable and the identity encoding should be used.
+func Negotiate(acceptEncoding string, offered []string) string {
+	if acceptEncoding == "" || len(offered) == 0 {
+		return ""
+	}
+	qValues := make(map[string]float64, 4)
+	for part := range strings.SplitSeq(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if name == "x-gzip" {
+			name = Gzip
+		}
+		q := 1.0
+		for param := range strings.SplitSeq(params, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(strings.TrimSpace(k), "q") {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		qValues[name] = q
+	}
+	best, bestQ := "", 0.0
+	for _, e := range offered {
+		q, ok := qValues[e]
+		if !ok {
+			q, ok = qValues["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	return best
+}
+
+// Returns the conventional file extension (including the leading dot) for
+// precompressed files in the given encoding, or an empty string if unknown.
+func FileExtension(encoding string) string {
+	switch encoding {
+	case Brotli:
+		return ".br"
+	case Zstd:
+		return ".zst"
+	case Gzip:
+		return ".gz"
+	}
+	return ""
+}
+
+// Reports whether a response of the given Content-Type is likely to shrink
+// meaningfully when compressed. Server-sent event streams are excluded so
+// that events are never held back waiting for an encoder to flush.
+func IsCompressibleContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if mediaType == "text/event-stream" {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "+json") ||
+		strings.HasSuffix(mediaType, "+xml") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/javascript", "application/x-javascript",
+		"application/ecmascript", "application/xml", "application/wasm",
+		"application/x-ndjson", "application/vnd.ms-fontobject",
+		"image/svg+xml", "image/x-icon", "image/vnd.microsoft.icon", "image/bmp",
+		"font/ttf", "font/otf":
+		return true
+	}
+	return false
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// ETAGS
+/////////////////////////////////////////////////////////////////////
+
+func addETagSuffix(etag, encoding string) string {
+	if len(etag) < 2 || strings.HasPrefix(etag, "W/") || etag[len(etag)-1] != '"' {
+		return etag
+	}
+	return etag[:len(etag)-1] + "-" + encoding + `"`
+}
+
+func stripETagSuffixes(r *http.Request, encodings []string) (*http.Request, bool) {
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	if ifNoneMatch == "" || !strings.Contains(ifNoneMatch, `-`) {
+		return r, false
+	}
+	stripped := false
+	parts := strings.Split(ifNoneMatch, ",")
+	for i, part := range parts {
+		trimmed := strings.TrimSpace(part)
+		if strings.HasPrefix(trimmed, "W/") {
+			continue
+		}
+		for _, e := range encodings {
+			if suffix := "-" + e + `"`; strings.HasSuffix(trimmed, suffix) {
+				parts[i] = trimmed[:len(trimmed)-len(suffix)] + `"`
+				stripped = true
+				break
+			}
+		}
+	}
+	if !stripped {
+		return r, false
+	}
+	r = r.Clone(r.Context())
+	r.Header.Set("If-None-Match", strings.Join(parts, ","))
+	return r, true
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// WRITER
+/////////////////////////////////////////////////////////////////////
+
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var writerPools = map[string]*sync.Pool{
+	Brotli: {New: func() any { return brotli.NewWriterLevel(nil, 5) }},
+	Gzip:   {New: func() any { return gzip.NewWriter(nil) }},
+	Zstd: {New: func() any {
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return enc
+	}},
+}
+
+type compressWriter struct {
+	w              http.ResponseWriter
+	head           bool // headers only; any body written is dropped
+	encoding       string
+	strippedSuffix bool
+	minSize        int
+	shouldCompress func(contentType string) bool
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	enc         encoder
+}
+
+func (cw *compressWriter) Header() http.Header {
+	return cw.w.Header()
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.decided || cw.wroteHeader {
+		return
+	}
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		cw.w.WriteHeader(code) // e.g., 103 Early Hints
+		return
+	}
+	cw.status = code
+	cw.wroteHeader = true
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.head {
+			return len(b), nil
+		}
+		if cw.enc != nil {
+			return cw.enc.Write(b)
+		}
+		return cw.w.Write(b)
+	}
+	cw.buf = append(cw.buf, b...)
+	if cw.minSize < 0 || len(cw.buf) >= cw.minSize {
+		if err := cw.decideAndFlushBuf(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+func (cw *compressWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.decided {
+		cw.decideAndFlushBuf()
+	}
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	http.NewResponseController(cw.w).Flush()
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.w
+}
+
+func (cw *compressWriter) Close() {
+	if !cw.decided && cw.wroteHeader {
+		cw.decideAndFlushBuf()
+	}
+	if cw.enc != nil {
+		cw.enc.Close()
+		cw.enc.Reset(nil)
+		writerPools[cw.encoding].Put(cw.enc)
+		cw.enc = nil
+	}
+}
+
+func (cw *compressWriter) decideAndFlushBuf() error {
+	cw.decided = true
+	h := cw.w.Header()
+	if _, hasType := h["Content-Type"]; !hasType && len(cw.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+	if cw.status == http.StatusNotModified && cw.strippedSuffix && cw.encoding != "" {
+		if etag := h.Get("ETag"); etag != "" {
+			h.Set("ETag", addETagSuffix(etag, cw.encoding))
+		}
+	}
+	if cw.isEligible(h) {
+		addVary(h, "Accept-Encoding")
+		if cw.encoding != "" {
+			if !cw.head {
+				cw.enc = writerPools[cw.encoding].Get().(encoder)
+				cw.enc.Reset(cw.w)
+			}
+			h.Set("Content-Encoding", cw.encoding)
+			h.Del("Content-Length")
+			h.Del("Accept-Ranges")
+			if etag := h.Get("ETag"); etag != "" {
+				h.Set("ETag", addETagSuffix(etag, cw.encoding))
+			}
+		}
+	}
+	cw.w.WriteHeader(cw.status)
+	if cw.head || len(cw.buf) == 0 {
+		cw.buf = nil
+		return nil
+	}
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(cw.buf)
+	} else {
+		_, err = cw.w.Write(cw.buf)
+	}
+	cw.buf = nil
+	return err
+}
+
+func (cw *compressWriter) isEligible(h http.Header) bool {
+	switch {
+	case cw.status < 200,
+		cw.status == http.StatusNoContent,
+		cw.status == http.StatusPartialContent,
+		cw.status == http.StatusNotModified:
+		return false
+	case cw.minSize > 0 && cw.bodySize(h) < cw.minSize:
+		return false
+	case h.Get("Content-Encoding") != "",
+		h.Get("Content-Range") != "",
+		strings.Contains(h.Get("Cache-Control"), "no-transform"):
+		return false
+	}
+	return cw.shouldCompress(h.Get("Content-Type"))
+}
+
+// A HEAD handler may set Content-Length without writing a body. If it does
+// neither, the size is unknown, so assume it's large enough to compress.
+func (cw *compressWriter) bodySize(h http.Header) int {
+	if !cw.head || len(cw.buf) > 0 {
+		return len(cw.buf)
+	}
+	if n, err := strconv.Atoi(h.Get("Content-Length")); err == nil {
+		return n
+	}
+	return cw.minSize
+}
+
+func addVary(h http.Header, value string) {
+	for _, existing := range h.Values("Vary") {
+		for v := range strings.SplitSeq(existing, ",") {
+			v = strings.TrimSpace(v)
+			if v == "*" || strings.EqualFold(v, value) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", value)
+}
@@ -0,0 +1,415 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/river-now/river/kit/middleware/etag"
+)
+
+var largeBody = strings.Repeat(`{"hello":"world"},`, 200)
+
+func serve(h http.Handler, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func textHandler(contentType, body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.Write([]byte(body))
+	})
+}
+
+func decode(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+	var r io.Reader
+	switch encoding {
+	case Gzip:
+		gr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("gzip reader: %v", err)
+		}
+		r = gr
+	case Brotli:
+		r = brotli.NewReader(bytes.NewReader(body))
+	case Zstd:
+		zr, err := zstd.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("zstd reader: %v", err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return string(body)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("decoding %s: %v", encoding, err)
+	}
+	return string(out)
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		offered        []string
+		expected       string
+	}{
+		{"empty header", "", DefaultEncodings, ""},
+		{"server preference wins ties", "gzip, br, zstd", DefaultEncodings, Brotli},
+		{"q-values respected", "br;q=0.5, gzip;q=0.9", DefaultEncodings, Gzip},
+		{"q=0 excludes", "br;q=0, gzip", DefaultEncodings, Gzip},
+		{"wildcard", "*", DefaultEncodings, Brotli},
+		{"wildcard with explicit exclusion", "br;q=0, *;q=0.5", DefaultEncodings, Zstd},
+		{"x-gzip alias", "x-gzip", DefaultEncodings, Gzip},
+		{"identity only", "identity", DefaultEncodings, ""},
+		{"not offered", "br", []string{Gzip}, ""},
+		{"case insensitive", "GZIP", DefaultEncodings, Gzip},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Negotiate(tt.acceptEncoding, tt.offered); got != tt.expected {
+				t.Errorf("Negotiate(%q) = %q, want %q", tt.acceptEncoding, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCompressesEachEncoding(t *testing.T) {
+	for _, encoding := range DefaultEncodings {
+		t.Run(encoding, func(t *testing.T) {
+			h := Auto()(textHandler("application/json", largeBody))
+			rec := serve(h, map[string]string{"Accept-Encoding": encoding})
+
+			if got := rec.Header().Get("Content-Encoding"); got != encoding {
+				t.Fatalf("expected Content-Encoding %q, got %q", encoding, got)
+			}
+			if rec.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("expected Vary: Accept-Encoding, got %q", rec.Header().Get("Vary"))
+			}
+			if rec.Header().Get("Content-Length") != "" {
+				t.Error("expected Content-Length to be removed")
+			}
+			if rec.Body.Len() >= len(largeBody) {
+				t.Errorf("expected compressed body to be smaller than %d, got %d", len(largeBody), rec.Body.Len())
+			}
+			if got := decode(t, encoding, rec.Body.Bytes()); got != largeBody {
+				t.Error("decoded body does not match original")
+			}
+		})
+	}
+}
+
+func TestSkips(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.Handler
+		config  *Config
+		vary    bool
+	}{
+		{
+			name:    "small body",
+			handler: textHandler("text/plain", "tiny"),
+		},
+		{
+			name:    "incompressible content type",
+			handler: textHandler("image/png", largeBody),
+		},
+		{
+			name: "already encoded",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.Header().Set("Content-Encoding", "br")
+				w.Write([]byte(largeBody))
+			}),
+		},
+		{
+			name: "no-transform",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.Header().Set("Cache-Control", "public, no-transform")
+				w.Write([]byte(largeBody))
+			}),
+		},
+		{
+			name: "partial content",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write([]byte(largeBody))
+			}),
+		},
+		{
+			name:    "skip func",
+			handler: textHandler("text/plain", largeBody),
+			config:  &Config{SkipFunc: func(r *http.Request) bool { return true }},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(Auto(tt.config)(tt.handler), map[string]string{"Accept-Encoding": "gzip"})
+			if got := rec.Header().Get("Content-Encoding"); got == Gzip {
+				t.Errorf("expected no gzip encoding, got %q", got)
+			}
+			if rec.Header().Get("Vary") != "" {
+				t.Errorf("expected no Vary header, got %q", rec.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestVaryWithoutAcceptEncoding(t *testing.T) {
+	rec := serve(Auto()(textHandler("text/html", largeBody)), nil)
+	if rec.Header().Get("Content-Encoding") != "" {
+		t.Error("expected identity response")
+	}
+	if rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("expected Vary: Accept-Encoding on compressible response, got %q", rec.Header().Get("Vary"))
+	}
+	if rec.Body.String() != largeBody {
+		t.Error("body mismatch")
+	}
+}
+
+func TestVaryNotDuplicated(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Vary", "Origin, accept-encoding")
+		w.Write([]byte(largeBody))
+	})
+	rec := serve(Auto()(h), map[string]string{"Accept-Encoding": "gzip"})
+	if vals := rec.Header().Values("Vary"); len(vals) != 1 {
+		t.Errorf("expected Vary to be left alone, got %v", vals)
+	}
+}
+
+func TestSniffsContentType(t *testing.T) {
+	rec := serve(Auto()(textHandler("", "<html><body>"+largeBody+"</body></html>")), map[string]string{
+		"Accept-Encoding": "gzip",
+	})
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("expected sniffed text/html, got %q", rec.Header().Get("Content-Type"))
+	}
+	if rec.Header().Get("Content-Encoding") != Gzip {
+		t.Error("expected sniffed HTML to be compressed")
+	}
+}
+
+func TestMinSize(t *testing.T) {
+	h := Auto(&Config{MinSize: -1})(textHandler("text/plain", "tiny"))
+	rec := serve(h, map[string]string{"Accept-Encoding": "gzip"})
+	if rec.Header().Get("Content-Encoding") != Gzip {
+		t.Error("expected compression when MinSize is negative")
+	}
+	if decode(t, Gzip, rec.Body.Bytes()) != "tiny" {
+		t.Error("body mismatch")
+	}
+}
+
+func TestCustomEncodings(t *testing.T) {
+	h := Auto(&Config{Encodings: []string{Gzip, "nope"}})(textHandler("text/plain", largeBody))
+	rec := serve(h, map[string]string{"Accept-Encoding": "br, gzip;q=0.1"})
+	if rec.Header().Get("Content-Encoding") != Gzip {
+		t.Errorf("expected gzip, got %q", rec.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestStatusPreserved(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(largeBody))
+	})
+	rec := serve(Auto()(h), map[string]string{"Accept-Encoding": "gzip"})
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+	if decode(t, Gzip, rec.Body.Bytes()) != largeBody {
+		t.Error("body mismatch")
+	}
+}
+
+func TestEmptyResponse(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	rec := serve(Auto()(h), map[string]string{"Accept-Encoding": "gzip"})
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", rec.Code)
+	}
+	if rec.Header().Get("Content-Encoding") != "" {
+		t.Error("expected no Content-Encoding on 204")
+	}
+}
+
+func TestFlush(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(largeBody))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("more"))
+	})
+	rec := serve(Auto()(h), map[string]string{"Accept-Encoding": "gzip"})
+	if !rec.Flushed {
+		t.Error("expected underlying writer to be flushed")
+	}
+	if decode(t, Gzip, rec.Body.Bytes()) != largeBody+"more" {
+		t.Error("body mismatch")
+	}
+}
+
+func TestHead(t *testing.T) {
+	serveBoth := func(t *testing.T, h http.Handler, headers map[string]string) (get, head *httptest.ResponseRecorder) {
+		t.Helper()
+		get = serve(h, headers)
+		req := httptest.NewRequest(http.MethodHead, "/", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		head = httptest.NewRecorder()
+		h.ServeHTTP(head, req)
+		if head.Body.Len() != 0 {
+			t.Errorf("expected no HEAD body, got %d bytes", head.Body.Len())
+		}
+		for _, name := range []string{"Content-Encoding", "Vary", "ETag", "Content-Length"} {
+			if got, want := head.Header().Get(name), get.Header().Get(name); got != want {
+				t.Errorf("HEAD %s = %q, GET %s = %q", name, got, name, want)
+			}
+		}
+		return get, head
+	}
+
+	t.Run("HandlerWritesBody", func(t *testing.T) {
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("ETag", `"abc"`)
+			w.Write([]byte(largeBody))
+		})
+		_, head := serveBoth(t, Auto()(h), map[string]string{"Accept-Encoding": "br"})
+		if head.Header().Get("Content-Encoding") != Brotli || head.Header().Get("ETag") != `"abc-br"` {
+			t.Errorf("unexpected HEAD headers: %v", head.Header())
+		}
+	})
+
+	t.Run("ServeContent", func(t *testing.T) {
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			http.ServeContent(w, r, "", time.Time{}, strings.NewReader(largeBody))
+		})
+		_, head := serveBoth(t, Auto()(h), map[string]string{"Accept-Encoding": "gzip"})
+		if head.Header().Get("Content-Encoding") != Gzip {
+			t.Errorf("expected gzip Content-Encoding on HEAD, got %v", head.Header())
+		}
+	})
+
+	t.Run("TooSmall", func(t *testing.T) {
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			http.ServeContent(w, r, "", time.Time{}, strings.NewReader("small"))
+		})
+		_, head := serveBoth(t, Auto()(h), map[string]string{"Accept-Encoding": "gzip"})
+		if head.Header().Get("Content-Encoding") != "" {
+			t.Error("expected small HEAD response to be left uncompressed")
+		}
+	})
+
+	t.Run("NoAcceptEncoding", func(t *testing.T) {
+		_, head := serveBoth(t, Auto()(textHandler("text/plain", largeBody)), nil)
+		if head.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("expected Vary: Accept-Encoding on HEAD, got %v", head.Header())
+		}
+	})
+}
+
+func TestStrongETagSuffix(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("ETag", `"abc"`)
+		w.Write([]byte(largeBody))
+	})
+	rec := serve(Auto()(h), map[string]string{"Accept-Encoding": "br"})
+	if got := rec.Header().Get("ETag"); got != `"abc-br"` {
+		t.Errorf(`expected ETag "abc-br", got %s`, got)
+	}
+}
+
+func TestWeakETagUnchanged(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("ETag", `W/"abc"`)
+		w.Write([]byte(largeBody))
+	})
+	rec := serve(Auto()(h), map[string]string{"Accept-Encoding": "br"})
+	if got := rec.Header().Get("ETag"); got != `W/"abc"` {
+		t.Errorf(`expected ETag W/"abc", got %s`, got)
+	}
+}
+
+func TestETagInterop(t *testing.T) {
+	inner := textHandler("text/plain", largeBody)
+	chains := map[string]http.Handler{
+		"compress outside strong etag": Auto()(etag.Auto(&etag.Config{Strong: true})(inner)),
+		"compress inside strong etag":  etag.Auto(&etag.Config{Strong: true})(Auto()(inner)),
+		"compress outside weak etag":   Auto()(etag.Auto()(inner)),
+	}
+	for name, h := range chains {
+		t.Run(name, func(t *testing.T) {
+			first := serve(h, map[string]string{"Accept-Encoding": "gzip"})
+			tag := first.Header().Get("ETag")
+			if tag == "" {
+				t.Fatal("expected an ETag")
+			}
+			if first.Header().Get("Content-Encoding") != Gzip {
+				t.Fatal("expected gzip response")
+			}
+
+			identity := serve(h, nil)
+			if !strings.HasPrefix(tag, "W/") && identity.Header().Get("ETag") == tag {
+				t.Error("expected strong ETags to differ between encoded and identity representations")
+			}
+
+			second := serve(h, map[string]string{"Accept-Encoding": "gzip", "If-None-Match": tag})
+			if second.Code != http.StatusNotModified {
+				t.Fatalf("expected 304, got %d", second.Code)
+			}
+			if got := second.Header().Get("ETag"); got != tag {
+				t.Errorf("expected 304 ETag %s, got %s", tag, got)
+			}
+		})
+	}
+}
+
+func TestIsCompressibleContentType(t *testing.T) {
+	yes := []string{
+		"text/html; charset=utf-8", "application/json", "application/javascript",
+		"image/svg+xml", "application/ld+json", "application/atom+xml", "application/wasm",
+	}
+	no := []string{"image/png", "video/mp4", "application/zip", "font/woff2", "text/event-stream", ""}
+	for _, ct := range yes {
+		if !IsCompressibleContentType(ct) {
+			t.Errorf("expected %q to be compressible", ct)
+		}
+	}
+	for _, ct := range no {
+		if IsCompressibleContentType(ct) {
+			t.Errorf("expected %q not to be compressible", ct)
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package secureheaders
 
-import "net/http"
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
 
 // see https://owasp.org/www-project-secure-headers/ci/headers_add.json
 var securityHeadersMap = map[string]string{
@@ -15,14 +20,187 @@ var securityHeadersMap = map[string]string{
 	"X-Permitted-Cross-Domain-Policies": "none",
 }
 
-// Sets various security-related headers to responses.
+var defaultBuilder = New(Config{})
+
+// Sets various security-related headers to responses, using the defaults
+// (see Config). For anything else, use New.
 func Middleware(next http.Handler) http.Handler {
+	return defaultBuilder.Middleware(next)
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// BUILDER
+/////////////////////////////////////////////////////////////////////
+
+// Set a string field of Config to Omit to not send that header at all.
+const Omit = "omit"
+
+// Every field is optional. Empty strings mean "use the default", which
+// matches the OWASP recommendation (see securityHeadersMap).
+type Config struct {
+	HSTS HSTSConfig
+	// Defaults to denying every feature except sync-xhr for the same origin.
+	PermissionsPolicy         string
+	CrossOriginOpenerPolicy   string // Defaults to "same-origin"
+	CrossOriginEmbedderPolicy string // Defaults to "require-corp"
+	CrossOriginResourcePolicy string // Defaults to "same-origin"
+	ReferrerPolicy            string // Defaults to "no-referrer"
+	// X-Frame-Options. Defaults to "deny". Can also be "sameorigin". To allow
+	// framing by specific other origins, set this to Omit and use the CSP
+	// frame-ancestors directive instead (see csp.FrameAncestors).
+	FrameOptions string
+	// Optional. Resolves to false if nil. In dev, HSTS and
+	// Cross-Origin-Embedder-Policy are never sent, so that plain-HTTP
+	// localhost and dev servers on other ports (e.g., Vite) keep working.
+	GetIsDev func() bool
+}
+
+type HSTSConfig struct {
+	MaxAge            time.Duration // Defaults to 1 year
+	ExcludeSubDomains bool
+	// Opts in to browser preload lists. Requires a MaxAge of at least 1
+	// year, and can't be combined with ExcludeSubDomains. Preloading is hard
+	// to undo, so see https://hstspreload.org first.
+	Preload  bool
+	Disabled bool
+}
+
+type Builder struct {
+	cfg      Config
+	headers  map[string]string // Omitted headers map to ""
+	devHdrs  map[string]string
+	getIsDev func() bool
+}
+
+// Panics if the config is invalid.
+func New(cfg Config) *Builder {
+	b := &Builder{cfg: cfg, getIsDev: cfg.GetIsDev}
+	b.headers = resolveHeaders(cfg)
+	b.devHdrs = make(map[string]string, len(b.headers))
+	for header, value := range b.headers {
+		b.devHdrs[header] = value
+	}
+	b.devHdrs["Strict-Transport-Security"] = ""
+	b.devHdrs["Cross-Origin-Embedder-Policy"] = ""
+	return b
+}
+
+// Sets the configured headers on every response, and removes the Server and
+// X-Powered-By headers.
+func (b *Builder) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		for header, value := range securityHeadersMap {
-			w.Header().Set(header, value)
-		}
+		b.apply(w)
 		w.Header().Del("Server")
 		w.Header().Del("X-Powered-By")
 		next.ServeHTTP(w, r)
 	})
 }
+
+// Override returns a middleware that replaces the headers set by b's
+// Middleware with those of b's config, updated by any non-zero fields of
+// overrides. Register it at the pattern level (with
+// mux.SetPatternLevelHTTPMiddleware), so it runs after b.Middleware.
+// Example (allowing one route to be framed, which with kit/csp also needs
+// csp.FrameAncestors):
+//
+//	embedHeaders := headers.Override(secureheaders.Config{FrameOptions: secureheaders.Omit})
+//	mux.SetPatternLevelHTTPMiddleware(embedRoute, embedHeaders)
+//	mux.SetPatternLevelHTTPMiddleware(embedRoute, csp.FrameAncestors("https://partner.example.com"))
+//
+// Panics if the resulting config is invalid.
+func (b *Builder) Override(overrides Config) func(http.Handler) http.Handler {
+	cfg := b.cfg
+	if overrides.HSTS != (HSTSConfig{}) {
+		cfg.HSTS = overrides.HSTS
+	}
+	for _, f := range []struct {
+		dst *string
+		src string
+	}{
+		{&cfg.PermissionsPolicy, overrides.PermissionsPolicy},
+		{&cfg.CrossOriginOpenerPolicy, overrides.CrossOriginOpenerPolicy},
+		{&cfg.CrossOriginEmbedderPolicy, overrides.CrossOriginEmbedderPolicy},
+		{&cfg.CrossOriginResourcePolicy, overrides.CrossOriginResourcePolicy},
+		{&cfg.ReferrerPolicy, overrides.ReferrerPolicy},
+		{&cfg.FrameOptions, overrides.FrameOptions},
+	} {
+		if f.src != "" {
+			*f.dst = f.src
+		}
+	}
+	if overrides.GetIsDev != nil {
+		cfg.GetIsDev = overrides.GetIsDev
+	}
+	ob := New(cfg)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ob.apply(w)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (b *Builder) apply(w http.ResponseWriter) {
+	headers := b.headers
+	if b.getIsDev != nil && b.getIsDev() {
+		headers = b.devHdrs
+	}
+	for header, value := range headers {
+		if value == "" {
+			w.Header().Del(header)
+		} else {
+			w.Header().Set(header, value)
+		}
+	}
+}
+
+func resolveHeaders(cfg Config) map[string]string {
+	headers := make(map[string]string, len(securityHeadersMap))
+	for header, value := range securityHeadersMap {
+		headers[header] = value
+	}
+	for header, value := range map[string]string{
+		"Permissions-Policy":           cfg.PermissionsPolicy,
+		"Cross-Origin-Opener-Policy":   cfg.CrossOriginOpenerPolicy,
+		"Cross-Origin-Embedder-Policy": cfg.CrossOriginEmbedderPolicy,
+		"Cross-Origin-Resource-Policy": cfg.CrossOriginResourcePolicy,
+		"Referrer-Policy":              cfg.ReferrerPolicy,
+		"X-Frame-Options":              cfg.FrameOptions,
+	} {
+		switch value {
+		case "":
+		case Omit:
+			headers[header] = ""
+		default:
+			headers[header] = value
+		}
+	}
+	headers["Strict-Transport-Security"] = resolveHSTS(cfg.HSTS)
+	return headers
+}
+
+func resolveHSTS(cfg HSTSConfig) string {
+	if cfg.Disabled {
+		return ""
+	}
+	maxAge := cfg.MaxAge
+	if maxAge == 0 {
+		maxAge = 365 * 24 * time.Hour
+	}
+	if maxAge < 0 {
+		panic("secureheaders: HSTS.MaxAge must be positive")
+	}
+	if cfg.Preload && (cfg.ExcludeSubDomains || maxAge < 365*24*time.Hour) {
+		panic(fmt.Sprintf(
+			"secureheaders: HSTS.Preload requires subdomains and a MaxAge of at least 1 year (got %v)", maxAge,
+		))
+	}
+	value := "max-age=" + strconv.FormatInt(int64(maxAge/time.Second), 10)
+	if !cfg.ExcludeSubDomains {
+		value += "; includeSubDomains"
+	}
+	if cfg.Preload {
+		value += "; preload"
+	}
+	return value
+}
@@ -3,7 +3,12 @@ package secureheaders
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/river-now/river/kit/csp"
+	"github.com/river-now/river/kit/mux"
 )
 
 func TestMiddleware_SetsSecurityHeaders(t *testing.T) {
@@ -85,3 +90,134 @@ func TestMiddleware_DoesNotOverrideExistingHeaders(t *testing.T) {
 		}
 	}
 }
+
+func TestBuilder(t *testing.T) {
+	serve := func(mw func(http.Handler) http.Handler) http.Header {
+		rr := httptest.NewRecorder()
+		mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(
+			rr, httptest.NewRequest(http.MethodGet, "/", nil),
+		)
+		return rr.Header()
+	}
+
+	t.Run("CustomValues", func(t *testing.T) {
+		h := serve(New(Config{
+			ReferrerPolicy:            "strict-origin-when-cross-origin",
+			FrameOptions:              "sameorigin",
+			CrossOriginEmbedderPolicy: Omit,
+		}).Middleware)
+		if got := h.Get("Referrer-Policy"); got != "strict-origin-when-cross-origin" {
+			t.Errorf("expected custom Referrer-Policy, got %q", got)
+		}
+		if got := h.Get("X-Frame-Options"); got != "sameorigin" {
+			t.Errorf("expected X-Frame-Options sameorigin, got %q", got)
+		}
+		if _, ok := h["Cross-Origin-Embedder-Policy"]; ok {
+			t.Error("expected Cross-Origin-Embedder-Policy to be omitted")
+		}
+		if got := h.Get("Cross-Origin-Opener-Policy"); got != "same-origin" {
+			t.Errorf("expected default Cross-Origin-Opener-Policy, got %q", got)
+		}
+	})
+
+	t.Run("HSTS", func(t *testing.T) {
+		h := serve(New(Config{HSTS: HSTSConfig{MaxAge: 2 * 365 * 24 * time.Hour, Preload: true}}).Middleware)
+		if got, want := h.Get("Strict-Transport-Security"), "max-age=63072000; includeSubDomains; preload"; got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+		h = serve(New(Config{HSTS: HSTSConfig{MaxAge: time.Hour, ExcludeSubDomains: true}}).Middleware)
+		if got, want := h.Get("Strict-Transport-Security"), "max-age=3600"; got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+		h = serve(New(Config{HSTS: HSTSConfig{Disabled: true}}).Middleware)
+		if _, ok := h["Strict-Transport-Security"]; ok {
+			t.Error("expected HSTS to be omitted")
+		}
+	})
+
+	t.Run("InvalidPreloadPanics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic")
+			}
+		}()
+		New(Config{HSTS: HSTSConfig{MaxAge: time.Hour, Preload: true}})
+	})
+
+	t.Run("DevRelaxesDefaults", func(t *testing.T) {
+		isDev := true
+		b := New(Config{GetIsDev: func() bool { return isDev }})
+		h := serve(b.Middleware)
+		for _, header := range []string{"Strict-Transport-Security", "Cross-Origin-Embedder-Policy"} {
+			if _, ok := h[header]; ok {
+				t.Errorf("expected %s to be omitted in dev", header)
+			}
+		}
+		if got := h.Get("X-Frame-Options"); got != "deny" {
+			t.Errorf("expected X-Frame-Options deny in dev, got %q", got)
+		}
+		isDev = false
+		if got := serve(b.Middleware).Get("Strict-Transport-Security"); got == "" {
+			t.Error("expected HSTS outside of dev")
+		}
+	})
+
+	t.Run("PatternLevelOverride", func(t *testing.T) {
+		b := New(Config{})
+		r := mux.NewRouter(nil)
+		mux.SetGlobalHTTPMiddleware(r, b.Middleware)
+		noop := func(w http.ResponseWriter, r *http.Request) {}
+		mux.RegisterHandlerFunc(r, http.MethodGet, "/app", noop)
+		embed := mux.RegisterHandlerFunc(r, http.MethodGet, "/embed", noop)
+		mux.SetPatternLevelHTTPMiddleware(embed, b.Override(Config{FrameOptions: Omit}))
+
+		get := func(path string) http.Header {
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+			return rr.Header()
+		}
+
+		if got := get("/app").Get("X-Frame-Options"); got != "deny" {
+			t.Errorf("expected X-Frame-Options deny on /app, got %q", got)
+		}
+		h := get("/embed")
+		if _, ok := h["X-Frame-Options"]; ok {
+			t.Errorf("expected X-Frame-Options to be omitted on /embed, got %q", h.Get("X-Frame-Options"))
+		}
+		if got := h.Get("Referrer-Policy"); got != "no-referrer" {
+			t.Errorf("expected other headers to be kept on /embed, got Referrer-Policy %q", got)
+		}
+	})
+	t.Run("PatternLevelOverrideWithCSP", func(t *testing.T) {
+		b := New(Config{})
+		r := mux.NewRouter(nil)
+		mux.SetGlobalHTTPMiddleware(r, b.Middleware)
+		mux.SetGlobalHTTPMiddleware(r, csp.NewBuilder(csp.Config{}).Middleware)
+		noop := func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) }
+		mux.RegisterHandlerFunc(r, http.MethodGet, "/app", noop)
+		embed := mux.RegisterHandlerFunc(r, http.MethodGet, "/embed", noop)
+		mux.SetPatternLevelHTTPMiddleware(embed, b.Override(Config{FrameOptions: Omit}))
+		mux.SetPatternLevelHTTPMiddleware(embed, csp.FrameAncestors("'self'", "https://partner.example.com"))
+
+		get := func(path string) http.Header {
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+			return rr.Header()
+		}
+
+		h := get("/app")
+		if got := h.Get("X-Frame-Options"); got != "deny" {
+			t.Errorf("expected X-Frame-Options deny on /app, got %q", got)
+		}
+		if got := h.Get(csp.HeaderName); !strings.Contains(got, "frame-ancestors 'none';") {
+			t.Errorf("expected frame-ancestors 'none' on /app, got %q", got)
+		}
+		h = get("/embed")
+		if _, ok := h["X-Frame-Options"]; ok {
+			t.Errorf("expected X-Frame-Options to be omitted on /embed, got %q", h.Get("X-Frame-Options"))
+		}
+		if got := h.Get(csp.HeaderName); !strings.Contains(got, "frame-ancestors 'self' https://partner.example.com;") {
+			t.Errorf("expected frame-ancestors override on /embed, got %q", got)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package mux
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+/////////////////////////////////////////////////////////////////////
+/////// AUTH
+/////////////////////////////////////////////////////////////////////
+
+// P is your principal type, typically a pointer to a user or session struct.
+type AuthConfig[P any] struct {
+	// REQUIRED. Resolves the principal for a request (e.g., from a session
+	// cookie or bearer token). Return the zero value and a nil error for
+	// anonymous requests. Errors result in a 500.
+	ResolvePrincipal func(r *http.Request) (P, error)
+	// Optional. Reports whether a resolved principal is logged in. Defaults
+	// to checking that it is not the zero value.
+	IsAuthenticated func(principal P) bool
+	// Optional, but required to use AuthRequirements.Roles.
+	GetRoles func(principal P) []string
+	// Optional, but required to use AuthRequirements.Scopes.
+	GetScopes func(principal P) []string
+}
+
+// Auth resolves and checks principals. There is no ReqData accessor for the
+// principal, since ReqData isn't parameterized by P; from a task handler or
+// task middleware, call Principal(rd.Request()).
+type Auth[P any] struct {
+	cfg AuthConfig[P]
+}
+
+type principalResult[P any] struct {
+	once      sync.Once
+	principal P
+	err       error
+}
+
+// Panics if ResolvePrincipal is nil.
+func NewAuth[P any](cfg AuthConfig[P]) *Auth[P] {
+	if cfg.ResolvePrincipal == nil {
+		panic("mux: AuthConfig.ResolvePrincipal is required")
+	}
+	if cfg.IsAuthenticated == nil {
+		cfg.IsAuthenticated = func(principal P) bool {
+			return !reflect.ValueOf(&principal).Elem().IsZero()
+		}
+	}
+	return &Auth[P]{cfg: cfg}
+}
+
+// Principal returns the request's principal, resolving it at most once per
+// request (across all middleware and the handler). ResolvePrincipal gets the
+// request passed by the first caller, so it sees that request's context
+// (e.g., values set by earlier HTTP middleware) and GetParam works on it.
+func (a *Auth[P]) Principal(r *http.Request) (P, error) {
+	rd := requestStore.GetValueFromContext(r.Context())
+	if rd == nil {
+		return a.cfg.ResolvePrincipal(r)
+	}
+	v, _ := rd.principals.LoadOrStore(a, &principalResult[P]{})
+	res := v.(*principalResult[P])
+	res.once.Do(func() {
+		res.principal, res.err = a.cfg.ResolvePrincipal(r)
+	})
+	return res.principal, res.err
+}
+
+// TaskMiddleware resolves the principal up front, in parallel with any
+// other task middleware. It never rejects a request on its own (for that,
+// see SetPatternLevelAuthRequirements), so it is safe to set globally.
+func (a *Auth[P]) TaskMiddleware() *TaskMiddleware[P] {
+	return TaskMiddlewareFromFunc(func(rd *ReqData[None]) (P, error) {
+		return a.Principal(rd.Request())
+	})
+}
+
+// Every requirement implies that the principal must be logged in.
+type AuthRequirements[P any] struct {
+	// The principal must have at least one of these roles.
+	Roles []string
+	// The principal must have all of these scopes.
+	Scopes []string
+	// Optional. Runs only if every other requirement is met. Return false
+	// to reject the request with a 403.
+	Policy func(rd *ReqData[None], principal P) (bool, error)
+}
+
+// RouteAuthInfo describes a route's auth requirements. It is exported to
+// the generated TypeScript, so don't put anything secret in role or scope
+// names.
+type RouteAuthInfo struct {
+	RequiresLogin bool     `json:"requiresLogin"`
+	Roles         []string `json:"roles,omitempty"`
+	Scopes        []string `json:"scopes,omitempty"`
+	HasPolicy     bool     `json:"hasPolicy,omitempty"`
+}
+
+// SetPatternLevelAuthRequirements adds a task middleware to route that
+// rejects anonymous requests with a 401, and requests that don't meet reqs
+// with a 403, and records reqs on the route (see Route.AuthInfo). Panics if
+// the route already has requirements, or if reqs uses roles or scopes that
+// auth has no way to look up.
+func SetPatternLevelAuthRequirements[P any, I any, O any](
+	route *Route[I, O], auth *Auth[P], reqs AuthRequirements[P],
+) {
+	if route.authInfo != nil {
+		panic(fmt.Sprintf("mux: auth requirements already set for %s %s", route.method, route.originalPattern))
+	}
+	if len(reqs.Roles) > 0 && auth.cfg.GetRoles == nil {
+		panic("mux: AuthConfig.GetRoles is required to use AuthRequirements.Roles")
+	}
+	if len(reqs.Scopes) > 0 && auth.cfg.GetScopes == nil {
+		panic("mux: AuthConfig.GetScopes is required to use AuthRequirements.Scopes")
+	}
+	route.authInfo = &RouteAuthInfo{
+		RequiresLogin: true,
+		Roles:         slices.Clone(reqs.Roles),
+		Scopes:        slices.Clone(reqs.Scopes),
+		HasPolicy:     reqs.Policy != nil,
+	}
+	SetPatternLevelTaskMiddleware(route, TaskMiddlewareFromFunc(func(rd *ReqData[None]) (None, error) {
+		principal, err := auth.Principal(rd.Request())
+		if err != nil {
+			return None{}, err
+		}
+		if !auth.cfg.IsAuthenticated(principal) {
+			rd.ResponseProxy().SetStatus(http.StatusUnauthorized)
+			return None{}, nil
+		}
+		if !auth.isAllowed(principal, reqs) {
+			rd.ResponseProxy().SetStatus(http.StatusForbidden)
+			return None{}, nil
+		}
+		if reqs.Policy != nil {
+			ok, err := reqs.Policy(rd, principal)
+			if err != nil {
+				return None{}, err
+			}
+			if !ok {
+				rd.ResponseProxy().SetStatus(http.StatusForbidden)
+			}
+		}
+		return None{}, nil
+	}))
+}
+
+func (a *Auth[P]) isAllowed(principal P, reqs AuthRequirements[P]) bool {
+	if len(reqs.Roles) > 0 {
+		roles := a.cfg.GetRoles(principal)
+		if !slices.ContainsFunc(reqs.Roles, func(role string) bool { return slices.Contains(roles, role) }) {
+			return false
+		}
+	}
+	if len(reqs.Scopes) > 0 {
+		scopes := a.cfg.GetScopes(principal)
+		for _, scope := range reqs.Scopes {
+			if !slices.Contains(scopes, scope) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,232 @@
+package mux
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+type testUser struct {
+	Name   string
+	Roles  []string
+	Scopes []string
+}
+
+var testUsers = map[string]*testUser{
+	"alice": {Name: "alice", Roles: []string{"admin"}, Scopes: []string{"read", "write"}},
+	"bob":   {Name: "bob", Roles: []string{"member"}, Scopes: []string{"read"}},
+}
+
+func newTestAuth(resolveCount *atomic.Int32) *Auth[*testUser] {
+	return NewAuth(AuthConfig[*testUser]{
+		ResolvePrincipal: func(r *http.Request) (*testUser, error) {
+			resolveCount.Add(1)
+			if r.Header.Get("X-User") == "broken" {
+				return nil, errors.New("session store unavailable")
+			}
+			return testUsers[r.Header.Get("X-User")], nil
+		},
+		GetRoles:  func(u *testUser) []string { return u.Roles },
+		GetScopes: func(u *testUser) []string { return u.Scopes },
+	})
+}
+
+func serveAs(r *Router, method, path, user string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuth(t *testing.T) {
+	t.Run("ResolvesPrincipalOncePerRequest", func(t *testing.T) {
+		var count atomic.Int32
+		auth := newTestAuth(&count)
+		r := NewRouter(nil)
+		SetGlobalTaskMiddleware(r, auth.TaskMiddleware())
+		route := RegisterTaskHandler(r, http.MethodGet, "/me", TaskHandlerFromFunc(
+			func(rd *ReqData[None]) (string, error) {
+				u, err := auth.Principal(rd.Request())
+				if err != nil {
+					return "", err
+				}
+				return u.Name, nil
+			},
+		))
+		SetPatternLevelAuthRequirements(route, auth, AuthRequirements[*testUser]{})
+
+		w := serveAs(r, http.MethodGet, "/me", "alice")
+		if w.Code != http.StatusOK || w.Body.String() != `"alice"`+"\n" {
+			t.Fatalf("expected 200 with alice, got %d %q", w.Code, w.Body.String())
+		}
+		if got := count.Load(); got != 1 {
+			t.Errorf("expected principal to be resolved once, got %d", got)
+		}
+	})
+
+	t.Run("Requirements", func(t *testing.T) {
+		var count atomic.Int32
+		auth := newTestAuth(&count)
+		r := NewRouter(nil)
+		ok := TaskHandlerFromFunc(func(rd *ReqData[None]) (None, error) { return None{}, nil })
+
+		SetPatternLevelAuthRequirements(RegisterTaskHandler(r, http.MethodPost, "/login-only", ok),
+			auth, AuthRequirements[*testUser]{},
+		)
+		SetPatternLevelAuthRequirements(RegisterTaskHandler(r, http.MethodPost, "/admin", ok),
+			auth, AuthRequirements[*testUser]{Roles: []string{"admin", "owner"}},
+		)
+		SetPatternLevelAuthRequirements(RegisterTaskHandler(r, http.MethodPost, "/write", ok),
+			auth, AuthRequirements[*testUser]{Scopes: []string{"read", "write"}},
+		)
+		SetPatternLevelAuthRequirements(RegisterTaskHandler(r, http.MethodPost, "/own/:name", ok),
+			auth, AuthRequirements[*testUser]{
+				Policy: func(rd *ReqData[None], u *testUser) (bool, error) {
+					return rd.Params()["name"] == u.Name, nil
+				},
+			},
+		)
+		RegisterTaskHandler(r, http.MethodPost, "/public", ok)
+
+		testCases := []struct {
+			path, user string
+			want       int
+		}{
+			{"/login-only", "", http.StatusUnauthorized},
+			{"/login-only", "bob", http.StatusOK},
+			{"/admin", "", http.StatusUnauthorized},
+			{"/admin", "bob", http.StatusForbidden},
+			{"/admin", "alice", http.StatusOK},
+			{"/write", "bob", http.StatusForbidden},
+			{"/write", "alice", http.StatusOK},
+			{"/own/bob", "alice", http.StatusForbidden},
+			{"/own/bob", "bob", http.StatusOK},
+			{"/public", "", http.StatusOK},
+			{"/login-only", "broken", http.StatusInternalServerError},
+		}
+		for _, tc := range testCases {
+			if got := serveAs(r, http.MethodPost, tc.path, tc.user).Code; got != tc.want {
+				t.Errorf("%s as %q: expected %d, got %d", tc.path, tc.user, tc.want, got)
+			}
+		}
+	})
+
+	t.Run("HTTPHandler", func(t *testing.T) {
+		var count atomic.Int32
+		auth := newTestAuth(&count)
+		r := NewRouter(nil)
+		route := RegisterHandlerFunc(r, http.MethodGet, "/page", func(w http.ResponseWriter, req *http.Request) {
+			u, _ := auth.Principal(req)
+			w.Write([]byte(u.Name))
+		})
+		SetPatternLevelAuthRequirements(route, auth, AuthRequirements[*testUser]{Roles: []string{"member"}})
+
+		if got := serveAs(r, http.MethodGet, "/page", "").Code; got != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", got)
+		}
+		count.Store(0)
+		w := serveAs(r, http.MethodGet, "/page", "bob")
+		if w.Code != http.StatusOK || w.Body.String() != "bob" {
+			t.Errorf("expected 200 with bob, got %d %q", w.Code, w.Body.String())
+		}
+		if got := count.Load(); got != 1 {
+			t.Errorf("expected principal to be resolved once, got %d", got)
+		}
+	})
+
+	t.Run("ResolverSeesParamsAndContext", func(t *testing.T) {
+		type ctxKey struct{}
+		var count atomic.Int32
+		auth := NewAuth(AuthConfig[*testUser]{
+			ResolvePrincipal: func(r *http.Request) (*testUser, error) {
+				count.Add(1)
+				if GetTasksCtx(r) == nil {
+					return nil, errors.New("no TasksCtx")
+				}
+				org, _ := r.Context().Value(ctxKey{}).(string)
+				if org != GetParam(r, "org") {
+					return nil, nil
+				}
+				return &testUser{Name: org + "-admin"}, nil
+			},
+		})
+		r := NewRouter(&Options{InjectTasksCtx: true})
+		// Stands in for e.g. session middleware, which puts the session in
+		// the request context.
+		SetGlobalHTTPMiddleware(r, func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				ctx := context.WithValue(req.Context(), ctxKey{}, req.Header.Get("X-Org"))
+				next.ServeHTTP(w, req.WithContext(ctx))
+			})
+		})
+		RegisterHandlerFunc(r, http.MethodGet, "/orgs/:org", func(w http.ResponseWriter, req *http.Request) {
+			u, err := auth.Principal(req)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if u == nil {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			auth.Principal(req)
+			w.Write([]byte(u.Name))
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/orgs/acme", nil)
+		req.Header.Set("X-Org", "acme")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Body.String() != "acme-admin" {
+			t.Errorf("expected 200 with acme-admin, got %d %q", w.Code, w.Body.String())
+		}
+		if got := count.Load(); got != 1 {
+			t.Errorf("expected principal to be resolved once, got %d", got)
+		}
+	})
+
+	t.Run("AuthInfo", func(t *testing.T) {
+		var count atomic.Int32
+		auth := newTestAuth(&count)
+		r := NewRouter(nil)
+		ok := TaskHandlerFromFunc(func(rd *ReqData[None]) (None, error) { return None{}, nil })
+		route := RegisterTaskHandler(r, http.MethodPost, "/admin", ok)
+		if route.AuthInfo() != nil {
+			t.Error("expected no auth info before requirements are set")
+		}
+		SetPatternLevelAuthRequirements(route, auth, AuthRequirements[*testUser]{
+			Roles:  []string{"admin"},
+			Policy: func(*ReqData[None], *testUser) (bool, error) { return true, nil },
+		})
+		info := route.AuthInfo()
+		if info == nil || !info.RequiresLogin || len(info.Roles) != 1 || !info.HasPolicy {
+			t.Errorf("unexpected auth info: %+v", info)
+		}
+
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic when setting requirements twice")
+			}
+		}()
+		SetPatternLevelAuthRequirements(route, auth, AuthRequirements[*testUser]{})
+	})
+
+	t.Run("MissingGetRolesPanics", func(t *testing.T) {
+		auth := NewAuth(AuthConfig[*testUser]{
+			ResolvePrincipal: func(*http.Request) (*testUser, error) { return nil, nil },
+		})
+		route := RegisterHandlerFunc(NewRouter(nil), http.MethodGet, "/", func(http.ResponseWriter, *http.Request) {})
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic")
+			}
+		}()
+		SetPatternLevelAuthRequirements(route, auth, AuthRequirements[*testUser]{Roles: []string{"admin"}})
+	})
+}
@@ -5,7 +5,9 @@ import (
 	"path"
 	"reflect"
 	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/river-now/river/kit/colorlog"
 	"github.com/river-now/river/kit/contextutil"
@@ -75,6 +77,8 @@ type Router struct {
 	mountRoot          string
 	allRoutes          []AnyRoute
 	injectTasksCtx     bool
+	tasksCtxOpts       *tasks.TasksCtxOptions
+	requestObservers   []func(*RequestInfo)
 }
 
 func (rt *Router) AllRoutes() []AnyRoute {
@@ -130,6 +134,10 @@ type Options struct {
 	MarshalInput func(r *http.Request, inputPtr any) error
 	// If true, automatically injects a TasksCtx into the request context.
 	InjectTasksCtx bool
+	// Optional. Applied to every per-request TasksCtx created by this router.
+	// Use TasksCtxOptions.MaxConcurrency to cap how many tasks (middleware,
+	// nested loaders, etc.) may hit shared resources like a DB pool at once.
+	TasksCtxOptions *tasks.TasksCtxOptions
 }
 
 func NewRouter(opts *Options) *Router {
@@ -159,6 +167,7 @@ func NewRouter(opts *Options) *Router {
 		httpMws:            emptyHTTPMws,
 		taskMws:            emptyTaskMws,
 		injectTasksCtx:     opts.InjectTasksCtx,
+		tasksCtxOpts:       opts.TasksCtxOptions,
 	}
 }
 
@@ -223,6 +232,27 @@ func SetPatternLevelHTTPMiddleware[I any, O any](route *Route[I, O], httpMw HTTP
 	})
 }
 
+// RequestInfo describes a handled request to one of a router's routes.
+type RequestInfo struct {
+	Request *http.Request
+	Route   AnyRoute
+	Params  Params
+	// The parsed input, for task handlers. Nil for HTTP handlers, and when
+	// the input fails to parse.
+	Input    any
+	Status   int // Defaults to 200 if the handler never set one
+	Duration time.Duration
+}
+
+// SetGlobalRequestObserver registers a func to be called after every
+// request that matches one of router's routes, including requests rejected
+// by middleware. Observers run synchronously, in registration order, after
+// the response has been written (but before the request returns), so keep
+// them fast. Requests that don't match a route are not observed.
+func SetGlobalRequestObserver(router *Router, observe func(info *RequestInfo)) {
+	router.requestObservers = append(router.requestObservers, observe)
+}
+
 func SetGlobalNotFoundHTTPHandler(router *Router, httpHandler http.Handler) {
 	router.notFoundHandler = httpHandler
 }
@@ -239,11 +269,13 @@ type Route[I, O any] struct {
 	taskHandler     tasks.AnyTask
 	needsTasksCtx   bool
 	compiledHTTP    atomic.Value
+	authInfo        *RouteAuthInfo
 }
 
 type AnyRoute interface {
 	OriginalPattern() string
 	Method() string
+	AuthInfo() *RouteAuthInfo
 	genericsutil.AnyZeroHelper
 	getHandlerType() string
 	getHTTPHandler() http.Handler
@@ -261,6 +293,12 @@ func (route *Route[I, O]) Method() string {
 	return route.method
 }
 
+// Returns nil if the route has no auth requirements (see
+// SetPatternLevelAuthRequirements).
+func (route *Route[I, O]) AuthInfo() *RouteAuthInfo {
+	return route.authInfo
+}
+
 // TaskHandlers are used for JSON responses only, and they are intended to
 // be particularly convenient for sending JSON. If you need to send a different
 // content type, use a traditional http.Handler instead.
@@ -343,6 +381,23 @@ func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	if len(rt.requestObservers) == 0 {
+		rt.serveMatch(w, r, best, nil)
+		return
+	}
+	route := best.methodMatcher.routes[best.match.OriginalPattern()]
+	info := &RequestInfo{Request: r, Route: route, Params: best.match.Params, Status: http.StatusOK}
+	sw := response.NewHookWriter(w, func(status int) { info.Status = status })
+	start := time.Now()
+	rt.serveMatch(sw, r, best, info)
+	info.Duration = time.Since(start)
+	for _, observe := range rt.requestObservers {
+		observe(info)
+	}
+}
+
+// If info is non-nil, fills in its Request and Input as they become known.
+func (rt *Router) serveMatch(w http.ResponseWriter, r *http.Request, best *findBestOutput, info *RequestInfo) {
 	match := best.match
 	mm := best.methodMatcher
 	route := mm.routes[match.OriginalPattern()]
@@ -355,9 +410,12 @@ func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			rd := &rdTransport{
 				params:    match.Params,
 				splatVals: match.SplatValues,
-				req:       r,
 			}
 			r = requestStore.GetRequestWithContext(r, rd)
+			rd.req = r
+			if info != nil {
+				info.Request = r
+			}
 		}
 		handler := route.httpChain(rt, mm)
 		if best.headFellBackToGet {
@@ -368,17 +426,23 @@ func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// Slow path: create TasksCtx and full request data
-	tasksCtx := tasks.NewTasksCtx(r.Context())
+	tasksCtx := tasks.NewTasksCtxWithOptions(r.Context(), rt.tasksCtxOpts)
 	rd := &rdTransport{
 		params:        match.Params,
 		splatVals:     match.SplatValues,
 		tasksCtx:      tasksCtx,
-		req:           r,
 		responseProxy: response.NewProxy(),
 	}
 	r = requestStore.GetRequestWithContext(r, rd)
+	rd.req = r
+	if info != nil {
+		info.Request = r
+	}
 	reqGetter := mm.reqDataGetters[match.OriginalPattern()]
 	reqData, err := reqGetter.getReqData(r, tasksCtx, match)
+	if err == nil && info != nil && route.getHandlerType() == "task" {
+		info.Input = reqData.getInput()
+	}
 	if err != nil {
 		if validate.IsValidationError(err) {
 			muxLog.Error("Validation error", "error", err, "pattern", match.OriginalPattern())
@@ -411,8 +475,9 @@ type rdTransport struct {
 	params        Params
 	splatVals     []string
 	tasksCtx      *tasks.TasksCtx
-	req           *http.Request
+	req           *http.Request // Carries rdTransport in its context
 	responseProxy *response.Proxy
+	principals    sync.Map // *Auth[P] -> *principalResult[P]
 }
 
 func applyHTTPMiddlewareWithOptions(mwWithOpts httpMiddlewareWithOptions, handler http.Handler) http.Handler {
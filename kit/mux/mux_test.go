@@ -917,3 +917,61 @@ func TestTasksCtxIsAvailableInTaskMiddleware(t *testing.T) {
 		t.Errorf("Expected status 200, got %d", rec.Code)
 	}
 }
+
+func TestRequestObserver(t *testing.T) {
+	router := NewRouter(nil)
+	var infos []*RequestInfo
+	SetGlobalRequestObserver(router, func(info *RequestInfo) {
+		infos = append(infos, info)
+	})
+
+	RegisterTaskHandler(router, "GET", "/items/:id", TaskHandlerFromFunc(func(rd *ReqData[None]) (string, error) {
+		return rd.Params()["id"], nil
+	}))
+	RegisterHandlerFunc(router, "POST", "/items", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest("GET", "/items/7", nil),
+		httptest.NewRequest("POST", "/items", nil),
+		httptest.NewRequest("GET", "/nope", nil),
+	} {
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if len(infos) != 2 {
+		t.Fatalf("Expected 2 observed requests, got %d", len(infos))
+	}
+	if info := infos[0]; info.Route.OriginalPattern() != "/items/:id" || info.Params["id"] != "7" ||
+		info.Status != http.StatusOK || info.Input != (None{}) || GetParam(info.Request, "id") != "7" {
+		t.Errorf("Unexpected info for task handler: %+v", info)
+	}
+	if info := infos[1]; info.Route.Method() != "POST" || info.Status != http.StatusCreated || info.Input != nil {
+		t.Errorf("Unexpected info for HTTP handler: %+v", info)
+	}
+}
+
+func TestRequestObserverKeepsFlusher(t *testing.T) {
+	router := NewRouter(nil)
+	var status int
+	SetGlobalRequestObserver(router, func(info *RequestInfo) { status = info.Status })
+	RegisterHandlerFunc(router, "GET", "/stream", func(w http.ResponseWriter, r *http.Request) {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Error("Expected the observed writer to implement http.Flusher")
+			return
+		}
+		w.Write([]byte("chunk"))
+		f.Flush()
+		if _, ok := w.(http.Hijacker); !ok {
+			t.Error("Expected the observed writer to implement http.Hijacker")
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/stream", nil))
+	if !rec.Flushed || status != http.StatusOK {
+		t.Errorf("Expected a flushed 200, got flushed=%v status=%d", rec.Flushed, status)
+	}
+}
@@ -42,6 +42,7 @@ type NestedRouter struct {
 	routeIndexMap  atomic.Value // map[string]int
 	version        uint64       // Version counter for atomic updates
 	mu             sync.RWMutex
+	maxConcurrency int
 }
 
 func (nr *NestedRouter) AllRoutes() map[string]AnyNestedRoute {
@@ -73,6 +74,10 @@ type NestedOptions struct {
 	DynamicParamPrefixRune rune
 	SplatSegmentRune       rune
 	ExplicitIndexSegment   string
+	// Optional. Caps how many matched task handlers RunNestedTasks will run
+	// at once for a single request. Zero means no limit. This stacks with
+	// any limit set on the request's TasksCtx (see Options.TasksCtxOptions).
+	MaxConcurrency int
 }
 
 func NewNestedRouter(opts *NestedOptions) *NestedRouter {
@@ -84,8 +89,9 @@ func NewNestedRouter(opts *NestedOptions) *NestedRouter {
 	matcherOpts.SplatSegmentRune = opt.Resolve(opts, opts.SplatSegmentRune, '*')
 	matcherOpts.ExplicitIndexSegment = opt.Resolve(opts, opts.ExplicitIndexSegment, "")
 	nr := &NestedRouter{
-		matcher: matcher.New(matcherOpts),
-		routes:  make(map[string]AnyNestedRoute),
+		matcher:        matcher.New(matcherOpts),
+		routes:         make(map[string]AnyNestedRoute),
+		maxConcurrency: opts.MaxConcurrency,
 	}
 	// Initialize atomic values
 	nr.compiledRoutes.Store(make([]compiledRoute, 0))
@@ -296,7 +302,8 @@ func RunNestedTasks(
 
 	// Execute all tasks in parallel if we have any
 	if len(callables) > 0 {
-		if err := tasks.Go(tasksCtx, callables...); err != nil {
+		goOpts := &tasks.GoOptions{MaxConcurrency: nestedRouter.maxConcurrency}
+		if err := tasks.GoWithOptions(tasksCtx, goOpts, callables...); err != nil {
 			muxLog.Error("tasks.Go reported an error during nested task execution", "error", err)
 		}
 	}
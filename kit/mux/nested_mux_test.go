@@ -1,9 +1,13 @@
 package mux
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/river-now/river/kit/tasks"
 )
@@ -355,6 +359,73 @@ func TestRunNestedTasks(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("Max_Concurrency", func(t *testing.T) {
+		nr := NewNestedRouter(&NestedOptions{MaxConcurrency: 1})
+
+		var current, peak int32
+		newHandler := func(name string) *TaskHandler[None, string] {
+			return TaskHandlerFromFunc(func(rd *ReqData[None]) (string, error) {
+				n := atomic.AddInt32(&current, 1)
+				if n > atomic.LoadInt32(&peak) {
+					atomic.StoreInt32(&peak, n)
+				}
+				time.Sleep(10 * time.Millisecond)
+				atomic.AddInt32(&current, -1)
+				return name, nil
+			})
+		}
+
+		RegisterNestedTaskHandler(nr, "", newHandler("root"))
+		RegisterNestedTaskHandler(nr, "/users", newHandler("users"))
+		RegisterNestedTaskHandler(nr, "/users/:id", newHandler("user"))
+
+		req := createRequestWithTasksCtx(http.MethodGet, "/users/1")
+		results, _ := FindNestedMatchesAndRunTasks(nr, req)
+
+		if peak != 1 {
+			t.Errorf("Expected at most 1 concurrent task, got %d", peak)
+		}
+		for i, result := range results.Slice {
+			if !result.OK() || !result.RanTask() {
+				t.Errorf("Task %d should have run successfully", i)
+			}
+		}
+	})
+
+	t.Run("Max_Concurrency_Early_Error", func(t *testing.T) {
+		nr := NewNestedRouter(&NestedOptions{MaxConcurrency: 1})
+
+		var ran int32
+		RegisterNestedTaskHandler(nr, "", TaskHandlerFromFunc(func(rd *ReqData[None]) (string, error) {
+			return "", errors.New("root failed")
+		}))
+		for _, pattern := range []string{"/users", "/users/:id"} {
+			RegisterNestedTaskHandler(nr, pattern, TaskHandlerFromFunc(func(rd *ReqData[None]) (string, error) {
+				atomic.AddInt32(&ran, 1)
+				return pattern, nil
+			}))
+		}
+
+		req := createRequestWithTasksCtx(http.MethodGet, "/users/1")
+		results, _ := FindNestedMatchesAndRunTasks(nr, req)
+
+		if len(results.Slice) != 3 {
+			t.Fatalf("Expected 3 results, got %d", len(results.Slice))
+		}
+		if err := results.Slice[0].Err(); err == nil || err.Error() != "root failed" {
+			t.Errorf("Expected root's own error, got %v", err)
+		}
+		// Queued loaders never start, but must not look successful either
+		for _, result := range results.Slice[1:] {
+			if !errors.Is(result.Err(), context.Canceled) {
+				t.Errorf("Expected %s to fail with context.Canceled, got %v", result.Pattern(), result.Err())
+			}
+		}
+		if ran != 0 {
+			t.Errorf("Expected queued loaders not to run after an error, but %d ran", ran)
+		}
+	})
 }
 
 func TestNestedRouterWithExplicitIndex(t *testing.T) {
@@ -0,0 +1,59 @@
+package response
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// HookWriter wraps an http.ResponseWriter and calls a hook once, just before
+// the response headers are sent, with the final (non-informational) status.
+// Use it to set headers or record the status from middleware. It forwards
+// Flush and Hijack, and its Unwrap method lets http.ResponseController reach
+// the underlying writer for anything else.
+type HookWriter struct {
+	http.ResponseWriter
+	onWriteHeader func(status int)
+	wroteHeader   bool
+}
+
+func NewHookWriter(w http.ResponseWriter, onWriteHeader func(status int)) *HookWriter {
+	return &HookWriter{ResponseWriter: w, onWriteHeader: onWriteHeader}
+}
+
+// WroteHeader reports whether the hook has run.
+func (hw *HookWriter) WroteHeader() bool {
+	return hw.wroteHeader
+}
+
+func (hw *HookWriter) hook(status int) {
+	if !hw.wroteHeader {
+		hw.wroteHeader = true
+		hw.onWriteHeader(status)
+	}
+}
+
+func (hw *HookWriter) WriteHeader(status int) {
+	if status >= 200 { // Skip informational responses
+		hw.hook(status)
+	}
+	hw.ResponseWriter.WriteHeader(status)
+}
+
+func (hw *HookWriter) Write(b []byte) (int, error) {
+	hw.hook(http.StatusOK)
+	return hw.ResponseWriter.Write(b)
+}
+
+func (hw *HookWriter) Flush() {
+	hw.hook(http.StatusOK)
+	http.NewResponseController(hw.ResponseWriter).Flush()
+}
+
+func (hw *HookWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(hw.ResponseWriter).Hijack()
+}
+
+func (hw *HookWriter) Unwrap() http.ResponseWriter {
+	return hw.ResponseWriter
+}
@@ -0,0 +1,72 @@
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHookWriter(t *testing.T) {
+	t.Run("WriteHeader", func(t *testing.T) {
+		var calls []int
+		rr := httptest.NewRecorder()
+		hw := NewHookWriter(rr, func(status int) { calls = append(calls, status) })
+		hw.WriteHeader(http.StatusEarlyHints)
+		hw.WriteHeader(http.StatusNotFound)
+		hw.WriteHeader(http.StatusOK)
+		hw.Write([]byte("x"))
+		if len(calls) != 1 || calls[0] != http.StatusNotFound {
+			t.Errorf("hook calls = %v, want [404]", calls)
+		}
+		if !hw.WroteHeader() {
+			t.Error("WroteHeader() = false, want true")
+		}
+	})
+
+	t.Run("ImplicitOK", func(t *testing.T) {
+		var got int
+		hw := NewHookWriter(httptest.NewRecorder(), func(status int) { got = status })
+		if hw.WroteHeader() {
+			t.Error("WroteHeader() = true before any write")
+		}
+		hw.Write([]byte("x"))
+		if got != http.StatusOK {
+			t.Errorf("status = %d, want 200", got)
+		}
+	})
+
+	t.Run("HookRunsBeforeHeadersAreSent", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		hw := NewHookWriter(rr, func(int) { rr.Header().Set("X-Hooked", "1") })
+		hw.Write([]byte("x"))
+		if rr.Result().Header.Get("X-Hooked") != "1" {
+			t.Error("header set in hook was not sent")
+		}
+	})
+
+	t.Run("Flush", func(t *testing.T) {
+		var got int
+		rr := httptest.NewRecorder()
+		var w http.ResponseWriter = NewHookWriter(rr, func(status int) { got = status })
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("HookWriter does not implement http.Flusher")
+		}
+		f.Flush()
+		if !rr.Flushed || got != http.StatusOK {
+			t.Errorf("flushed = %v, status = %d", rr.Flushed, got)
+		}
+	})
+
+	t.Run("Hijack", func(t *testing.T) {
+		var w http.ResponseWriter = NewHookWriter(httptest.NewRecorder(), func(int) {})
+		h, ok := w.(http.Hijacker)
+		if !ok {
+			t.Fatal("HookWriter does not implement http.Hijacker")
+		}
+		// The recorder can't be hijacked, so the error is forwarded.
+		if _, _, err := h.Hijack(); err == nil {
+			t.Error("expected error hijacking a recorder")
+		}
+	})
+}
@@ -47,29 +47,50 @@ func Serialize(ks *keyset.Keyset, rv RawValue) (SecureBytes, error) {
 }
 
 func Parse[T any](ks *keyset.Keyset, sb SecureBytes) (T, error) {
+	out, _, err := parse[T](ks, sb)
+	return out, err
+}
+
+func parse[T any](ks *keyset.Keyset, sb SecureBytes) (T, int, error) {
 	var zeroT T
 	if len(sb) == 0 {
-		return zeroT, fmt.Errorf("invalid secure bytes: empty value")
+		return zeroT, -1, fmt.Errorf("invalid secure bytes: empty value")
 	}
 	if len(sb) > MaxSize {
-		return zeroT, fmt.Errorf("secure bytes too large (over 1MB)")
+		return zeroT, -1, fmt.Errorf("secure bytes too large (over 1MB)")
 	}
 	if err := ks.Validate(); err != nil {
-		return zeroT, fmt.Errorf("invalid keyset: %w", err)
+		return zeroT, -1, fmt.Errorf("invalid keyset: %w", err)
 	}
-	plaintext, err := keyset.Attempt(ks, func(k cryptoutil.Key32) ([]byte, error) {
+	plaintext, keyIndex, err := keyset.AttemptWithKeyIndex(ks, func(k cryptoutil.Key32) ([]byte, error) {
 		return cryptoutil.DecryptSymmetricXChaCha20Poly1305(sb, k)
 	})
 	if err != nil {
-		return zeroT, fmt.Errorf("error decrypting value: %w", err)
+		return zeroT, -1, fmt.Errorf("error decrypting value: %w", err)
 	}
 	version := plaintext[0]
 	if version != current_pkg_version {
-		return zeroT, fmt.Errorf("unsupported SecureBytes version %d", version)
+		return zeroT, -1, fmt.Errorf("unsupported SecureBytes version %d", version)
 	}
 	out, err := bytesutil.FromGob[T](plaintext[1:])
 	if err != nil {
-		return zeroT, fmt.Errorf("error decoding gob: %w", err)
+		return zeroT, -1, fmt.Errorf("error decoding gob: %w", err)
+	}
+	return out, keyIndex, nil
+}
+
+// ParseAndUpgrade is like Parse, but if sb was encrypted with a
+// non-primary key, it also returns sb re-encrypted with the primary
+// key (otherwise upgraded is nil). Store the upgraded value in place
+// of the old one so that older keys can eventually be dropped.
+func ParseAndUpgrade[T any](ks *keyset.Keyset, sb SecureBytes) (value T, upgraded SecureBytes, err error) {
+	value, keyIndex, err := parse[T](ks, sb)
+	if err != nil || keyIndex == 0 {
+		return value, nil, err
+	}
+	upgraded, err = Serialize(ks, value)
+	if err != nil {
+		return value, nil, fmt.Errorf("error re-encrypting value: %w", err)
 	}
-	return out, nil
+	return value, upgraded, nil
 }
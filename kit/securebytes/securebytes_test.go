@@ -228,6 +228,41 @@ func TestSecureBytes_KeyRotation(t *testing.T) {
 	}
 }
 
+func TestSecureBytes_ParseAndUpgrade(t *testing.T) {
+	oldKeys := mustKeys(t, 1)
+	newKeys := mustKeys(t, 1)
+	rotatedKeys, _ := keyset.FromUnwrapped(keyset.UnwrappedKeyset{newKeys.Unwrap()[0], oldKeys.Unwrap()[0]})
+
+	value := "sensitive data for upgrade"
+	sb, err := Serialize(oldKeys, value)
+	if err != nil {
+		t.Fatalf("Serialize with oldKey failed: %v", err)
+	}
+
+	got, upgraded, err := ParseAndUpgrade[string](rotatedKeys, sb)
+	if err != nil {
+		t.Fatalf("ParseAndUpgrade failed: %v", err)
+	}
+	if got != value || upgraded == nil {
+		t.Fatalf("expected value and upgraded ciphertext, got %q, %v", got, upgraded)
+	}
+
+	// The upgraded value decrypts with the new key alone
+	gotUpgraded, err := Parse[string](newKeys, upgraded)
+	if err != nil || gotUpgraded != value {
+		t.Fatalf("Parse of upgraded value with newKey failed: %q, %v", gotUpgraded, err)
+	}
+
+	_, again, err := ParseAndUpgrade[string](rotatedKeys, upgraded)
+	if err != nil || again != nil {
+		t.Fatalf("expected no upgrade for a value encrypted with the primary key, got %v, %v", again, err)
+	}
+
+	if _, _, err := ParseAndUpgrade[string](newKeys, sb); err == nil {
+		t.Fatal("expected error parsing with a keyset lacking the original key")
+	}
+}
+
 func TestSecureBytes_EmptyInput(t *testing.T) {
 	kcs := mustKeys(t, 1)
 
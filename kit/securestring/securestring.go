@@ -41,5 +41,27 @@ func Parse[T any](ks *keyset.Keyset, ss SecureString) (T, error) {
 	return securebytes.Parse[T](ks, securebytes.SecureBytes(ciphertext))
 }
 
+// ParseAndUpgrade is like Parse, but if ss was encrypted with a
+// non-primary key, it also returns ss re-encrypted with the primary
+// key (otherwise upgraded is empty). Store the upgraded value in place
+// of the old one so that older keys can eventually be dropped.
+func ParseAndUpgrade[T any](ks *keyset.Keyset, ss SecureString) (value T, upgraded SecureString, err error) {
+	if len(ss) == 0 {
+		return value, "", fmt.Errorf("invalid secure string: empty value")
+	}
+	if len(ss) > MaxBase64Size {
+		return value, "", fmt.Errorf("secure string too large (over 1.33MB)")
+	}
+	ciphertext, err := bytesutil.FromBase64(string(ss))
+	if err != nil {
+		return value, "", fmt.Errorf("error decoding base64: %w", err)
+	}
+	value, upgradedBytes, err := securebytes.ParseAndUpgrade[T](ks, securebytes.SecureBytes(ciphertext))
+	if err != nil || upgradedBytes == nil {
+		return value, "", err
+	}
+	return value, SecureString(bytesutil.ToBase64(upgradedBytes)), nil
+}
+
 // Deprecated: Use only if you need to support legacy encrypted values.
 const LegacyHKDFInfoStr = "river_kit_securestring_v1_encryption_key"
@@ -216,6 +216,41 @@ func TestSecureString_KeyRotation(t *testing.T) {
 	}
 }
 
+func TestSecureString_ParseAndUpgrade(t *testing.T) {
+	oldKeys := mustKeys(t, 1)
+	newKeys := mustKeys(t, 1)
+	rotatedKeys, _ := keyset.FromUnwrapped(keyset.UnwrappedKeyset{newKeys.Unwrap()[0], oldKeys.Unwrap()[0]})
+
+	value := "sensitive data for upgrade"
+	ss, err := Serialize(oldKeys, value)
+	if err != nil {
+		t.Fatalf("Serialize with oldKey failed: %v", err)
+	}
+
+	got, upgraded, err := ParseAndUpgrade[string](rotatedKeys, ss)
+	if err != nil {
+		t.Fatalf("ParseAndUpgrade failed: %v", err)
+	}
+	if got != value || upgraded == "" {
+		t.Fatalf("expected value and upgraded ciphertext, got %q, %v", got, upgraded)
+	}
+
+	// The upgraded value decrypts with the new key alone
+	gotUpgraded, err := Parse[string](newKeys, upgraded)
+	if err != nil || gotUpgraded != value {
+		t.Fatalf("Parse of upgraded value with newKey failed: %q, %v", gotUpgraded, err)
+	}
+
+	_, again, err := ParseAndUpgrade[string](rotatedKeys, upgraded)
+	if err != nil || again != "" {
+		t.Fatalf("expected no upgrade for a value encrypted with the primary key, got %v, %v", again, err)
+	}
+
+	if _, _, err := ParseAndUpgrade[string](newKeys, ss); err == nil {
+		t.Fatal("expected error parsing with a keyset lacking the original key")
+	}
+}
+
 func TestSecureString_EmptyInput(t *testing.T) {
 	kcs := mustKeys(t, 1)
 
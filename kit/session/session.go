@@ -0,0 +1,465 @@
+// Package session provides HTTP sessions with idle and absolute expiry, ID
+// rotation, and revocation. Sessions are either stored entirely in an
+// encrypted cookie (the default) or server-side behind a Store (in which case
+// the cookie holds only an encrypted session ID).
+//
+// Sessions bind to CSRF tokens via Manager.GetSessionID, which plugs straight
+// into csrf.ProtectorConfig.GetSessionID. As with any session scheme used
+// alongside the csrf package, you must cycle the CSRF token whenever a session
+// is created, rotated, or destroyed (e.g., on login and logout).
+package session
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/river-now/river/kit/bytesutil"
+	"github.com/river-now/river/kit/colorlog"
+	"github.com/river-now/river/kit/contextutil"
+	"github.com/river-now/river/kit/cookies"
+	"github.com/river-now/river/kit/cryptoutil"
+	"github.com/river-now/river/kit/response"
+)
+
+const idSize = 32 // Size, in bytes, of random session IDs.
+
+var (
+	// Returned when a request has no session, or its session is expired,
+	// revoked, or otherwise invalid.
+	ErrNoSession = errors.New("session: no valid session")
+	// Returned by Manager.Revoke and Manager.RevokeSubject for cookie-stored
+	// sessions, which can't be revoked server-side (see Config.IsRevoked).
+	ErrRevocationUnsupported = errors.New("session: revocation requires a Store")
+	// Wrapped by the error returned alongside a still-valid session when
+	// persisting its LastSeenAt update fails.
+	ErrTouchFailed = errors.New("session: failed to touch session")
+)
+
+var sessionLog = colorlog.New("session")
+
+type Session[T any] struct {
+	ID string
+	// Optional. Typically a user ID. Used to revoke all of a subject's
+	// sessions at once (e.g., on password change).
+	Subject    string
+	Data       T
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+}
+
+type Config struct {
+	// REQUIRED: A configured cookie manager.
+	CookieManager *cookies.Manager
+	// Optional. If nil, sessions are stored in an encrypted cookie.
+	Store Store
+	// Do not prefix the name with "__Host-". Prefixing is handled internally.
+	// Defaults to "session".
+	CookieName string
+	// A session expires if it goes unused for this long. Defaults to 24 hours.
+	IdleTimeout time.Duration
+	// A session expires this long after it was created, regardless of use.
+	// Defaults to 7 days.
+	AbsoluteTimeout time.Duration
+	// The minimum time between LastSeenAt updates (each of which means a
+	// store write and/or a new cookie). Defaults to 1 minute.
+	TouchInterval time.Duration
+	// Optional. Called for every otherwise valid session. Return true to
+	// reject the session. This is the only way to revoke cookie-stored
+	// sessions short of rotating your keyset (e.g., by comparing CreatedAt
+	// against a per-subject "sessions valid after" timestamp).
+	IsRevoked func(ctx context.Context, subject string, createdAt time.Time) bool
+	// Optional. Called by Middleware for errors other than ErrNoSession (e.g.,
+	// the store is unreachable, or a touch couldn't be saved), which would
+	// otherwise be indistinguishable from a logged-out user. Defaults to
+	// logging the error.
+	OnError func(r *http.Request, err error)
+}
+
+type Manager[T any] struct {
+	cfg          Config
+	idCookie     *cookies.SecureCookie[string]
+	stateCookie  *cookies.SecureCookie[cookieState[T]]
+	contextStore *contextutil.Store[*Session[T]]
+	now          func() time.Time
+}
+
+// Everything for a cookie-stored session. Fields must be exported for gob.
+type cookieState[T any] struct {
+	ID         string
+	Subject    string
+	Data       T
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+}
+
+// Panics if you fail to provide a CookieManager or provide negative timeouts.
+func NewManager[T any](cfg Config) *Manager[T] {
+	if cfg.CookieManager == nil {
+		panic("session: CookieManager is required")
+	}
+	if cfg.IdleTimeout < 0 || cfg.AbsoluteTimeout < 0 || cfg.TouchInterval < 0 {
+		panic("session: timeouts must be positive")
+	}
+	if cfg.CookieName == "" {
+		cfg.CookieName = "session"
+	}
+	if cfg.IdleTimeout == 0 {
+		cfg.IdleTimeout = 24 * time.Hour
+	}
+	if cfg.AbsoluteTimeout == 0 {
+		cfg.AbsoluteTimeout = 7 * 24 * time.Hour
+	}
+	if cfg.TouchInterval == 0 {
+		cfg.TouchInterval = time.Minute
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = func(r *http.Request, err error) {
+			sessionLog.Error("Failed to load session", "error", err, "method", r.Method, "path", r.URL.Path)
+		}
+	}
+	cookieCfg := cookies.SecureCookieConfig{
+		Manager:  cfg.CookieManager,
+		Name:     cfg.CookieName,
+		TTL:      cfg.AbsoluteTimeout,
+		SameSite: cookies.SameSiteLaxMode,
+		HttpOnly: cookies.HttpOnlyTrue,
+	}
+	return &Manager[T]{
+		cfg:          cfg,
+		idCookie:     cookies.NewSecureCookie[string](cookieCfg),
+		stateCookie:  cookies.NewSecureCookie[cookieState[T]](cookieCfg),
+		contextStore: contextutil.NewStore[*Session[T]]("session"),
+		now:          time.Now,
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// MIDDLEWARE
+/////////////////////////////////////////////////////////////////////
+
+// Loads the request's session (if any) into the request context, touching it
+// as needed to keep it from going idle. Downstream, use FromContext to get
+// it. Requests without a valid session pass through with no session. Other
+// errors are passed to Config.OnError; if only the touch failed, the session
+// is still loaded.
+func (m *Manager[T]) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sess, err := m.GetWithWriter(w, r)
+		if err != nil && !errors.Is(err, ErrNoSession) {
+			m.cfg.OnError(r, err)
+		}
+		if sess != nil {
+			r = m.contextStore.GetRequestWithContext(r, sess)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Returns the session loaded by Middleware, or nil if there is none.
+func (m *Manager[T]) FromContext(ctx context.Context) *Session[T] {
+	return m.contextStore.GetValueFromContext(ctx)
+}
+
+// Returns the current session ID, or an empty string if there is no valid
+// session. Suitable for csrf.ProtectorConfig.GetSessionID. Uses the session
+// loaded by Middleware if there is one, so put Middleware first.
+func (m *Manager[T]) GetSessionID(r *http.Request) string {
+	if sess := m.FromContext(r.Context()); sess != nil {
+		return sess.ID
+	}
+	sess, _, err := m.load(r)
+	if err != nil {
+		return ""
+	}
+	return sess.ID
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// GET
+/////////////////////////////////////////////////////////////////////
+
+// Returns the request's session, or ErrNoSession. If the session is due for
+// a touch (see Config.TouchInterval), its LastSeenAt is updated and a fresh
+// cookie is set. If only the touch fails, the (still valid) session is
+// returned along with an error wrapping ErrTouchFailed.
+func (m *Manager[T]) GetWithProxy(rp *response.Proxy, r *http.Request) (*Session[T], error) {
+	sess, needsTouch, err := m.load(r)
+	if err != nil {
+		return nil, err
+	}
+	if needsTouch {
+		lastSeenAt := sess.LastSeenAt
+		sess.LastSeenAt = m.now()
+		if err := m.persist(rp, r.Context(), sess); err != nil {
+			sess.LastSeenAt = lastSeenAt
+			return sess, fmt.Errorf("%w: %w", ErrTouchFailed, err)
+		}
+	}
+	return sess, nil
+}
+
+// Returns the request's session, or ErrNoSession. If the session is due for
+// a touch (see Config.TouchInterval), its LastSeenAt is updated and a fresh
+// cookie is set. If only the touch fails, the (still valid) session is
+// returned along with an error wrapping ErrTouchFailed.
+func (m *Manager[T]) GetWithWriter(w http.ResponseWriter, r *http.Request) (*Session[T], error) {
+	rp := response.NewProxy()
+	sess, err := m.GetWithProxy(rp, r)
+	rp.ApplyToResponseWriter(w, r)
+	return sess, err
+}
+
+func (m *Manager[T]) load(r *http.Request) (sess *Session[T], needsTouch bool, err error) {
+	if m.cfg.Store == nil {
+		state, err := m.stateCookie.Get(r)
+		if err != nil {
+			return nil, false, ErrNoSession
+		}
+		sess = &Session[T]{
+			ID:         state.ID,
+			Subject:    state.Subject,
+			Data:       state.Data,
+			CreatedAt:  state.CreatedAt,
+			LastSeenAt: state.LastSeenAt,
+		}
+	} else {
+		id, err := m.idCookie.Get(r)
+		if err != nil {
+			return nil, false, ErrNoSession
+		}
+		rec, err := m.cfg.Store.Get(r.Context(), storeKey(id))
+		if err != nil {
+			return nil, false, fmt.Errorf("session: failed to get session from store: %w", err)
+		}
+		if rec == nil {
+			return nil, false, ErrNoSession
+		}
+		data, err := bytesutil.FromGob[T](rec.Data)
+		if err != nil {
+			return nil, false, fmt.Errorf("session: failed to decode session data: %w", err)
+		}
+		sess = &Session[T]{
+			ID:         id,
+			Subject:    rec.Subject,
+			Data:       data,
+			CreatedAt:  rec.CreatedAt,
+			LastSeenAt: rec.LastSeenAt,
+		}
+	}
+
+	now := m.now()
+	if !now.Before(m.expiresAt(sess)) {
+		return nil, false, ErrNoSession
+	}
+	if m.cfg.IsRevoked != nil && m.cfg.IsRevoked(r.Context(), sess.Subject, sess.CreatedAt) {
+		return nil, false, ErrNoSession
+	}
+	return sess, now.Sub(sess.LastSeenAt) >= m.cfg.TouchInterval, nil
+}
+
+func (m *Manager[T]) expiresAt(sess *Session[T]) time.Time {
+	idle := sess.LastSeenAt.Add(m.cfg.IdleTimeout)
+	absolute := sess.CreatedAt.Add(m.cfg.AbsoluteTimeout)
+	if idle.Before(absolute) {
+		return idle
+	}
+	return absolute
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// CREATE, SAVE, ROTATE, DESTROY
+/////////////////////////////////////////////////////////////////////
+
+// Starts a new session (e.g., on login), replacing the request's current
+// session, if any. Remember to cycle your CSRF token.
+func (m *Manager[T]) CreateWithProxy(rp *response.Proxy, r *http.Request, subject string, data T) (*Session[T], error) {
+	if err := m.deleteCurrent(r); err != nil {
+		return nil, err
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	now := m.now()
+	sess := &Session[T]{ID: id, Subject: subject, Data: data, CreatedAt: now, LastSeenAt: now}
+	if err := m.persist(rp, r.Context(), sess); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+// Starts a new session (e.g., on login), replacing the request's current
+// session, if any. Remember to cycle your CSRF token.
+func (m *Manager[T]) CreateWithWriter(w http.ResponseWriter, r *http.Request, subject string, data T) (*Session[T], error) {
+	rp := response.NewProxy()
+	sess, err := m.CreateWithProxy(rp, r, subject, data)
+	rp.ApplyToResponseWriter(w, r)
+	return sess, err
+}
+
+// Persists changes to sess.Data (and sess.Subject). For privilege changes,
+// use Rotate instead.
+func (m *Manager[T]) SaveWithProxy(rp *response.Proxy, r *http.Request, sess *Session[T]) error {
+	sess.LastSeenAt = m.now()
+	return m.persist(rp, r.Context(), sess)
+}
+
+// Persists changes to sess.Data (and sess.Subject). For privilege changes,
+// use Rotate instead.
+func (m *Manager[T]) SaveWithWriter(w http.ResponseWriter, r *http.Request, sess *Session[T]) error {
+	rp := response.NewProxy()
+	err := m.SaveWithProxy(rp, r, sess)
+	rp.ApplyToResponseWriter(w, r)
+	return err
+}
+
+// Gives sess a new ID and persists it, along with any changes to sess.Data
+// and sess.Subject. Call this whenever the session's privilege level changes
+// (e.g., on step-up auth or role change) to prevent session fixation.
+// CreatedAt is kept, so the absolute timeout still counts from the original
+// login. Remember to cycle your CSRF token.
+//
+// Only a server-side Store can invalidate the old ID. With cookie sessions
+// (no Store), a copy of the old cookie stays valid until it expires.
+func (m *Manager[T]) RotateWithProxy(rp *response.Proxy, r *http.Request, sess *Session[T]) error {
+	if m.cfg.Store != nil {
+		if err := m.cfg.Store.Delete(r.Context(), storeKey(sess.ID)); err != nil {
+			return fmt.Errorf("session: failed to delete old session: %w", err)
+		}
+	}
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	sess.ID = id
+	sess.LastSeenAt = m.now()
+	return m.persist(rp, r.Context(), sess)
+}
+
+// Gives sess a new ID and persists it, along with any changes to sess.Data
+// and sess.Subject. Call this whenever the session's privilege level changes
+// (e.g., on step-up auth or role change) to prevent session fixation.
+// CreatedAt is kept, so the absolute timeout still counts from the original
+// login. Remember to cycle your CSRF token.
+//
+// Only a server-side Store can invalidate the old ID. With cookie sessions
+// (no Store), a copy of the old cookie stays valid until it expires.
+func (m *Manager[T]) RotateWithWriter(w http.ResponseWriter, r *http.Request, sess *Session[T]) error {
+	rp := response.NewProxy()
+	err := m.RotateWithProxy(rp, r, sess)
+	rp.ApplyToResponseWriter(w, r)
+	return err
+}
+
+// Ends the request's session, if any (e.g., on logout), and deletes the
+// session cookie. Remember to cycle your CSRF token.
+func (m *Manager[T]) DestroyWithProxy(rp *response.Proxy, r *http.Request) error {
+	err := m.deleteCurrent(r)
+	rp.SetCookie(m.idCookie.NewDeletion())
+	return err
+}
+
+// Ends the request's session, if any (e.g., on logout), and deletes the
+// session cookie. Remember to cycle your CSRF token.
+func (m *Manager[T]) DestroyWithWriter(w http.ResponseWriter, r *http.Request) error {
+	rp := response.NewProxy()
+	err := m.DestroyWithProxy(rp, r)
+	rp.ApplyToResponseWriter(w, r)
+	return err
+}
+
+func (m *Manager[T]) deleteCurrent(r *http.Request) error {
+	if m.cfg.Store == nil {
+		return nil
+	}
+	id, err := m.idCookie.Get(r)
+	if err != nil {
+		return nil
+	}
+	if err := m.cfg.Store.Delete(r.Context(), storeKey(id)); err != nil {
+		return fmt.Errorf("session: failed to delete session: %w", err)
+	}
+	return nil
+}
+
+func (m *Manager[T]) persist(rp *response.Proxy, ctx context.Context, sess *Session[T]) error {
+	if m.cfg.Store == nil {
+		cookie, err := m.stateCookie.New(cookieState[T]{
+			ID:         sess.ID,
+			Subject:    sess.Subject,
+			Data:       sess.Data,
+			CreatedAt:  sess.CreatedAt,
+			LastSeenAt: sess.LastSeenAt,
+		})
+		if err != nil {
+			return fmt.Errorf("session: failed to create session cookie: %w", err)
+		}
+		rp.SetCookie(cookie)
+		return nil
+	}
+
+	data, err := bytesutil.ToGob(sess.Data)
+	if err != nil {
+		return fmt.Errorf("session: failed to encode session data: %w", err)
+	}
+	err = m.cfg.Store.Set(ctx, &Record{
+		Key:        storeKey(sess.ID),
+		Subject:    sess.Subject,
+		Data:       data,
+		CreatedAt:  sess.CreatedAt,
+		LastSeenAt: sess.LastSeenAt,
+		ExpiresAt:  m.expiresAt(sess),
+	})
+	if err != nil {
+		return fmt.Errorf("session: failed to save session: %w", err)
+	}
+	cookie, err := m.idCookie.New(sess.ID)
+	if err != nil {
+		return fmt.Errorf("session: failed to create session cookie: %w", err)
+	}
+	rp.SetCookie(cookie)
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// REVOCATION
+/////////////////////////////////////////////////////////////////////
+
+// Revokes a single session by ID (e.g., from an "active sessions" screen).
+func (m *Manager[T]) Revoke(ctx context.Context, sessionID string) error {
+	if m.cfg.Store == nil {
+		return ErrRevocationUnsupported
+	}
+	return m.cfg.Store.Delete(ctx, storeKey(sessionID))
+}
+
+// Revokes every session belonging to subject (e.g., on password change).
+func (m *Manager[T]) RevokeSubject(ctx context.Context, subject string) error {
+	if m.cfg.Store == nil {
+		return ErrRevocationUnsupported
+	}
+	if subject == "" {
+		return errors.New("session: subject is required")
+	}
+	return m.cfg.Store.DeleteBySubject(ctx, subject)
+}
+
+func newID() (string, error) {
+	b, err := cryptoutil.RandomBytes(idSize)
+	if err != nil {
+		return "", fmt.Errorf("session: failed to generate session ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Stores never see raw session IDs, so a leaked store can't be used to
+// hijack sessions.
+func storeKey(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:])
+}
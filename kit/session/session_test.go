@@ -0,0 +1,451 @@
+package session
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/river-now/river/kit/cookies"
+	"github.com/river-now/river/kit/csrf"
+	"github.com/river-now/river/kit/keyset"
+	"github.com/river-now/river/kit/response"
+)
+
+type testData struct {
+	Role string
+}
+
+func createTestKeyset(t *testing.T) *keyset.Keyset {
+	t.Helper()
+	secret := base64.StdEncoding.EncodeToString([]byte("12345678901234567890123456789012"))
+	ks, err := keyset.RootSecretsToRootKeyset(keyset.RootSecrets{secret})
+	if err != nil {
+		t.Fatalf("failed to create keyset: %v", err)
+	}
+	return ks
+}
+
+type testClock struct{ t time.Time }
+
+func (c *testClock) now() time.Time          { return c.t }
+func (c *testClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestManager(t *testing.T, store Store) (*Manager[testData], *cookies.Manager, *testClock) {
+	t.Helper()
+	ks := createTestKeyset(t)
+	cookieMgr := cookies.NewManager(cookies.ManagerConfig{
+		GetKeyset: func() *keyset.Keyset { return ks },
+	})
+	mgr := NewManager[testData](Config{
+		CookieManager:   cookieMgr,
+		Store:           store,
+		IdleTimeout:     time.Hour,
+		AbsoluteTimeout: 4 * time.Hour,
+		TouchInterval:   time.Minute,
+	})
+	clock := &testClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	mgr.now = clock.now
+	switch s := store.(type) {
+	case *MemoryStore:
+		s.now = clock.now
+	case *SQLStore:
+		s.now = clock.now
+	}
+	return mgr, cookieMgr, clock
+}
+
+// Returns a request carrying the latest non-deleted cookies from rp.
+func requestWithCookies(rp *response.Proxy) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	latest := map[string]*http.Cookie{}
+	for _, c := range rp.GetCookies() {
+		latest[c.Name] = c
+	}
+	for _, c := range latest {
+		if c.MaxAge >= 0 {
+			r.AddCookie(&http.Cookie{Name: c.Name, Value: c.Value})
+		}
+	}
+	return r
+}
+
+func forEachMode(t *testing.T, f func(t *testing.T, newStore func() Store)) {
+	t.Run("CookieStored", func(t *testing.T) {
+		f(t, func() Store { return nil })
+	})
+	t.Run("MemoryStore", func(t *testing.T) {
+		f(t, func() Store { return NewMemoryStore() })
+	})
+	t.Run("SQLStore", func(t *testing.T) {
+		f(t, func() Store { return newTestSQLStore(t) })
+	})
+}
+
+// Wraps a MemoryStore, failing reads or writes on demand.
+type flakyStore struct {
+	*MemoryStore
+	failGet, failSet bool
+}
+
+var errStoreDown = errors.New("store down")
+
+func (s *flakyStore) Get(ctx context.Context, key string) (*Record, error) {
+	if s.failGet {
+		return nil, errStoreDown
+	}
+	return s.MemoryStore.Get(ctx, key)
+}
+
+func (s *flakyStore) Set(ctx context.Context, rec *Record) error {
+	if s.failSet {
+		return errStoreDown
+	}
+	return s.MemoryStore.Set(ctx, rec)
+}
+
+func TestNewManager(t *testing.T) {
+	t.Run("RequiresCookieManager", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic")
+			}
+		}()
+		NewManager[testData](Config{})
+	})
+
+	t.Run("Defaults", func(t *testing.T) {
+		ks := createTestKeyset(t)
+		mgr := NewManager[testData](Config{
+			CookieManager: cookies.NewManager(cookies.ManagerConfig{GetKeyset: func() *keyset.Keyset { return ks }}),
+		})
+		if mgr.cfg.CookieName != "session" || mgr.cfg.IdleTimeout != 24*time.Hour ||
+			mgr.cfg.AbsoluteTimeout != 7*24*time.Hour || mgr.cfg.TouchInterval != time.Minute {
+			t.Errorf("unexpected defaults: %+v", mgr.cfg)
+		}
+	})
+}
+
+func TestLifecycle(t *testing.T) {
+	forEachMode(t, func(t *testing.T, newStore func() Store) {
+		mgr, _, clock := newTestManager(t, newStore())
+
+		rp := response.NewProxy()
+		created, err := mgr.CreateWithProxy(rp, httptest.NewRequest(http.MethodPost, "/login", nil), "user-1", testData{Role: "member"})
+		if err != nil {
+			t.Fatalf("CreateWithProxy() error = %v", err)
+		}
+		r := requestWithCookies(rp)
+
+		t.Run("Get", func(t *testing.T) {
+			rp := response.NewProxy()
+			sess, err := mgr.GetWithProxy(rp, r)
+			if err != nil {
+				t.Fatalf("GetWithProxy() error = %v", err)
+			}
+			if sess.ID != created.ID || sess.Subject != "user-1" || sess.Data.Role != "member" {
+				t.Errorf("session = %+v", sess)
+			}
+			if len(rp.GetCookies()) != 0 {
+				t.Error("should not touch a session within TouchInterval")
+			}
+		})
+
+		t.Run("NoSession", func(t *testing.T) {
+			_, err := mgr.GetWithProxy(response.NewProxy(), httptest.NewRequest(http.MethodGet, "/", nil))
+			if !errors.Is(err, ErrNoSession) {
+				t.Errorf("expected ErrNoSession, got %v", err)
+			}
+		})
+
+		t.Run("IdleExpiry", func(t *testing.T) {
+			clock.advance(50 * time.Minute)
+			touchRP := response.NewProxy()
+			if _, err := mgr.GetWithProxy(touchRP, r); err != nil {
+				t.Fatalf("GetWithProxy() error = %v", err)
+			}
+			if len(touchRP.GetCookies()) != 1 {
+				t.Fatal("expected the session to be touched")
+			}
+			r = requestWithCookies(touchRP)
+
+			// Within the idle timeout of the touch, but not of creation
+			clock.advance(50 * time.Minute)
+			if _, err := mgr.GetWithProxy(response.NewProxy(), r); err != nil {
+				t.Fatalf("touched session should still be valid: %v", err)
+			}
+
+			clock.advance(61 * time.Minute)
+			if _, err := mgr.GetWithProxy(response.NewProxy(), r); !errors.Is(err, ErrNoSession) {
+				t.Errorf("expected ErrNoSession after idle timeout, got %v", err)
+			}
+		})
+
+		t.Run("AbsoluteExpiry", func(t *testing.T) {
+			rp := response.NewProxy()
+			if _, err := mgr.CreateWithProxy(rp, httptest.NewRequest(http.MethodPost, "/", nil), "user-1", testData{}); err != nil {
+				t.Fatal(err)
+			}
+			r := requestWithCookies(rp)
+
+			// Stay active well within the idle timeout, up to the absolute timeout
+			for range 4 {
+				clock.advance(59 * time.Minute)
+				rp := response.NewProxy()
+				if _, err := mgr.GetWithProxy(rp, r); err != nil {
+					t.Fatalf("active session should be valid: %v", err)
+				}
+				r = requestWithCookies(rp)
+			}
+
+			clock.advance(5 * time.Minute)
+			if _, err := mgr.GetWithProxy(response.NewProxy(), r); !errors.Is(err, ErrNoSession) {
+				t.Errorf("expected ErrNoSession after absolute timeout, got %v", err)
+			}
+		})
+	})
+}
+
+func TestRotate(t *testing.T) {
+	forEachMode(t, func(t *testing.T, newStore func() Store) {
+		mgr, _, _ := newTestManager(t, newStore())
+
+		rp := response.NewProxy()
+		sess, err := mgr.CreateWithProxy(rp, httptest.NewRequest(http.MethodPost, "/", nil), "user-1", testData{Role: "member"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		oldReq := requestWithCookies(rp)
+		oldID, createdAt := sess.ID, sess.CreatedAt
+
+		sess.Data.Role = "admin"
+		rotateRP := response.NewProxy()
+		if err := mgr.RotateWithProxy(rotateRP, oldReq, sess); err != nil {
+			t.Fatalf("RotateWithProxy() error = %v", err)
+		}
+		if sess.ID == oldID || !sess.CreatedAt.Equal(createdAt) {
+			t.Errorf("expected a new ID and the original CreatedAt, got %+v", sess)
+		}
+
+		got, err := mgr.GetWithProxy(response.NewProxy(), requestWithCookies(rotateRP))
+		if err != nil || got.ID != sess.ID || got.Data.Role != "admin" {
+			t.Fatalf("rotated session = %+v, %v", got, err)
+		}
+
+		if mgr.cfg.Store != nil {
+			if _, err := mgr.GetWithProxy(response.NewProxy(), oldReq); !errors.Is(err, ErrNoSession) {
+				t.Errorf("old session ID should be invalid after rotation, got %v", err)
+			}
+		}
+	})
+}
+
+func TestDestroyAndRevoke(t *testing.T) {
+	t.Run("Destroy", func(t *testing.T) {
+		forEachMode(t, func(t *testing.T, newStore func() Store) {
+			mgr, _, _ := newTestManager(t, newStore())
+			rp := response.NewProxy()
+			if _, err := mgr.CreateWithProxy(rp, httptest.NewRequest(http.MethodPost, "/", nil), "user-1", testData{}); err != nil {
+				t.Fatal(err)
+			}
+			r := requestWithCookies(rp)
+
+			w := httptest.NewRecorder()
+			if err := mgr.DestroyWithWriter(w, r); err != nil {
+				t.Fatalf("DestroyWithWriter() error = %v", err)
+			}
+			cookies := w.Result().Cookies()
+			if len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+				t.Errorf("expected a deletion cookie, got %v", cookies)
+			}
+			if mgr.cfg.Store != nil {
+				if _, err := mgr.GetWithProxy(response.NewProxy(), r); !errors.Is(err, ErrNoSession) {
+					t.Errorf("destroyed session should be gone from the store, got %v", err)
+				}
+			}
+		})
+	})
+
+	t.Run("RevokeSubject", func(t *testing.T) {
+		mgr, _, _ := newTestManager(t, NewMemoryStore())
+		var reqs []*http.Request
+		for _, subject := range []string{"user-1", "user-1", "user-2"} {
+			rp := response.NewProxy()
+			if _, err := mgr.CreateWithProxy(rp, httptest.NewRequest(http.MethodPost, "/", nil), subject, testData{}); err != nil {
+				t.Fatal(err)
+			}
+			reqs = append(reqs, requestWithCookies(rp))
+		}
+		if err := mgr.RevokeSubject(context.Background(), "user-1"); err != nil {
+			t.Fatalf("RevokeSubject() error = %v", err)
+		}
+		for i, r := range reqs {
+			_, err := mgr.GetWithProxy(response.NewProxy(), r)
+			if wantValid := i == 2; (err == nil) != wantValid {
+				t.Errorf("session %d: err = %v, want valid = %v", i, err, wantValid)
+			}
+		}
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		mgr, _, _ := newTestManager(t, NewMemoryStore())
+		rp := response.NewProxy()
+		sess, err := mgr.CreateWithProxy(rp, httptest.NewRequest(http.MethodPost, "/", nil), "user-1", testData{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := mgr.Revoke(context.Background(), sess.ID); err != nil {
+			t.Fatalf("Revoke() error = %v", err)
+		}
+		if _, err := mgr.GetWithProxy(response.NewProxy(), requestWithCookies(rp)); !errors.Is(err, ErrNoSession) {
+			t.Errorf("expected ErrNoSession, got %v", err)
+		}
+	})
+
+	t.Run("CookieStoredRevocation", func(t *testing.T) {
+		mgr, _, _ := newTestManager(t, nil)
+		if err := mgr.Revoke(context.Background(), "x"); !errors.Is(err, ErrRevocationUnsupported) {
+			t.Errorf("expected ErrRevocationUnsupported, got %v", err)
+		}
+
+		var revokedBefore time.Time
+		mgr.cfg.IsRevoked = func(_ context.Context, subject string, createdAt time.Time) bool {
+			return subject == "user-1" && !createdAt.After(revokedBefore)
+		}
+		rp := response.NewProxy()
+		sess, err := mgr.CreateWithProxy(rp, httptest.NewRequest(http.MethodPost, "/", nil), "user-1", testData{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := requestWithCookies(rp)
+		if _, err := mgr.GetWithProxy(response.NewProxy(), r); err != nil {
+			t.Fatalf("GetWithProxy() error = %v", err)
+		}
+		revokedBefore = sess.CreatedAt
+		if _, err := mgr.GetWithProxy(response.NewProxy(), r); !errors.Is(err, ErrNoSession) {
+			t.Errorf("expected IsRevoked to reject the session, got %v", err)
+		}
+	})
+}
+
+func TestMiddlewareAndCSRF(t *testing.T) {
+	mgr, cookieMgr, _ := newTestManager(t, NewMemoryStore())
+
+	protector := csrf.NewProtector(csrf.ProtectorConfig{
+		CookieManager: cookieMgr,
+		GetSessionID:  mgr.GetSessionID,
+	})
+
+	rp := response.NewProxy()
+	sess, err := mgr.CreateWithProxy(rp, httptest.NewRequest(http.MethodPost, "/", nil), "user-1", testData{Role: "member"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := protector.CycleTokenWithProxy(rp, sess.ID); err != nil {
+		t.Fatal(err)
+	}
+	r := requestWithCookies(rp)
+
+	var fromContext *Session[testData]
+	var sessionIDSeenByCSRF string
+	handler := mgr.Middleware(protector.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fromContext = mgr.FromContext(r.Context())
+		sessionIDSeenByCSRF = mgr.GetSessionID(r)
+	})))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if fromContext == nil || fromContext.ID != sess.ID || sessionIDSeenByCSRF != sess.ID {
+		t.Errorf("FromContext() = %+v, GetSessionID() = %q, want ID %q", fromContext, sessionIDSeenByCSRF, sess.ID)
+	}
+	if cookies := w.Result().Cookies(); len(cookies) != 0 {
+		t.Errorf("expected no new cookies for a fresh session and bound CSRF token, got %v", cookies)
+	}
+
+	if got := mgr.GetSessionID(httptest.NewRequest(http.MethodGet, "/", nil)); got != "" {
+		t.Errorf("GetSessionID() without a session = %q, want empty", got)
+	}
+}
+
+func TestMiddlewareStoreErrors(t *testing.T) {
+	store := &flakyStore{MemoryStore: NewMemoryStore()}
+	mgr, _, clock := newTestManager(t, store)
+	store.now = clock.now
+	var errs []error
+	mgr.cfg.OnError = func(r *http.Request, err error) { errs = append(errs, err) }
+
+	rp := response.NewProxy()
+	sess, err := mgr.CreateWithProxy(rp, httptest.NewRequest(http.MethodPost, "/", nil), "user-1", testData{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(r *http.Request) *Session[testData] {
+		var fromContext *Session[testData]
+		mgr.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fromContext = mgr.FromContext(r.Context())
+		})).ServeHTTP(httptest.NewRecorder(), r)
+		return fromContext
+	}
+
+	t.Run("NoSessionIsNotAnError", func(t *testing.T) {
+		errs = nil
+		if got := serve(httptest.NewRequest(http.MethodGet, "/", nil)); got != nil {
+			t.Errorf("FromContext() = %+v, want nil", got)
+		}
+		if len(errs) != 0 {
+			t.Errorf("OnError called with %v", errs)
+		}
+	})
+
+	t.Run("TouchFailureKeepsSession", func(t *testing.T) {
+		errs = nil
+		clock.advance(2 * time.Minute)
+		store.failSet = true
+		defer func() { store.failSet = false }()
+
+		got := serve(requestWithCookies(rp))
+		if got == nil || got.ID != sess.ID {
+			t.Errorf("FromContext() = %+v, want the loaded session", got)
+		}
+		if len(errs) != 1 || !errors.Is(errs[0], ErrTouchFailed) || !errors.Is(errs[0], errStoreDown) {
+			t.Errorf("OnError errors = %v, want one wrapping ErrTouchFailed", errs)
+		}
+	})
+
+	t.Run("StoreOutageIsReported", func(t *testing.T) {
+		errs = nil
+		store.failGet = true
+		defer func() { store.failGet = false }()
+
+		if got := serve(requestWithCookies(rp)); got != nil {
+			t.Errorf("FromContext() = %+v, want nil", got)
+		}
+		if len(errs) != 1 || !errors.Is(errs[0], errStoreDown) {
+			t.Errorf("OnError errors = %v, want the store error", errs)
+		}
+	})
+}
+
+func TestMemoryStoreDeleteExpired(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	store.Set(ctx, &Record{Key: "a", ExpiresAt: now.Add(-time.Second)})
+	store.Set(ctx, &Record{Key: "b", ExpiresAt: now.Add(time.Hour)})
+	if err := store.DeleteExpired(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.records) != 1 {
+		t.Errorf("expected 1 record, got %d", len(store.records))
+	}
+	if rec, _ := store.Get(ctx, "b"); rec == nil {
+		t.Error("unexpired record should remain")
+	}
+}
@@ -0,0 +1,144 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/river-now/river/kit/sqlutil"
+)
+
+type SQLDialect = sqlutil.Dialect
+
+const (
+	SQLDialectSQLite   = sqlutil.DialectSQLite
+	SQLDialectPostgres = sqlutil.DialectPostgres
+	SQLDialectMySQL    = sqlutil.DialectMySQL
+)
+
+type SQLStoreConfig struct {
+	DB      *sql.DB    // Required.
+	Dialect SQLDialect // Defaults to SQLDialectSQLite.
+	// Defaults to "sessions". Must be a plain SQL identifier.
+	TableName string
+}
+
+// A Store backed by a SQL database. Use SchemaSQL to create its table. Times
+// are stored as Unix milliseconds. Expired rows are ignored on read, and
+// deleted by DeleteExpired.
+type SQLStore struct {
+	db      *sql.DB
+	dialect SQLDialect
+	table   string
+	now     func() time.Time
+}
+
+// Panics if you fail to provide a DB or provide an invalid TableName.
+func NewSQLStore(cfg SQLStoreConfig) *SQLStore {
+	if cfg.DB == nil {
+		panic("session: SQLStoreConfig.DB is required")
+	}
+	if cfg.TableName == "" {
+		cfg.TableName = "sessions"
+	}
+	if !sqlutil.IsPlainIdentifier(cfg.TableName) {
+		panic(fmt.Sprintf("session: invalid table name %q", cfg.TableName))
+	}
+	return &SQLStore{db: cfg.DB, dialect: cfg.Dialect, table: cfg.TableName, now: time.Now}
+}
+
+// Returns the statements that create the store's table and indexes, if they
+// don't already exist.
+func (s *SQLStore) SchemaSQL() []string {
+	if s.dialect == SQLDialectMySQL {
+		return []string{fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	session_key VARCHAR(64) NOT NULL PRIMARY KEY,
+	subject VARCHAR(255) NOT NULL,
+	data LONGBLOB NOT NULL,
+	created_at BIGINT NOT NULL,
+	last_seen_at BIGINT NOT NULL,
+	expires_at BIGINT NOT NULL,
+	INDEX %s_subject_idx (subject),
+	INDEX %s_expires_at_idx (expires_at)
+)`, s.table, s.table, s.table)}
+	}
+	blobType := "BLOB"
+	if s.dialect == SQLDialectPostgres {
+		blobType = "BYTEA"
+	}
+	return []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	session_key TEXT NOT NULL PRIMARY KEY,
+	subject TEXT NOT NULL,
+	data %s NOT NULL,
+	created_at BIGINT NOT NULL,
+	last_seen_at BIGINT NOT NULL,
+	expires_at BIGINT NOT NULL
+)`, s.table, blobType),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_subject_idx ON %s (subject)`, s.table, s.table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_expires_at_idx ON %s (expires_at)`, s.table, s.table),
+	}
+}
+
+// Rewrites "?" placeholders for the store's dialect.
+func (s *SQLStore) q(query string) string {
+	return sqlutil.Rebind(s.dialect, query)
+}
+
+func (s *SQLStore) Get(ctx context.Context, key string) (*Record, error) {
+	rec := Record{Key: key}
+	var createdAt, lastSeenAt, expiresAt int64
+	err := s.db.QueryRowContext(ctx, s.q(fmt.Sprintf(
+		`SELECT subject, data, created_at, last_seen_at, expires_at FROM %s WHERE session_key = ? AND expires_at > ?`,
+		s.table,
+	)), key, s.now().UnixMilli()).Scan(&rec.Subject, &rec.Data, &createdAt, &lastSeenAt, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rec.CreatedAt = time.UnixMilli(createdAt)
+	rec.LastSeenAt = time.UnixMilli(lastSeenAt)
+	rec.ExpiresAt = time.UnixMilli(expiresAt)
+	return &rec, nil
+}
+
+// Upserts, so that concurrent writes for the same key (e.g., two requests
+// touching the same session) can't conflict on the primary key.
+func (s *SQLStore) Set(ctx context.Context, rec *Record) error {
+	query := fmt.Sprintf(
+		`INSERT INTO %s (session_key, subject, data, created_at, last_seen_at, expires_at) VALUES (?, ?, ?, ?, ?, ?) `,
+		s.table,
+	)
+	if s.dialect == SQLDialectMySQL {
+		query += `ON DUPLICATE KEY UPDATE subject = VALUES(subject), data = VALUES(data), ` +
+			`created_at = VALUES(created_at), last_seen_at = VALUES(last_seen_at), expires_at = VALUES(expires_at)`
+	} else {
+		query += `ON CONFLICT (session_key) DO UPDATE SET subject = excluded.subject, data = excluded.data, ` +
+			`created_at = excluded.created_at, last_seen_at = excluded.last_seen_at, expires_at = excluded.expires_at`
+	}
+	_, err := s.db.ExecContext(ctx, s.q(query),
+		rec.Key, rec.Subject, rec.Data,
+		rec.CreatedAt.UnixMilli(), rec.LastSeenAt.UnixMilli(), rec.ExpiresAt.UnixMilli(),
+	)
+	return err
+}
+
+func (s *SQLStore) Delete(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, s.q(fmt.Sprintf(`DELETE FROM %s WHERE session_key = ?`, s.table)), key)
+	return err
+}
+
+func (s *SQLStore) DeleteBySubject(ctx context.Context, subject string) error {
+	_, err := s.db.ExecContext(ctx, s.q(fmt.Sprintf(`DELETE FROM %s WHERE subject = ?`, s.table)), subject)
+	return err
+}
+
+// Deletes every expired row. Call periodically (e.g., from a cron job).
+func (s *SQLStore) DeleteExpired(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, s.q(fmt.Sprintf(`DELETE FROM %s WHERE expires_at <= ?`, s.table)), s.now().UnixMilli())
+	return err
+}
@@ -0,0 +1,187 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+func newTestSQLStore(t *testing.T) *SQLStore {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1) // each connection would get its own in-memory database
+	t.Cleanup(func() { db.Close() })
+	store := NewSQLStore(SQLStoreConfig{DB: db})
+	for _, stmt := range store.SchemaSQL() {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("failed to create schema: %v", err)
+		}
+	}
+	return store
+}
+
+func TestSQLStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.UnixMilli(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli())
+
+	newStore := func(t *testing.T) *SQLStore {
+		store := newTestSQLStore(t)
+		store.now = func() time.Time { return now }
+		return store
+	}
+	record := func(key, subject string, expiresIn time.Duration) *Record {
+		return &Record{
+			Key:        key,
+			Subject:    subject,
+			Data:       []byte("data-" + key),
+			CreatedAt:  now.Add(-time.Hour),
+			LastSeenAt: now,
+			ExpiresAt:  now.Add(expiresIn),
+		}
+	}
+	keys := func(t *testing.T, store *SQLStore) []string {
+		t.Helper()
+		rows, err := store.db.Query(`SELECT session_key FROM sessions ORDER BY session_key`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var keys []string
+		for rows.Next() {
+			var k string
+			if err := rows.Scan(&k); err != nil {
+				t.Fatal(err)
+			}
+			keys = append(keys, k)
+		}
+		return keys
+	}
+
+	t.Run("SetAndGet", func(t *testing.T) {
+		store := newStore(t)
+		want := record("a", "user-1", time.Hour)
+		if err := store.Set(ctx, want); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+		got, err := store.Get(ctx, "a")
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if got == nil || got.Subject != want.Subject || string(got.Data) != string(want.Data) ||
+			!got.CreatedAt.Equal(want.CreatedAt) || !got.LastSeenAt.Equal(want.LastSeenAt) || !got.ExpiresAt.Equal(want.ExpiresAt) {
+			t.Errorf("Get() = %+v, want %+v", got, want)
+		}
+		if got, err := store.Get(ctx, "missing"); got != nil || err != nil {
+			t.Errorf("Get(missing) = %+v, %v, want nil, nil", got, err)
+		}
+	})
+
+	t.Run("SetUpserts", func(t *testing.T) {
+		store := newStore(t)
+		if err := store.Set(ctx, record("a", "user-1", time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		updated := record("a", "user-2", 2*time.Hour)
+		updated.Data = []byte("updated")
+		if err := store.Set(ctx, updated); err != nil {
+			t.Fatalf("Set() on an existing key error = %v", err)
+		}
+		got, _ := store.Get(ctx, "a")
+		if got == nil || got.Subject != "user-2" || string(got.Data) != "updated" || !got.ExpiresAt.Equal(updated.ExpiresAt) {
+			t.Errorf("Get() = %+v, want the updated record", got)
+		}
+		if k := keys(t, store); len(k) != 1 {
+			t.Errorf("rows = %v, want exactly one", k)
+		}
+	})
+
+	t.Run("GetIgnoresExpired", func(t *testing.T) {
+		store := newStore(t)
+		store.Set(ctx, record("a", "user-1", 0))
+		if got, err := store.Get(ctx, "a"); got != nil || err != nil {
+			t.Errorf("Get() = %+v, %v, want nil, nil for an expired record", got, err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		store := newStore(t)
+		store.Set(ctx, record("a", "user-1", time.Hour))
+		store.Set(ctx, record("b", "user-1", time.Hour))
+		if err := store.Delete(ctx, "a"); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if err := store.Delete(ctx, "missing"); err != nil {
+			t.Errorf("Delete(missing) error = %v", err)
+		}
+		if k := keys(t, store); len(k) != 1 || k[0] != "b" {
+			t.Errorf("rows = %v, want [b]", k)
+		}
+	})
+
+	t.Run("DeleteBySubject", func(t *testing.T) {
+		store := newStore(t)
+		store.Set(ctx, record("a", "user-1", time.Hour))
+		store.Set(ctx, record("b", "user-1", time.Hour))
+		store.Set(ctx, record("c", "user-2", time.Hour))
+		if err := store.DeleteBySubject(ctx, "user-1"); err != nil {
+			t.Fatalf("DeleteBySubject() error = %v", err)
+		}
+		if k := keys(t, store); len(k) != 1 || k[0] != "c" {
+			t.Errorf("rows = %v, want [c]", k)
+		}
+	})
+
+	t.Run("DeleteExpired", func(t *testing.T) {
+		store := newStore(t)
+		store.Set(ctx, record("a", "user-1", -time.Second))
+		store.Set(ctx, record("b", "user-1", 0))
+		store.Set(ctx, record("c", "user-1", time.Hour))
+		if err := store.DeleteExpired(ctx); err != nil {
+			t.Fatalf("DeleteExpired() error = %v", err)
+		}
+		if k := keys(t, store); len(k) != 1 || k[0] != "c" {
+			t.Errorf("rows = %v, want [c]", k)
+		}
+	})
+}
+
+func TestSQLStorePlaceholders(t *testing.T) {
+	query := `DELETE FROM sessions WHERE session_key = ? AND expires_at > ?`
+	tests := []struct {
+		dialect SQLDialect
+		want    string
+	}{
+		{SQLDialectSQLite, query},
+		{SQLDialectMySQL, query},
+		{SQLDialectPostgres, `DELETE FROM sessions WHERE session_key = $1 AND expires_at > $2`},
+	}
+	for _, tt := range tests {
+		s := &SQLStore{dialect: tt.dialect, table: "sessions"}
+		if got := s.q(query); got != tt.want {
+			t.Errorf("dialect %d: q() = %q, want %q", tt.dialect, got, tt.want)
+		}
+	}
+}
+
+func TestSQLStoreSchemaSQL(t *testing.T) {
+	t.Run("Postgres", func(t *testing.T) {
+		stmts := (&SQLStore{dialect: SQLDialectPostgres, table: "app_sessions"}).SchemaSQL()
+		if len(stmts) != 3 || !strings.Contains(stmts[0], "app_sessions") || !strings.Contains(stmts[0], "BYTEA") {
+			t.Errorf("unexpected schema: %v", stmts)
+		}
+	})
+
+	t.Run("MySQL", func(t *testing.T) {
+		stmts := (&SQLStore{dialect: SQLDialectMySQL, table: "sessions"}).SchemaSQL()
+		if len(stmts) != 1 || !strings.Contains(stmts[0], "INDEX sessions_subject_idx") {
+			t.Errorf("unexpected schema: %v", stmts)
+		}
+	})
+}
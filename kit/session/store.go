@@ -0,0 +1,106 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A server-side session as seen by a Store.
+type Record struct {
+	// A SHA-256 hash of the session ID. Stores never see raw session IDs.
+	Key        string
+	Subject    string
+	Data       []byte // Gob-encoded session data
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	// The earlier of the idle and absolute expiry. Stores may delete records
+	// at or after this time.
+	ExpiresAt time.Time
+}
+
+// Server-side session storage. Implementations must be safe for concurrent
+// use.
+type Store interface {
+	// Returns nil (and no error) if there is no such record, or it has
+	// expired.
+	Get(ctx context.Context, key string) (*Record, error)
+	// Inserts or replaces the record with rec.Key.
+	Set(ctx context.Context, rec *Record) error
+	// Deleting a nonexistent record is not an error.
+	Delete(ctx context.Context, key string) error
+	DeleteBySubject(ctx context.Context, subject string) error
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// MEMORY STORE
+/////////////////////////////////////////////////////////////////////
+
+// An in-memory Store, suitable for development, tests, and single-instance
+// deployments that can tolerate losing sessions on restart. Expired records
+// are dropped lazily and by DeleteExpired.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records map[string]Record
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]Record), now: time.Now}
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) (*Record, error) {
+	s.mu.RLock()
+	rec, ok := s.records[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+	if !s.now().Before(rec.ExpiresAt) {
+		s.mu.Lock()
+		if current, ok := s.records[key]; ok && !s.now().Before(current.ExpiresAt) {
+			delete(s.records, key)
+		}
+		s.mu.Unlock()
+		return nil, nil
+	}
+	return &rec, nil
+}
+
+func (s *MemoryStore) Set(_ context.Context, rec *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[rec.Key] = *rec
+	return nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+func (s *MemoryStore) DeleteBySubject(_ context.Context, subject string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, rec := range s.records {
+		if rec.Subject == subject {
+			delete(s.records, key)
+		}
+	}
+	return nil
+}
+
+// Drops every expired record. Call periodically to bound memory use.
+func (s *MemoryStore) DeleteExpired(_ context.Context) error {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, rec := range s.records {
+		if !now.Before(rec.ExpiresAt) {
+			delete(s.records, key)
+		}
+	}
+	return nil
+}
@@ -36,15 +36,16 @@ func NewManager(secrets keyset.RootSecrets) (*Manager, error) {
 // VerifyAndReadCookieValue retrieves and verifies the value of a signed cookie.
 // It returns an error if the cookie is not found or is invalid.
 func (m Manager) VerifyAndReadCookieValue(r *http.Request, key string) (string, error) {
+	value, _, err := m.verifyAndReadCookieValueWithKeyIndex(r, key)
+	return value, err
+}
+
+func (m Manager) verifyAndReadCookieValueWithKeyIndex(r *http.Request, key string) (string, int, error) {
 	cookie, err := r.Cookie(key)
 	if err != nil {
-		return "", err
-	}
-	value, err := m.verifyAndReadValue(cookie.Value)
-	if err != nil {
-		return "", err
+		return "", -1, err
 	}
-	return value, nil
+	return m.verifyAndReadValueWithKeyIndex(cookie.Value)
 }
 
 // NewDeletionCookie creates a new cookie that will delete the specified cookie when sent to the client.
@@ -98,16 +99,23 @@ func (m Manager) signValue(unsignedValue string, encrypt bool) (string, error) {
 // verifyAndReadValue verifies and reads the signed value.
 // It returns the original unsigned value or an error if verification fails.
 func (m Manager) verifyAndReadValue(signedValue string) (string, error) {
+	value, _, err := m.verifyAndReadValueWithKeyIndex(signedValue)
+	return value, err
+}
+
+// verifyAndReadValueWithKeyIndex is like verifyAndReadValue, but also
+// returns the index of the secret that verified the value.
+func (m Manager) verifyAndReadValueWithKeyIndex(signedValue string) (string, int, error) {
 	bytes, err := bytesutil.FromBase64(signedValue)
 	if err != nil {
-		return "", fmt.Errorf("error decoding base64: %w", err)
+		return "", -1, fmt.Errorf("error decoding base64: %w", err)
 	}
 	if len(bytes) < 1 {
-		return "", errors.New("invalid signed value")
+		return "", -1, errors.New("invalid signed value")
 	}
 	prefix := bytes[0]
 	signedBytes := bytes[1:]
-	return keyset.Attempt(m.keyset,
+	return keyset.AttemptWithKeyIndex(m.keyset,
 		func(secret cryptoutil.Key32) (string, error) {
 			value, err := cryptoutil.VerifyAndReadSymmetric(signedBytes, secret)
 			if err == nil {
@@ -184,6 +192,37 @@ func (sc *SignedCookie[T]) VerifyAndReadCookieValue(r *http.Request) (T, error)
 	return bytesutil.FromGob[T](dataBytes)
 }
 
+// VerifyAndReadCookieValueAndUpgrade is like VerifyAndReadCookieValue, but if the cookie
+// was signed with a non-primary secret, it also returns a replacement cookie signed with
+// the primary secret (otherwise the returned cookie is nil). Set the replacement on the
+// response so that older secrets can eventually be dropped. Note that the replacement's
+// expiration is reset based on TTL.
+func (sc *SignedCookie[T]) VerifyAndReadCookieValueAndUpgrade(r *http.Request) (T, *http.Cookie, error) {
+	var zeroT T
+
+	value, keyIndex, err := sc.Manager.verifyAndReadCookieValueWithKeyIndex(r, sc.BaseCookie.Name)
+	if err != nil {
+		return zeroT, nil, err
+	}
+
+	dataBytes, err := bytesutil.FromBase64(value)
+	if err != nil {
+		return zeroT, nil, err
+	}
+
+	data, err := bytesutil.FromGob[T](dataBytes)
+	if err != nil || keyIndex == 0 {
+		return data, nil, err
+	}
+
+	upgraded, err := sc.NewSignedCookie(data, nil)
+	if err != nil {
+		return data, nil, fmt.Errorf("error re-signing cookie: %w", err)
+	}
+
+	return data, upgraded, nil
+}
+
 // newSecureCookieWithoutValue creates a new secure cookie with the provided name, expiration, and base settings.
 // It ensures that the cookie is marked as HTTP-only and secure.
 func newSecureCookieWithoutValue(name string, expires *time.Time, baseCookie *BaseCookie) *http.Cookie {
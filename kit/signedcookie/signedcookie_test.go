@@ -453,6 +453,41 @@ func TestManagerMultipleSecrets(t *testing.T) {
 	})
 }
 
+func TestSignedCookieUpgrade(t *testing.T) {
+	oldManager, _ := NewManager(keyset.RootSecrets{aSecret})
+	rotatedManager, _ := NewManager(keyset.RootSecrets{bSecret, aSecret})
+
+	oldCookie := &SignedCookie[string]{Manager: oldManager, TTL: time.Hour, BaseCookie: http.Cookie{Name: "test-cookie"}, Encrypt: true}
+	rotatedCookie := &SignedCookie[string]{Manager: rotatedManager, TTL: time.Hour, BaseCookie: http.Cookie{Name: "test-cookie"}, Encrypt: true}
+
+	cookie, _ := oldCookie.NewSignedCookie("test-value", nil)
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.AddCookie(cookie)
+
+	value, upgraded, err := rotatedCookie.VerifyAndReadCookieValueAndUpgrade(req)
+	if err != nil {
+		t.Fatalf("Failed to read cookie: %v", err)
+	}
+	if value != "test-value" || upgraded == nil {
+		t.Fatalf("Expected value and upgraded cookie, got %q, %v", value, upgraded)
+	}
+
+	t.Run("UpgradedCookieUsesPrimarySecret", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.AddCookie(upgraded)
+		value, again, err := rotatedCookie.VerifyAndReadCookieValueAndUpgrade(req)
+		if err != nil || value != "test-value" {
+			t.Fatalf("Failed to read upgraded cookie: %q, %v", value, err)
+		}
+		if again != nil {
+			t.Errorf("Expected no upgrade for a cookie signed with the primary secret")
+		}
+		if _, err := oldCookie.VerifyAndReadCookieValue(req); err == nil {
+			t.Errorf("Expected old manager to reject upgraded cookie")
+		}
+	})
+}
+
 func TestSignedCookieEdgeCases(t *testing.T) {
 	secrets := keyset.RootSecrets{aSecret}
 	manager, _ := NewManager(secrets)
@@ -4,6 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
 // Transaction runs a function within a transaction.
@@ -30,3 +33,38 @@ func TransactionContext(db *sql.DB, ctx context.Context, opts *sql.TxOptions, f
 	err = f(tx)
 	return err
 }
+
+type Dialect int
+
+const (
+	DialectSQLite Dialect = iota
+	DialectPostgres
+	DialectMySQL
+)
+
+// Rebind rewrites "?" placeholders in query for the provided dialect (e.g.,
+// to "$1", "$2", etc. for Postgres).
+func Rebind(dialect Dialect, query string) string {
+	if dialect != DialectPostgres {
+		return query
+	}
+	var sb strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			sb.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+var identifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// IsPlainIdentifier reports whether s is safe to interpolate into a query as
+// an unquoted identifier (e.g., a configurable table name).
+func IsPlainIdentifier(s string) bool {
+	return identifierRegex.MatchString(s)
+}
@@ -0,0 +1,52 @@
+package sqlutil
+
+import "testing"
+
+func TestRebind(t *testing.T) {
+	query := "SELECT a FROM t WHERE b = ? AND c IN (?, ?)"
+	tests := []struct {
+		name    string
+		dialect Dialect
+		want    string
+	}{
+		{"SQLite", DialectSQLite, query},
+		{"MySQL", DialectMySQL, query},
+		{"Postgres", DialectPostgres, "SELECT a FROM t WHERE b = $1 AND c IN ($2, $3)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Rebind(tt.dialect, query); got != tt.want {
+				t.Errorf("Rebind() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("NoPlaceholders", func(t *testing.T) {
+		if got := Rebind(DialectPostgres, "SELECT 1"); got != "SELECT 1" {
+			t.Errorf("Rebind() = %q, want unchanged query", got)
+		}
+	})
+}
+
+func TestIsPlainIdentifier(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"sessions", true},
+		{"audit_log", true},
+		{"_t1", true},
+		{"Table2", true},
+		{"", false},
+		{"1table", false},
+		{"my-table", false},
+		{"schema.table", false},
+		{"t; DROP TABLE users", false},
+		{`"quoted"`, false},
+	}
+	for _, tt := range tests {
+		if got := IsPlainIdentifier(tt.in); got != tt.want {
+			t.Errorf("IsPlainIdentifier(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package tasks
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// limiter is a counting semaphore that hands out free slots to the
+// highest-priority waiter first (FIFO among waiters of equal priority).
+type limiter struct {
+	mu      sync.Mutex
+	limit   int
+	active  int
+	seq     uint64
+	waiters waiterHeap
+}
+
+func newLimiter(limit int) *limiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &limiter{limit: limit}
+}
+
+func (l *limiter) acquire(ctx context.Context, priority int) error {
+	l.mu.Lock()
+	if l.active < l.limit && len(l.waiters) == 0 {
+		l.active++
+		l.mu.Unlock()
+		return nil
+	}
+	w := &waiter{priority: priority, seq: l.seq, ready: make(chan struct{})}
+	l.seq++
+	heap.Push(&l.waiters, w)
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		select {
+		case <-w.ready:
+			// Slot was handed to us while we were giving up, so give it back.
+			l.mu.Unlock()
+			l.release()
+		default:
+			heap.Remove(&l.waiters, w.index)
+			l.mu.Unlock()
+		}
+		return ctx.Err()
+	}
+}
+
+func (l *limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.waiters) > 0 {
+		// Transfer the slot directly so "active" stays constant.
+		w := heap.Pop(&l.waiters).(*waiter)
+		close(w.ready)
+		return
+	}
+	l.active--
+}
+
+type waiter struct {
+	priority int
+	seq      uint64
+	ready    chan struct{}
+	index    int
+}
+
+type waiterHeap []*waiter
+
+func (h waiterHeap) Len() int { return len(h) }
+func (h waiterHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h waiterHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *waiterHeap) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*h)
+	*h = append(*h, w)
+}
+func (h *waiterHeap) Pop() any {
+	old := *h
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	w.index = -1
+	*h = old[:n-1]
+	return w
+}
@@ -11,10 +11,14 @@ package tasks
 // via top-level var declarations).
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"math"
 	"reflect"
+	"slices"
 	"sync"
+	"sync/atomic"
 
 	"github.com/river-now/river/kit/genericsutil"
 	"golang.org/x/sync/errgroup"
@@ -55,17 +59,39 @@ type TasksCtx struct {
 	mu      *sync.RWMutex
 	results map[taskKey]*TaskResult
 	ctx     context.Context
+	limiter *limiter
+	// True when the goroutine using this TasksCtx is occupying a
+	// limiter slot (i.e., it is running inside a limited tasks.Go call).
+	holdsSlot bool
+}
+
+type TasksCtxOptions struct {
+	// Caps how many callables may run at once across every tasks.Go call
+	// sharing this TasksCtx (including nested calls made from inside
+	// tasks). A callable that is blocked waiting on its own nested
+	// tasks.Go call, or on a task that another callable is already running,
+	// gives up its slot until it is unblocked. Zero or negative means no
+	// limit.
+	MaxConcurrency int
 }
 
 func NewTasksCtx(parent context.Context) *TasksCtx {
+	return NewTasksCtxWithOptions(parent, nil)
+}
+
+func NewTasksCtxWithOptions(parent context.Context, opts *TasksCtxOptions) *TasksCtx {
 	if parent == nil {
 		parent = context.Background()
 	}
-	return &TasksCtx{
+	c := &TasksCtx{
 		mu:      &sync.RWMutex{},
 		results: make(map[taskKey]*TaskResult, 4), // Pre-allocate for typical request size
 		ctx:     parent,
 	}
+	if opts != nil {
+		c.limiter = newLimiter(opts.MaxConcurrency)
+	}
+	return c
 }
 
 func (c *TasksCtx) NativeContext() context.Context {
@@ -86,19 +112,24 @@ func Do[I comparable, O any](c *TasksCtx, task *Task[I, O], input I) (result O,
 	}
 
 	r := c.getOrCreateResult(task, input)
-	r.once.Do(func() {
-		val, err := task.fn(c, input)
-		if err != nil {
-			r.Err = err
-			return
-		}
-		if cerr := c.ctx.Err(); cerr != nil {
-			r.Err = cerr
-			return
-		}
-		r.Data = val
-		r.Err = nil
-	})
+	if r.claimed.CompareAndSwap(false, true) {
+		func() {
+			defer close(r.done)
+			val, err := task.fn(c, input)
+			if err != nil {
+				r.Err = err
+				return
+			}
+			if cerr := c.ctx.Err(); cerr != nil {
+				r.Err = cerr
+				return
+			}
+			r.Data = val
+			r.Err = nil
+		}()
+	} else {
+		c.wait(r)
+	}
 
 	if r.Err != nil {
 		return result, r.Err
@@ -137,13 +168,33 @@ func (c *TasksCtx) getOrCreateResult(taskPtr any, input any) *TaskResult {
 }
 
 type TaskResult struct {
-	Data any
-	Err  error
-	once *sync.Once
+	Data    any
+	Err     error
+	claimed atomic.Bool
+	done    chan struct{}
 }
 
 func newTaskResult() *TaskResult {
-	return &TaskResult{once: &sync.Once{}}
+	return &TaskResult{done: make(chan struct{})}
+}
+
+// Waits for another goroutine to finish running r's task. If the caller is
+// occupying a limiter slot, it gives the slot up while it waits: the task's
+// owner may have given up its own slot to run a nested tasks.Go call, whose
+// children would otherwise never get one.
+func (c *TasksCtx) wait(r *TaskResult) {
+	select {
+	case <-r.done:
+		return
+	default:
+	}
+	if !c.holdsSlot {
+		<-r.done
+		return
+	}
+	c.limiter.release()
+	<-r.done
+	c.limiter.acquire(context.Background(), math.MaxInt)
 }
 
 func (r *TaskResult) OK() bool {
@@ -200,13 +251,54 @@ func (bc *BoundCall[O]) Run(ctx *TasksCtx) error {
 
 func (bc *BoundCall[O]) IsCallable() {}
 
+type prioritizedCallable struct {
+	Callable
+	priority int
+}
+
+// Wraps a Callable so that, whenever a concurrency limit is in effect, it is
+// started ahead of callables with a lower priority. Higher values run first,
+// and callables with equal priority run in the order they were passed. The
+// default priority is 0. Without a limit, priorities have no effect.
+func WithPriority(c Callable, priority int) Callable {
+	if c == nil {
+		return nil
+	}
+	return &prioritizedCallable{Callable: c, priority: priority}
+}
+
+func getPriority(c Callable) int {
+	if pc, ok := c.(*prioritizedCallable); ok {
+		return pc.priority
+	}
+	return 0
+}
+
+type GoOptions struct {
+	// Caps how many of this call's callables may run at once. Applies in
+	// addition to any limit set on the TasksCtx via TasksCtxOptions. Zero
+	// or negative means no per-call limit.
+	MaxConcurrency int
+}
+
 func Go(ctx *TasksCtx, calls ...Callable) error {
+	return GoWithOptions(ctx, nil, calls...)
+}
+
+func GoWithOptions(ctx *TasksCtx, opts *GoOptions, calls ...Callable) error {
 	if ctx == nil {
 		return errors.New("tasks: Go called with nil TasksCtx")
 	}
 	if err := ctx.ctx.Err(); err != nil {
 		return err
 	}
+	callLimit := 0
+	if opts != nil {
+		callLimit = opts.MaxConcurrency
+	}
+	if ctx.limiter != nil || (callLimit > 0 && callLimit < len(calls)) {
+		return goLimited(ctx, callLimit, calls)
+	}
 	valid := calls[:0]
 	for _, c := range calls {
 		if c != nil {
@@ -236,3 +328,106 @@ func Go(ctx *TasksCtx, calls ...Callable) error {
 	}
 	return g.Wait()
 }
+
+func goLimited(ctx *TasksCtx, callLimit int, calls []Callable) error {
+	valid := make([]Callable, 0, len(calls))
+	for _, c := range calls {
+		if c != nil {
+			valid = append(valid, c)
+		}
+	}
+	if len(valid) == 0 {
+		return nil
+	}
+	slices.SortStableFunc(valid, func(a, b Callable) int {
+		return cmp.Compare(getPriority(b), getPriority(a))
+	})
+
+	// If the caller is itself occupying a slot, free it while it waits on
+	// its children, then take one back (ahead of everyone else) afterwards.
+	if ctx.holdsSlot {
+		ctx.limiter.release()
+		defer ctx.limiter.acquire(context.Background(), math.MaxInt)
+	}
+
+	local := newLimiter(callLimit)
+	// Cancel before releasing a failed callable's slot, so queued callables
+	// are never started after an error.
+	gCtx, cancel := context.WithCancel(ctx.ctx)
+	defer cancel()
+	shared := &TasksCtx{
+		mu:        ctx.mu,
+		results:   ctx.results,
+		ctx:       gCtx,
+		limiter:   ctx.limiter,
+		holdsSlot: ctx.limiter != nil,
+	}
+	release := func() {
+		if shared.limiter != nil {
+			shared.limiter.release()
+		}
+		if local != nil {
+			local.release()
+		}
+	}
+
+	var (
+		wg         sync.WaitGroup
+		errOnce    sync.Once
+		firstErr   error
+		acquireErr error
+		started    int
+	)
+	for _, call := range valid {
+		priority := getPriority(call)
+		if local != nil {
+			if err := local.acquire(gCtx, priority); err != nil {
+				acquireErr = err
+				break
+			}
+		}
+		if shared.limiter != nil {
+			if err := shared.limiter.acquire(gCtx, priority); err != nil {
+				if local != nil {
+					local.release()
+				}
+				acquireErr = err
+				break
+			}
+		}
+		if err := gCtx.Err(); err != nil {
+			release()
+			acquireErr = err
+			break
+		}
+		c := call
+		started++
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := c.Run(shared)
+			if err == nil {
+				err = shared.ctx.Err()
+			}
+			if err != nil {
+				errOnce.Do(func() { firstErr = err })
+				cancel()
+			}
+			release()
+		}()
+	}
+	wg.Wait()
+	if acquireErr != nil {
+		// Run whatever never got a slot on a canceled TasksCtx, as the
+		// unlimited path would, so each callable sees the error (tasks
+		// return it without running) rather than silently never running.
+		drained := &TasksCtx{mu: ctx.mu, results: ctx.results, ctx: gCtx}
+		for _, c := range valid[started:] {
+			c.Run(drained)
+		}
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	return acquireErr
+}
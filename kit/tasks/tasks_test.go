@@ -543,3 +543,242 @@ func TestTasksWithDifferentInputs(t *testing.T) {
 		}
 	})
 }
+
+func TestConcurrencyLimits(t *testing.T) {
+	newTrackedTask := func(current, peak *int32) *Task[int, int] {
+		return NewTask(func(c *TasksCtx, input int) (int, error) {
+			n := atomic.AddInt32(current, 1)
+			for {
+				p := atomic.LoadInt32(peak)
+				if n <= p || atomic.CompareAndSwapInt32(peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(current, -1)
+			return input, nil
+		})
+	}
+
+	t.Run("PerCallLimit", func(t *testing.T) {
+		var current, peak int32
+		task := newTrackedTask(&current, &peak)
+		ctx := NewTasksCtx(context.Background())
+
+		results := make([]int, 10)
+		calls := make([]Callable, 10)
+		for i := range calls {
+			calls[i] = Bind(task, i).AssignTo(&results[i])
+		}
+		if err := GoWithOptions(ctx, &GoOptions{MaxConcurrency: 3}, calls...); err != nil {
+			t.Fatal(err)
+		}
+		if peak > 3 {
+			t.Errorf("Expected at most 3 concurrent executions, got %d", peak)
+		}
+		for i, r := range results {
+			if r != i {
+				t.Errorf("Expected result %d at index %d, got %d", i, i, r)
+			}
+		}
+	})
+
+	t.Run("TasksCtxLimitAcrossCalls", func(t *testing.T) {
+		var current, peak int32
+		task := newTrackedTask(&current, &peak)
+		ctx := NewTasksCtxWithOptions(context.Background(), &TasksCtxOptions{MaxConcurrency: 2})
+
+		var wg sync.WaitGroup
+		for g := range 3 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				calls := make([]Callable, 4)
+				for i := range calls {
+					calls[i] = Bind(task, g*10+i)
+				}
+				if err := Go(ctx, calls...); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+		if peak > 2 {
+			t.Errorf("Expected at most 2 concurrent executions, got %d", peak)
+		}
+	})
+
+	t.Run("NestedGoDoesNotDeadlock", func(t *testing.T) {
+		var current, peak int32
+		leaf := newTrackedTask(&current, &peak)
+		parent := NewTask(func(c *TasksCtx, input int) (int, error) {
+			var a, b int
+			if err := Go(c, Bind(leaf, input*10).AssignTo(&a), Bind(leaf, input*10+1).AssignTo(&b)); err != nil {
+				return 0, err
+			}
+			return a + b, nil
+		})
+		ctx := NewTasksCtxWithOptions(context.Background(), &TasksCtxOptions{MaxConcurrency: 1})
+
+		done := make(chan error, 1)
+		var r1, r2 int
+		go func() {
+			done <- Go(ctx, Bind(parent, 1).AssignTo(&r1), Bind(parent, 2).AssignTo(&r2))
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Nested Go deadlocked under a TasksCtx limit of 1")
+		}
+		if r1 != 21 || r2 != 41 {
+			t.Errorf("Expected 21 and 41, got %d and %d", r1, r2)
+		}
+		if peak > 1 {
+			t.Errorf("Expected at most 1 concurrent leaf execution, got %d", peak)
+		}
+	})
+
+	t.Run("WaitingOnSharedTaskDoesNotDeadlock", func(t *testing.T) {
+		leaf := NewTask(func(c *TasksCtx, input int) (int, error) {
+			time.Sleep(5 * time.Millisecond)
+			return input, nil
+		})
+		// Gives up its slot to run children, which the waiter below would
+		// otherwise take and hold while blocked on this task.
+		shared := NewTask(func(c *TasksCtx, _ int) (int, error) {
+			var a, b int
+			if err := Go(c, Bind(leaf, 1).AssignTo(&a), Bind(leaf, 2).AssignTo(&b)); err != nil {
+				return 0, err
+			}
+			return a + b, nil
+		})
+		waiter := NewTask(func(c *TasksCtx, _ int) (int, error) {
+			return Do(c, shared, 0)
+		})
+		ctx := NewTasksCtxWithOptions(context.Background(), &TasksCtxOptions{MaxConcurrency: 1})
+
+		done := make(chan error, 1)
+		var r1, r2 int
+		go func() {
+			done <- Go(ctx, Bind(shared, 0).AssignTo(&r1), Bind(waiter, 0).AssignTo(&r2))
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Waiting on a shared task deadlocked under a TasksCtx limit of 1")
+		}
+		if r1 != 3 || r2 != 3 {
+			t.Errorf("Expected 3 and 3, got %d and %d", r1, r2)
+		}
+	})
+
+	t.Run("PriorityOrdering", func(t *testing.T) {
+		var mu sync.Mutex
+		var order []int
+		task := NewTask(func(c *TasksCtx, input int) (int, error) {
+			mu.Lock()
+			order = append(order, input)
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			return input, nil
+		})
+		ctx := NewTasksCtx(context.Background())
+
+		err := GoWithOptions(ctx, &GoOptions{MaxConcurrency: 1},
+			Bind(task, 1),
+			WithPriority(Bind(task, 2), 5),
+			Bind(task, 3),
+			WithPriority(Bind(task, 4), 10),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []int{4, 2, 1, 3}
+		if fmt.Sprint(order) != fmt.Sprint(expected) {
+			t.Errorf("Expected execution order %v, got %v", expected, order)
+		}
+	})
+
+	t.Run("ErrorStopsQueuedCalls", func(t *testing.T) {
+		var ran int32
+		failing := NewTask(func(c *TasksCtx, _ int) (int, error) {
+			return 0, errors.New("boom")
+		})
+		counting := NewTask(func(c *TasksCtx, input int) (int, error) {
+			atomic.AddInt32(&ran, 1)
+			time.Sleep(20 * time.Millisecond)
+			return input, nil
+		})
+		ctx := NewTasksCtx(context.Background())
+
+		err := GoWithOptions(ctx, &GoOptions{MaxConcurrency: 1},
+			Bind(failing, 0),
+			Bind(counting, 1),
+			Bind(counting, 2),
+		)
+		if err == nil || err.Error() != "boom" {
+			t.Fatalf("Expected 'boom' error, got %v", err)
+		}
+		if ran != 0 {
+			t.Errorf("Expected queued calls to be skipped after an error, but %d ran", ran)
+		}
+	})
+
+	t.Run("QueuedCallsSeeTheError", func(t *testing.T) {
+		var runErrs []error
+		var mu sync.Mutex
+		failing := NewTask(func(c *TasksCtx, _ int) (int, error) {
+			return 0, errors.New("boom")
+		})
+		ok := NewTask(func(c *TasksCtx, input int) (int, error) { return input, nil })
+		record := func(c Callable) Callable {
+			return callableFunc(func(ctx *TasksCtx) error {
+				err := c.Run(ctx)
+				mu.Lock()
+				runErrs = append(runErrs, err)
+				mu.Unlock()
+				return err
+			})
+		}
+		ctx := NewTasksCtx(context.Background())
+
+		GoWithOptions(ctx, &GoOptions{MaxConcurrency: 1},
+			record(Bind(failing, 0)), record(Bind(ok, 1)), record(Bind(ok, 2)),
+		)
+		if len(runErrs) != 3 {
+			t.Fatalf("Expected every call to run, got %d", len(runErrs))
+		}
+		for _, err := range runErrs[1:] {
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected queued calls to get context.Canceled, got %v", err)
+			}
+		}
+	})
+
+	t.Run("CanceledWhileWaiting", func(t *testing.T) {
+		var current, peak int32
+		task := newTrackedTask(&current, &peak)
+		parentCtx, cancel := context.WithCancel(context.Background())
+		ctx := NewTasksCtxWithOptions(parentCtx, &TasksCtxOptions{MaxConcurrency: 1})
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+		}()
+		err := Go(ctx, Bind(task, 1), Bind(task, 2), Bind(task, 3))
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+type callableFunc func(ctx *TasksCtx) error
+
+func (f callableFunc) Run(ctx *TasksCtx) error { return f(ctx) }
+func (f callableFunc) IsCallable()             {}
@@ -0,0 +1,270 @@
+// Package urltoken provides URL-safe, expiring, purpose-scoped tokens, for
+// things like passwordless login links and time-limited download links.
+// Tokens are encrypted and authenticated via securebytes (rather than
+// securestring, since payloads are arbitrary gob-encoded values) with a keyset
+// derived from an AppKeyset for the token purpose, so tokens issued for one
+// purpose are never accepted for another. Tokens can optionally be made
+// single-use with a Store.
+package urltoken
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/river-now/river/kit/contextutil"
+	"github.com/river-now/river/kit/cryptoutil"
+	"github.com/river-now/river/kit/keyset"
+	"github.com/river-now/river/kit/securebytes"
+)
+
+const idSize = 16 // Size, in bytes, of random token IDs.
+
+var (
+	// Returned when a token is malformed, was issued for a different
+	// purpose or URL, or fails authentication.
+	ErrInvalidToken = errors.New("urltoken: invalid token")
+	ErrExpiredToken = errors.New("urltoken: token has expired")
+	// Returned when a single-use token has already been used.
+	ErrUsedToken = errors.New("urltoken: token has already been used")
+)
+
+type Config struct {
+	// REQUIRED: Tokens are encrypted with AppKeyset.HKDF("urltoken:" + Purpose).
+	AppKeyset *keyset.AppKeyset
+	// REQUIRED: What the tokens are for (e.g., "magic_link" or "download").
+	// Once set, do not change this unless you want to invalidate every
+	// outstanding token.
+	Purpose string
+	// How long tokens are valid. Defaults to 15 minutes.
+	TTL time.Duration
+	// Optional. If set, each token is accepted at most once.
+	Store Store
+	// The query parameter that holds the token in signed URLs. Defaults
+	// to "token".
+	QueryParam string
+}
+
+type Manager[T any] struct {
+	cfg          Config
+	getKeyset    func() *keyset.Keyset
+	contextStore *contextutil.Store[T]
+	now          func() time.Time
+}
+
+// Everything in a token. Fields must be exported for gob.
+type payload[T any] struct {
+	Purpose   string
+	ID        string
+	ExpiresAt int64  // Unix seconds
+	URLHash   []byte // Nil unless the token signs a URL
+	Data      T
+}
+
+// Panics if you fail to provide an AppKeyset or Purpose.
+func NewManager[T any](cfg Config) *Manager[T] {
+	if cfg.AppKeyset == nil {
+		panic("urltoken: AppKeyset is required")
+	}
+	if cfg.Purpose == "" {
+		panic("urltoken: Purpose is required")
+	}
+	if cfg.TTL < 0 {
+		panic("urltoken: TTL must be positive")
+	}
+	if cfg.TTL == 0 {
+		cfg.TTL = 15 * time.Minute
+	}
+	if cfg.QueryParam == "" {
+		cfg.QueryParam = "token"
+	}
+	return &Manager[T]{
+		cfg:          cfg,
+		getKeyset:    cfg.AppKeyset.HKDF("urltoken:" + cfg.Purpose),
+		contextStore: contextutil.NewStore[T]("urltoken:" + cfg.Purpose),
+		now:          time.Now,
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// TOKENS
+/////////////////////////////////////////////////////////////////////
+
+// New returns a token carrying data (e.g., a user ID for a login link).
+func (m *Manager[T]) New(data T) (string, error) {
+	return m.newToken(data, nil)
+}
+
+// Verify checks the token and returns the data it carries. If the manager
+// has a Store, the token is used up.
+func (m *Manager[T]) Verify(ctx context.Context, token string) (T, error) {
+	return m.verify(ctx, token, nil)
+}
+
+func (m *Manager[T]) newToken(data T, urlHash []byte) (string, error) {
+	idBytes, err := cryptoutil.RandomBytes(idSize)
+	if err != nil {
+		return "", fmt.Errorf("urltoken: failed to generate token ID: %w", err)
+	}
+	sb, err := securebytes.Serialize(m.getKeyset(), payload[T]{
+		Purpose:   m.cfg.Purpose,
+		ID:        base64.RawURLEncoding.EncodeToString(idBytes),
+		ExpiresAt: m.now().Add(m.cfg.TTL).Unix(),
+		URLHash:   urlHash,
+		Data:      data,
+	})
+	if err != nil {
+		return "", fmt.Errorf("urltoken: failed to create token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(sb), nil
+}
+
+func (m *Manager[T]) verify(ctx context.Context, token string, urlHash []byte) (T, error) {
+	var zeroT T
+	sb, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(sb) == 0 {
+		return zeroT, ErrInvalidToken
+	}
+	p, err := securebytes.Parse[payload[T]](m.getKeyset(), securebytes.SecureBytes(sb))
+	if err != nil || p.Purpose != m.cfg.Purpose {
+		return zeroT, ErrInvalidToken
+	}
+	// Plain tokens can't be used as signed URLs, and vice versa
+	if urlHash == nil && p.URLHash != nil {
+		return zeroT, ErrInvalidToken
+	}
+	if urlHash != nil && subtle.ConstantTimeCompare(p.URLHash, urlHash) != 1 {
+		return zeroT, ErrInvalidToken
+	}
+	expiresAt := time.Unix(p.ExpiresAt, 0)
+	if !m.now().Before(expiresAt) {
+		return zeroT, ErrExpiredToken
+	}
+	if m.cfg.Store != nil {
+		ok, err := m.cfg.Store.Use(ctx, p.ID, expiresAt)
+		if err != nil {
+			return zeroT, fmt.Errorf("urltoken: failed to record token use: %w", err)
+		}
+		if !ok {
+			return zeroT, ErrUsedToken
+		}
+	}
+	return p.Data, nil
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// SIGNED URLS
+/////////////////////////////////////////////////////////////////////
+
+// SignURL returns rawURL with a token added as a query parameter. The token
+// carries data and is bound to the URL's path and every other query
+// parameter, so none of them can be changed. The scheme and host are not
+// bound, so relative URLs work, and signed URLs survive proxies.
+func (m *Manager[T]) SignURL(rawURL string, data T) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("urltoken: invalid URL: %w", err)
+	}
+	q := u.Query()
+	q.Del(m.cfg.QueryParam)
+	token, err := m.newToken(data, canonicalURLHash(u.Path, q))
+	if err != nil {
+		return "", err
+	}
+	q.Set(m.cfg.QueryParam, token)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// VerifyRequest checks the token in a request to a URL produced by SignURL,
+// and returns the data it carries. If the manager has a Store, the token is
+// used up.
+func (m *Manager[T]) VerifyRequest(r *http.Request) (T, error) {
+	q := r.URL.Query()
+	token := q.Get(m.cfg.QueryParam)
+	q.Del(m.cfg.QueryParam)
+	return m.verify(r.Context(), token, canonicalURLHash(r.URL.Path, q))
+}
+
+// Middleware rejects requests without a valid signed URL (see SignURL) with
+// a 403, and otherwise makes the token data available downstream via
+// FromContext. Suitable for mux.SetPatternLevelHTTPMiddleware.
+func (m *Manager[T]) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := m.VerifyRequest(r)
+		if err != nil {
+			http.Error(w, "Forbidden: invalid or expired link", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, m.contextStore.GetRequestWithContext(r, data))
+	})
+}
+
+// FromContext returns the token data stored by Middleware.
+func (m *Manager[T]) FromContext(ctx context.Context) T {
+	return m.contextStore.GetValueFromContext(ctx)
+}
+
+// url.Values.Encode sorts by key, so equivalent queries hash the same.
+func canonicalURLHash(path string, q url.Values) []byte {
+	sum := sha256.Sum256([]byte(path + "?" + q.Encode()))
+	return sum[:]
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// SINGLE-USE STORES
+/////////////////////////////////////////////////////////////////////
+
+// Store records used token IDs. Implementations must be safe for
+// concurrent use, and Use must be atomic (e.g., an INSERT that fails on a
+// unique constraint).
+type Store interface {
+	// Records id as used and returns true, unless it was already used, in
+	// which case it returns false. The record may be dropped after
+	// expiresAt, since the token can no longer be used anyway.
+	Use(ctx context.Context, id string, expiresAt time.Time) (bool, error)
+}
+
+// How often MemoryStore.Use drops expired records.
+const memoryStoreSweepInterval = time.Minute
+
+// An in-memory Store, suitable for tests and single-instance deployments.
+// Expired records are dropped periodically as new tokens are used.
+type MemoryStore struct {
+	mu        sync.Mutex
+	used      map[string]time.Time
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{used: make(map[string]time.Time), now: time.Now}
+}
+
+func (s *MemoryStore) Use(_ context.Context, id string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	// Sweeping is O(n), so do it at most once per interval rather than on
+	// every verification.
+	if !now.Before(s.nextSweep) {
+		for usedID, exp := range s.used {
+			if !now.Before(exp) {
+				delete(s.used, usedID)
+			}
+		}
+		s.nextSweep = now.Add(memoryStoreSweepInterval)
+	}
+	if exp, ok := s.used[id]; ok && now.Before(exp) {
+		return false, nil
+	}
+	s.used[id] = expiresAt
+	return true, nil
+}
@@ -0,0 +1,195 @@
+package urltoken
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/river-now/river/kit/keyset"
+)
+
+func newTestAppKeyset(t *testing.T) *keyset.AppKeyset {
+	t.Helper()
+	t.Setenv("URLTOKEN_TEST_SECRET", base64.StdEncoding.EncodeToString([]byte("12345678901234567890123456789012")))
+	return keyset.MustAppKeyset(keyset.AppKeysetConfig{
+		LatestFirstEnvVarNames: []string{"URLTOKEN_TEST_SECRET"},
+		ApplicationName:        "urltoken-test",
+	})
+}
+
+func TestTokens(t *testing.T) {
+	ctx := context.Background()
+	appKeyset := newTestAppKeyset(t)
+	m := NewManager[string](Config{AppKeyset: appKeyset, Purpose: "magic_link", TTL: time.Minute})
+	now := time.Now()
+	m.now = func() time.Time { return now }
+
+	token, err := m.New("user-1")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if token != url.QueryEscape(token) {
+		t.Errorf("token %q is not URL-safe", token)
+	}
+
+	t.Run("Verify", func(t *testing.T) {
+		data, err := m.Verify(ctx, token)
+		if err != nil || data != "user-1" {
+			t.Errorf("Verify() = %q, %v; want user-1, nil", data, err)
+		}
+		// Reusable without a Store
+		if _, err := m.Verify(ctx, token); err != nil {
+			t.Errorf("second Verify() error = %v", err)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		defer func() { now = now.Add(-time.Minute) }()
+		if _, err := m.Verify(ctx, token); !errors.Is(err, ErrExpiredToken) {
+			t.Errorf("expected ErrExpiredToken, got %v", err)
+		}
+	})
+
+	t.Run("WrongPurpose", func(t *testing.T) {
+		other := NewManager[string](Config{AppKeyset: appKeyset, Purpose: "download"})
+		if _, err := other.Verify(ctx, token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		b, _ := base64.RawURLEncoding.DecodeString(token)
+		b[len(b)-1] ^= 1
+		for _, bad := range []string{"", "not base64!", base64.RawURLEncoding.EncodeToString(b)} {
+			if _, err := m.Verify(ctx, bad); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Verify(%q): expected ErrInvalidToken, got %v", bad, err)
+			}
+		}
+	})
+
+	t.Run("SingleUse", func(t *testing.T) {
+		single := NewManager[string](Config{AppKeyset: appKeyset, Purpose: "magic_link", Store: NewMemoryStore()})
+		token, _ := single.New("user-1")
+		if _, err := single.Verify(ctx, token); err != nil {
+			t.Fatalf("first Verify() error = %v", err)
+		}
+		if _, err := single.Verify(ctx, token); !errors.Is(err, ErrUsedToken) {
+			t.Errorf("expected ErrUsedToken, got %v", err)
+		}
+	})
+
+	t.Run("InvalidConfig", func(t *testing.T) {
+		for _, cfg := range []Config{{Purpose: "x"}, {AppKeyset: appKeyset}, {AppKeyset: appKeyset, Purpose: "x", TTL: -1}} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("expected panic for %+v", cfg)
+					}
+				}()
+				NewManager[string](cfg)
+			}()
+		}
+	})
+}
+
+type download struct {
+	FileID string
+}
+
+func TestSignedURLs(t *testing.T) {
+	m := NewManager[download](Config{AppKeyset: newTestAppKeyset(t), Purpose: "download", Store: NewMemoryStore()})
+
+	signed, err := m.SignURL("/files/report.pdf?disposition=attachment", download{FileID: "f1"})
+	if err != nil {
+		t.Fatalf("SignURL() error = %v", err)
+	}
+
+	var got download
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = m.FromContext(r.Context())
+	}))
+	serve := func(target string) int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w.Code
+	}
+
+	t.Run("Tampered", func(t *testing.T) {
+		u, _ := url.Parse(signed)
+		q := u.Query()
+		q.Set("disposition", "inline")
+		u.RawQuery = q.Encode()
+		otherPath := *u
+		otherPath.Path = "/files/secret.pdf"
+		q.Set("disposition", "attachment")
+		otherPath.RawQuery = q.Encode()
+		for _, target := range []string{u.String(), otherPath.String(), "/files/report.pdf?disposition=attachment"} {
+			if code := serve(target); code != http.StatusForbidden {
+				t.Errorf("%s: status = %d, want 403", target, code)
+			}
+		}
+	})
+
+	t.Run("PlainTokenRejected", func(t *testing.T) {
+		token, _ := m.New(download{FileID: "f1"})
+		if code := serve("/files/report.pdf?disposition=attachment&token=" + token); code != http.StatusForbidden {
+			t.Errorf("status = %d, want 403", code)
+		}
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		// Query parameter order doesn't matter
+		u, _ := url.Parse(signed)
+		reordered := "/files/report.pdf?token=" + u.Query().Get("token") + "&disposition=attachment"
+		if code := serve(reordered); code != http.StatusOK || got.FileID != "f1" {
+			t.Errorf("status = %d, data = %+v; want 200 and f1", code, got)
+		}
+		if code := serve(signed); code != http.StatusForbidden {
+			t.Errorf("reused single-use URL: status = %d, want 403", code)
+		}
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	use := func(id string, ttl time.Duration) bool {
+		t.Helper()
+		ok, err := s.Use(ctx, id, now.Add(ttl))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+
+	if !use("a", time.Second) || use("a", time.Second) {
+		t.Fatal("expected the first use to succeed and the second to fail")
+	}
+	use("b", time.Hour)
+
+	// Expired records aren't swept until the interval has passed...
+	now = now.Add(2 * time.Second)
+	use("c", time.Hour)
+	if _, ok := s.used["a"]; !ok {
+		t.Error("expected no sweep before the interval")
+	}
+	// ...but they no longer count as used in the meantime
+	if !use("a", time.Hour) {
+		t.Error("expected an expired record to be reusable")
+	}
+
+	now = now.Add(memoryStoreSweepInterval + 2*time.Hour)
+	use("d", time.Hour)
+	if len(s.used) != 1 {
+		t.Errorf("expected only the new record after a sweep, got %v", s.used)
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"slices"
 	"strings"
 
+	"github.com/river-now/river/kit/bytesutil"
+	"github.com/river-now/river/kit/cryptoutil"
 	"github.com/river-now/river/kit/htmlutil"
 	"github.com/river-now/river/kit/netutil"
 	"github.com/river-now/river/kit/stringsutil"
@@ -111,18 +113,10 @@ func ToDevScripts(options ToDevScriptsOptions) (template.HTML, error) {
 	port := GetVitePortStr()
 
 	if options.Variant == Variants.React {
-		var b stringsutil.Builder
-
-		b.Linef(`import RefreshRuntime from "http://localhost:%s/@react-refresh";`, port)
-		b.Line("RefreshRuntime.injectIntoGlobalHook(window);")
-		b.Line("window.$RefreshReg$ = () => {};")
-		b.Line("window.$RefreshSig$ = () => (type) => type;")
-		b.Line("window.__vite_plugin_react_preamble_installed__ = true;")
-
 		err = htmlutil.RenderElementToBuilder(&htmlutil.Element{
 			Tag:                 "script",
 			AttributesKnownSafe: map[string]string{"type": "module"},
-			DangerousInnerHTML:  b.String(),
+			DangerousInnerHTML:  reactRefreshPreamble(port),
 		}, &htmlBuilder)
 		if err != nil {
 			return "", fmt.Errorf("could not render vite script: %w", err)
@@ -151,6 +145,38 @@ func ToDevScripts(options ToDevScriptsOptions) (template.HTML, error) {
 	return template.HTML(htmlBuilder.String()), nil
 }
 
+func reactRefreshPreamble(port string) string {
+	var b stringsutil.Builder
+	b.Linef(`import RefreshRuntime from "http://localhost:%s/@react-refresh";`, port)
+	b.Line("RefreshRuntime.injectIntoGlobalHook(window);")
+	b.Line("window.$RefreshReg$ = () => {};")
+	b.Line("window.$RefreshSig$ = () => (type) => type;")
+	b.Line("window.__vite_plugin_react_preamble_installed__ = true;")
+	return b.String()
+}
+
+// ToDevCSPSources returns the Content-Security-Policy sources, by directive,
+// that the scripts from ToDevScripts need: the Vite dev server's origin (and
+// its HMR websocket) and, for the React variant, the hash of the inline
+// preamble. Note that Vite also injects CSS as <style> tags without a nonce,
+// which no strict policy allows, so send the policy report-only in dev.
+func ToDevCSPSources(variant Variant) map[string][]string {
+	port := GetVitePortStr()
+	origin := "http://localhost:" + port
+	sources := map[string][]string{
+		"script-src":  {origin},
+		"style-src":   {origin},
+		"img-src":     {origin},
+		"font-src":    {origin},
+		"connect-src": {origin, "ws://localhost:" + port},
+	}
+	if variant == Variants.React {
+		hash := cryptoutil.Sha256Hash([]byte(reactRefreshPreamble(port)))
+		sources["script-src"] = append(sources["script-src"], "'sha256-"+bytesutil.ToBase64(hash)+"'")
+	}
+	return sources
+}
+
 func stripPrecedingSlash(s string) string {
 	if strings.HasPrefix(s, "/") {
 		return s[1:]
@@ -4,6 +4,7 @@ import (
 	"app"
 
 	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/river-now/river/kit/middleware/compress"
 	"github.com/river-now/river/kit/middleware/etag"
 	"github.com/river-now/river/kit/middleware/healthcheck"
 	"github.com/river-now/river/kit/middleware/robotstxt"
@@ -16,8 +17,9 @@ func Core() *mux.Router {
 
 	mux.SetGlobalHTTPMiddleware(r, chimw.Logger)
 	mux.SetGlobalHTTPMiddleware(r, chimw.Recoverer)
+	mux.SetGlobalHTTPMiddleware(r, app.Wave.ReportPanicsToDevOverlay())
 	mux.SetGlobalHTTPMiddleware(r, etag.Auto())
-	mux.SetGlobalHTTPMiddleware(r, chimw.Compress(5))
+	mux.SetGlobalHTTPMiddleware(r, compress.Auto())
 	mux.SetGlobalHTTPMiddleware(r, app.Wave.ServeStatic(true))
 	mux.SetGlobalHTTPMiddleware(r, secureheaders.Middleware)
 	mux.SetGlobalHTTPMiddleware(r, healthcheck.Healthz)
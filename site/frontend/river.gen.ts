@@ -151,9 +151,13 @@ declare global {
 
 export const publicPathPrefix = "/";
 
+export function publicURLBase(): string {
+	return (globalThis as any)[Symbol.for("__river_internal__")]?.publicPathPrefix ?? publicPathPrefix;
+}
+
 export function waveRuntimeURL(originalPublicURL: keyof typeof staticPublicAssetMap) {
 	const url = staticPublicAssetMap[originalPublicURL] ?? originalPublicURL;
-	return publicPathPrefix + url;
+	return publicURLBase() + url;
 }
 
 export function riverVitePlugin(): Plugin {
@@ -169,7 +173,10 @@ export function riverVitePlugin(): Plugin {
 
 			return {
 				...c,
-				base: isDev ? "/" : "/",
+				// Relative, so the built chunks load each other (and
+				// their assets) from wherever the entry was loaded from,
+				// which keeps the public URL base a runtime setting.
+				base: isDev ? "/" : "./",
 				build: {
 					target: "es2022",
 					...c.build,
@@ -230,7 +237,7 @@ export function riverVitePlugin(): Plugin {
 				(_, __, assetPath) => {
 					const hashed = (staticPublicAssetMap as Record<string, string>)[assetPath];
 					if (!hashed) return `"${assetPath}"`;
-					return `"/${hashed}"`;
+					return `((globalThis[Symbol.for("__river_internal__")]?.publicPathPrefix ?? "/") + "${hashed}")`;
 				},
 			);
 			if (replacedCode === code) return null;
@@ -0,0 +1,53 @@
+// Command wave provides standalone Wave tooling.
+//
+// Usage:
+//
+//	wave check [-config wave.config.json] [-root .]
+//
+// "check" validates a Wave config file against the config schema, resolves
+// every referenced path, and validates every glob pattern, printing each
+// problem as "<JSON pointer>: <message>". Exits with status 1 if any problems
+// are found.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/river-now/river/wave"
+)
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "check" {
+		fmt.Fprintln(os.Stderr, "usage: wave check [-config wave.config.json] [-root dir]")
+		os.Exit(2)
+	}
+
+	fset := flag.NewFlagSet("check", flag.ExitOnError)
+	configFile := fset.String("config", "wave.config.json", "path to the Wave config file")
+	rootDir := fset.String("root", "", "directory that config paths are relative to (defaults to the config file's directory)")
+	fset.Parse(os.Args[2:])
+
+	configBytes, err := os.ReadFile(*configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading config file: %v\n", err)
+		os.Exit(1)
+	}
+
+	root := *rootDir
+	if root == "" {
+		root = filepath.Dir(*configFile)
+	}
+
+	problems := wave.CheckConfig(configBytes, root)
+	for _, p := range problems {
+		fmt.Fprintln(os.Stderr, p.Error())
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "%s: %d problem(s) found\n", *configFile, len(problems))
+		os.Exit(1)
+	}
+	fmt.Printf("%s: OK\n", *configFile)
+}
@@ -0,0 +1,218 @@
+package ki
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/river-now/river/kit/middleware/compress"
+)
+
+// A listing of every file emitted into the public dist directory, written at
+// prod build time to dist/static/internal/asset_manifest.json for use by
+// external deploy tooling (e.g., to upload only changed assets to object
+// storage and invalidate the matching CDN paths).
+type AssetManifest struct {
+	Assets []AssetManifestEntry `json:"assets"` // Sorted by HashedPath
+}
+
+type AssetManifestEntry struct {
+	// Path as referenced in source (e.g., "images/logo.png"). For files that
+	// Wave did not hash itself (e.g., Vite output), same as HashedPath.
+	OriginalPath string `json:"originalPath"`
+	// Path relative to the public dist directory (and to the public path
+	// prefix or asset base URL), e.g., "images/logo_abc123.png".
+	HashedPath      string `json:"hashedPath"`
+	Size            int64  `json:"size"`
+	ContentType     string `json:"contentType,omitempty"`
+	ContentEncoding string `json:"contentEncoding,omitempty"` // Set for precompressed siblings ("br" or "gzip")
+	SHA256          string `json:"sha256"`                    // Hex-encoded
+}
+
+type AssetManifestDiff struct {
+	Added   []AssetManifestEntry `json:"added"`
+	Removed []AssetManifestEntry `json:"removed"`
+	// Entries present in both manifests (by HashedPath) whose content
+	// differs. Holds the new entries.
+	Changed []AssetManifestEntry `json:"changed"`
+}
+
+func (d AssetManifestDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+func (c *Config) writeAssetManifest() error {
+	fileMap, err := c.loadMapFromGob(PublicFileMapGobName, true)
+	if err != nil {
+		return fmt.Errorf("error loading public file map: %w", err)
+	}
+	originalByHashed := make(map[string]string, len(fileMap))
+	for original, v := range fileMap {
+		if !v.IsPrehashed {
+			originalByHashed[v.Val] = original
+		}
+	}
+
+	publicOutDir := c.GetStaticPublicOutDir()
+
+	manifest := AssetManifest{Assets: []AssetManifestEntry{}}
+
+	err = filepath.WalkDir(publicOutDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		relativePath, err := filepath.Rel(publicOutDir, p)
+		if err != nil {
+			return err
+		}
+		relativePath = filepath.ToSlash(relativePath)
+
+		// Only treat ".br"/".gz"/etc. files as precompressed siblings if the
+		// uncompressed file is there too (e.g., not "archive.tar.gz")
+		isSibling := false
+		if isPrecompressedSibling(p) {
+			_, err := os.Stat(strings.TrimSuffix(p, filepath.Ext(p)))
+			isSibling = err == nil
+		}
+
+		entry, err := toAssetManifestEntry(p, relativePath, isSibling)
+		if err != nil {
+			return err
+		}
+
+		uncompressedPath := relativePath
+		if entry.ContentEncoding != "" {
+			uncompressedPath = strings.TrimSuffix(relativePath, path.Ext(relativePath))
+		}
+		entry.OriginalPath = uncompressedPath
+		if original, ok := originalByHashed[uncompressedPath]; ok {
+			entry.OriginalPath = original
+		}
+		if entry.ContentEncoding != "" {
+			entry.OriginalPath += path.Ext(relativePath)
+		}
+
+		manifest.Assets = append(manifest.Assets, entry)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error walking public dist dir: %w", err)
+	}
+
+	slices.SortFunc(manifest.Assets, func(a, b AssetManifestEntry) int {
+		return strings.Compare(a.HashedPath, b.HashedPath)
+	})
+
+	bytes, err := json.MarshalIndent(manifest, "", "\t")
+	if err != nil {
+		return fmt.Errorf("error marshalling asset manifest: %w", err)
+	}
+	return os.WriteFile(c._dist.S().Static.S().Internal.S().AssetManifestDotJSON.FullPath(), bytes, 0644)
+}
+
+func toAssetManifestEntry(fullPath, relativePath string, isPrecompressedSibling bool) (AssetManifestEntry, error) {
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return AssetManifestEntry{}, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return AssetManifestEntry{}, err
+	}
+
+	entry := AssetManifestEntry{
+		HashedPath: relativePath,
+		Size:       size,
+		SHA256:     hex.EncodeToString(h.Sum(nil)),
+	}
+
+	ext := path.Ext(relativePath)
+	if isPrecompressedSibling {
+		for _, encoding := range precompressedEncodings {
+			if ext == compress.FileExtension(encoding) {
+				entry.ContentEncoding = encoding
+			}
+		}
+		ext = path.Ext(strings.TrimSuffix(relativePath, ext))
+	}
+	entry.ContentType = mime.TypeByExtension(ext)
+
+	return entry, nil
+}
+
+// Returns the on-disk path of the asset manifest written by the latest prod
+// build.
+func (c *Config) GetAssetManifestPath() string {
+	return c._dist.S().Static.S().Internal.S().AssetManifestDotJSON.FullPath()
+}
+
+func ReadAssetManifest(r io.Reader) (*AssetManifest, error) {
+	var manifest AssetManifest
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("error decoding asset manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+func LoadAssetManifest(filePath string) (*AssetManifest, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening asset manifest: %w", err)
+	}
+	defer f.Close()
+	return ReadAssetManifest(f)
+}
+
+// Compares two asset manifests by hashed path. Either may be nil (e.g., for
+// a first deploy, pass nil as prev). Each list in the result is sorted by
+// HashedPath.
+func DiffAssetManifests(prev, next *AssetManifest) AssetManifestDiff {
+	prevByPath := map[string]AssetManifestEntry{}
+	if prev != nil {
+		for _, e := range prev.Assets {
+			prevByPath[e.HashedPath] = e
+		}
+	}
+
+	var diff AssetManifestDiff
+	seen := map[string]struct{}{}
+	if next != nil {
+		for _, e := range next.Assets {
+			seen[e.HashedPath] = struct{}{}
+			old, ok := prevByPath[e.HashedPath]
+			switch {
+			case !ok:
+				diff.Added = append(diff.Added, e)
+			case old.SHA256 != e.SHA256:
+				diff.Changed = append(diff.Changed, e)
+			}
+		}
+	}
+	for p, e := range prevByPath {
+		if _, ok := seen[p]; !ok {
+			diff.Removed = append(diff.Removed, e)
+		}
+	}
+
+	byHashedPath := func(a, b AssetManifestEntry) int { return strings.Compare(a.HashedPath, b.HashedPath) }
+	slices.SortFunc(diff.Added, byHashedPath)
+	slices.SortFunc(diff.Removed, byHashedPath)
+	slices.SortFunc(diff.Changed, byHashedPath)
+
+	return diff
+}
@@ -0,0 +1,91 @@
+package ki
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestAssetManifest(t *testing.T) {
+	env := setupTestEnv(t)
+	defer teardownTestEnv(t)
+
+	env.createTestFile(t, "public-static/app.js", "console.log('app');")
+	if err := env.config.handlePublicFiles(false); err != nil {
+		t.Fatalf("handlePublicFiles() error = %v", err)
+	}
+
+	fileMap, err := env.config.loadMapFromGob(PublicFileMapGobName, true)
+	if err != nil {
+		t.Fatalf("loadMapFromGob() error = %v", err)
+	}
+	hashedApp := fileMap["app.js"].Val
+
+	env.createTestFile(t, "dist/static/assets/public/"+hashedApp+".br", "brotli")
+	env.createTestFile(t, "dist/static/assets/public/river_out_chunk_abc.js", "export {};")
+	env.createTestFile(t, "dist/static/assets/public/archive.tar.gz", "not a sibling")
+
+	if err := env.config.writeAssetManifest(); err != nil {
+		t.Fatalf("writeAssetManifest() error = %v", err)
+	}
+	manifest, err := LoadAssetManifest(env.config.GetAssetManifestPath())
+	if err != nil {
+		t.Fatalf("LoadAssetManifest() error = %v", err)
+	}
+
+	byHashed := map[string]AssetManifestEntry{}
+	for _, e := range manifest.Assets {
+		byHashed[e.HashedPath] = e
+	}
+
+	t.Run("HashedFile", func(t *testing.T) {
+		e := byHashed[hashedApp]
+		sum := sha256.Sum256([]byte("console.log('app');"))
+		if e.OriginalPath != "app.js" || e.Size != int64(len("console.log('app');")) ||
+			e.SHA256 != hex.EncodeToString(sum[:]) || !strings.Contains(e.ContentType, "javascript") {
+			t.Errorf("entry = %+v", e)
+		}
+	})
+
+	t.Run("PrecompressedSibling", func(t *testing.T) {
+		e := byHashed[hashedApp+".br"]
+		if e.OriginalPath != "app.js.br" || e.ContentEncoding != "br" || !strings.Contains(e.ContentType, "javascript") {
+			t.Errorf("entry = %+v", e)
+		}
+	})
+
+	t.Run("UnmappedFiles", func(t *testing.T) {
+		if e := byHashed["river_out_chunk_abc.js"]; e.OriginalPath != "river_out_chunk_abc.js" {
+			t.Errorf("entry = %+v", e)
+		}
+		if e := byHashed["archive.tar.gz"]; e.ContentEncoding != "" {
+			t.Errorf("archive.tar.gz should not be treated as a precompressed sibling: %+v", e)
+		}
+	})
+
+	t.Run("Diff", func(t *testing.T) {
+		prev := &AssetManifest{Assets: []AssetManifestEntry{
+			{HashedPath: "a_1.js", SHA256: "1"},
+			{HashedPath: "keep.txt", SHA256: "k"},
+			{HashedPath: "robots.txt", SHA256: "old"},
+		}}
+		next := &AssetManifest{Assets: []AssetManifestEntry{
+			{HashedPath: "a_2.js", SHA256: "2"},
+			{HashedPath: "keep.txt", SHA256: "k"},
+			{HashedPath: "robots.txt", SHA256: "new"},
+		}}
+		diff := DiffAssetManifests(prev, next)
+		if len(diff.Added) != 1 || diff.Added[0].HashedPath != "a_2.js" ||
+			len(diff.Removed) != 1 || diff.Removed[0].HashedPath != "a_1.js" ||
+			len(diff.Changed) != 1 || diff.Changed[0].SHA256 != "new" {
+			t.Errorf("diff = %+v", diff)
+		}
+		if !DiffAssetManifests(next, next).IsEmpty() {
+			t.Error("diffing a manifest against itself should be empty")
+		}
+		if got := DiffAssetManifests(nil, manifest); len(got.Added) != len(manifest.Assets) {
+			t.Errorf("diff against nil should add everything, got %+v", got)
+		}
+	})
+}
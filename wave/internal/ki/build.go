@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/fs"
+	"maps"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -116,6 +118,20 @@ func (c *Config) Build(opts BuildOptions) error {
 		return fmt.Errorf("error processing build time files: %w", err)
 	}
 
+	if !opts.IsDev && c.is_using_browser() {
+		if err := c.addSRIForUnmappedPublicAssets(); err != nil {
+			return fmt.Errorf("error adding SRI values for public assets: %w", err)
+		}
+		if c.is_precompression_enabled() {
+			if err := c.precompressPublicAssets(); err != nil {
+				return fmt.Errorf("error precompressing public assets: %w", err)
+			}
+		}
+		if err := c.writeAssetManifest(); err != nil {
+			return fmt.Errorf("error writing asset manifest: %w", err)
+		}
+	}
+
 	err = configschema.Write(filepath.Join(
 		c._dist.S().Static.S().Internal.FullPath(),
 		"schema.json",
@@ -157,6 +173,12 @@ func (c *Config) buildCSS() error {
 		return fmt.Errorf("error processing normal CSS: %w", err)
 	}
 
+	for _, name := range slices.Sorted(maps.Keys(c.cleanSources.NamedCSSEntries)) {
+		if err := c.processCSSNamed(name); err != nil {
+			return fmt.Errorf("error processing %s CSS: %w", name, err)
+		}
+	}
+
 	return nil
 }
 
@@ -169,6 +191,7 @@ var (
 	cssImportURLsMu         *sync.RWMutex  = &sync.RWMutex{}
 	criticalReliedUponFiles                = map[string]struct{}{}
 	normalReliedUponFiles                  = map[string]struct{}{}
+	namedReliedUponFiles                   = map[string]map[string]struct{}{}
 	esbuildCtxCritical      esbuildCtxSafe = esbuildCtxSafe{}
 	esbuildCtxNormal        esbuildCtxSafe = esbuildCtxSafe{}
 )
@@ -176,12 +199,22 @@ var (
 func (c *Config) processCSSCritical() error { return c.__processCSS("critical") }
 func (c *Config) processCSSNormal() error   { return c.__processCSS("normal") }
 
-// nature = "critical" or "normal"
-func (c *Config) __processCSS(nature string) error {
-	entryPoint := c.cleanSources.NonCriticalCSSEntry
-	if nature == "critical" {
-		entryPoint = c.cleanSources.CriticalCSSEntry
+func (c *Config) processCSSNamed(name string) error { return c.__processCSS(name) }
+
+func (c *Config) getCSSEntryPoint(nature string) string {
+	switch nature {
+	case "critical":
+		return c.cleanSources.CriticalCSSEntry
+	case "normal":
+		return c.cleanSources.NonCriticalCSSEntry
+	default:
+		return c.cleanSources.NamedCSSEntries[nature]
 	}
+}
+
+// nature = "critical", "normal", or the name of a named CSS entry
+func (c *Config) __processCSS(nature string) error {
+	entryPoint := c.getCSSEntryPoint(nature)
 
 	if entryPoint == "" {
 		return nil
@@ -189,6 +222,8 @@ func (c *Config) __processCSS(nature string) error {
 
 	isDev := GetIsDev()
 
+	cssModuleClasses := cssModuleClassMap{}
+
 	ctx, ctxErr := esbuild.Context(esbuild.BuildOptions{
 		EntryPoints:       []string{entryPoint},
 		Bundle:            true,
@@ -197,7 +232,7 @@ func (c *Config) __processCSS(nature string) error {
 		MinifySyntax:      !isDev,
 		Write:             false,
 		Metafile:          true,
-		Plugins: []esbuild.Plugin{
+		Plugins: append([]esbuild.Plugin{
 			{
 				Name: "url-resolver",
 				Setup: func(build esbuild.PluginBuild) {
@@ -215,8 +250,11 @@ func (c *Config) __processCSS(nature string) error {
 									return esbuild.OnResolveResult{}, nil
 								}
 
+								// Relative to the public root, where the bundles
+								// live, so the asset base stays a runtime setting
+								// (see ResolveCSSURLs for inlined CSS).
 								return esbuild.OnResolveResult{
-									Path:     c.MustGetPublicURLBuildtime(args.Path),
+									Path:     c.mustGetPublicPathBuildtime(args.Path),
 									External: true,
 								}, nil
 							}
@@ -225,7 +263,7 @@ func (c *Config) __processCSS(nature string) error {
 					)
 				},
 			},
-		},
+		}, c.css_modules_plugins(cssModuleClasses)...),
 	})
 	if ctxErr != nil {
 		return fmt.Errorf("error creating esbuild context: %v", ctxErr.Errors)
@@ -235,10 +273,12 @@ func (c *Config) __processCSS(nature string) error {
 		esbuildCtxCritical.mu.Lock()
 		esbuildCtxCritical.ctx = ctx
 		esbuildCtxCritical.mu.Unlock()
-	} else {
+	} else if nature == "normal" {
 		esbuildCtxNormal.mu.Lock()
 		esbuildCtxNormal.ctx = ctx
 		esbuildCtxNormal.mu.Unlock()
+	} else {
+		defer ctx.Dispose()
 	}
 
 	result := ctx.Rebuild()
@@ -246,24 +286,39 @@ func (c *Config) __processCSS(nature string) error {
 		return fmt.Errorf("error building CSS: %w", err)
 	}
 
+	if c._uc.Core.CSSModules.Pattern != "" {
+		if err := c.save_css_modules(nature, cssModuleClasses); err != nil {
+			return fmt.Errorf("error saving CSS modules map: %w", err)
+		}
+	}
+
+	css, err := c.run_css_transforms(result.OutputFiles[0].Contents, nature)
+	if err != nil {
+		return fmt.Errorf("error transforming CSS: %w", err)
+	}
+
 	var metafile esbuildutil.ESBuildMetafileSubset
 	if err := json.Unmarshal([]byte(result.Metafile), &metafile); err != nil {
 		return fmt.Errorf("error unmarshalling esbuild metafile: %w", err)
 	}
 
-	srcURL := c.cleanSources.NonCriticalCSSEntry
-	if nature == "critical" {
-		srcURL = c.cleanSources.CriticalCSSEntry
-	}
+	srcURL := entryPoint
 
 	imports := metafile.Inputs[srcURL].Imports
 
 	cssImportURLsMu.Lock()
 
-	if nature == "critical" {
+	var reliedUponFiles map[string]struct{}
+	switch nature {
+	case "critical":
 		criticalReliedUponFiles = map[string]struct{}{}
-	} else {
+		reliedUponFiles = criticalReliedUponFiles
+	case "normal":
 		normalReliedUponFiles = map[string]struct{}{}
+		reliedUponFiles = normalReliedUponFiles
+	default:
+		namedReliedUponFiles[nature] = map[string]struct{}{}
+		reliedUponFiles = namedReliedUponFiles[nature]
 	}
 
 	for _, imp := range imports {
@@ -271,11 +326,7 @@ func (c *Config) __processCSS(nature string) error {
 			continue
 		}
 
-		if nature == "critical" {
-			criticalReliedUponFiles[imp.Path] = struct{}{}
-		} else {
-			normalReliedUponFiles[imp.Path] = struct{}{}
-		}
+		reliedUponFiles[imp.Path] = struct{}{}
 	}
 
 	cssImportURLsMu.Unlock()
@@ -286,7 +337,7 @@ func (c *Config) __processCSS(nature string) error {
 	switch nature {
 	case "critical":
 		outputPath = c._dist.S().Static.S().Internal.FullPath()
-	case "normal":
+	default:
 		outputPath = c._dist.S().Static.S().Assets.S().Public.FullPath()
 	}
 
@@ -304,9 +355,11 @@ func (c *Config) __processCSS(nature string) error {
 				return fmt.Errorf("error removing old normal CSS file: %w", err)
 			}
 		}
+	}
 
+	if nature != "critical" {
 		// Hash the css output
-		outputFileName = getHashedFilename(result.OutputFiles[0].Contents, "normal.css")
+		outputFileName = getHashedFilename(css, nature+".css")
 	}
 
 	// Ensure output directory exists
@@ -325,7 +378,15 @@ func (c *Config) __processCSS(nature string) error {
 		}
 	}
 
-	return os.WriteFile(outputFile, result.OutputFiles[0].Contents, 0644)
+	// If named, record the hashed filename in named_css_file_refs.json (this
+	// also removes the entry's previous output file)
+	if nature != "critical" && nature != "normal" {
+		if err := c.saveNamedCSSRef(nature, outputFileName); err != nil {
+			return fmt.Errorf("error saving named CSS file ref: %w", err)
+		}
+	}
+
+	return os.WriteFile(outputFile, css, 0644)
 }
 
 type staticFileProcessorOpts struct {
@@ -415,11 +476,24 @@ func (c *Config) processStaticFiles(opts *staticFileProcessorOpts) error {
 	}
 
 	fileChan := make(chan fileInfo, 100)
-	errChan := make(chan error, 1)
 	var wg sync.WaitGroup
 
+	// The first error wins and stops discovery; workers then drain the
+	// channel without processing, so every goroutine exits before we return.
+	done := make(chan struct{})
+	var firstErr error
+	var failOnce sync.Once
+	fail := func(err error) {
+		failOnce.Do(func() {
+			firstErr = err
+			close(done)
+		})
+	}
+
 	// File discovery goroutine
+	wg.Add(1)
 	go func() {
+		defer wg.Done()
 		defer close(fileChan)
 		err := filepath.WalkDir(opts.srcDir, func(path string, d fs.DirEntry, err error) error {
 			if err != nil {
@@ -438,12 +512,16 @@ func (c *Config) processStaticFiles(opts *staticFileProcessorOpts) error {
 				if _, isIgnore := STATIC_FILENAMES_IGNORE_LIST[filepath.Base(relativePath)]; isIgnore {
 					return nil
 				}
-				fileChan <- fileInfo{path: path, relativePath: relativePath, isNoHashDir: isNoHashDir}
+				select {
+				case fileChan <- fileInfo{path: path, relativePath: relativePath, isNoHashDir: isNoHashDir}:
+				case <-done:
+					return filepath.SkipAll
+				}
 			}
 			return nil
 		})
 		if err != nil {
-			errChan <- err
+			fail(err)
 		}
 	}()
 
@@ -454,21 +532,21 @@ func (c *Config) processStaticFiles(opts *staticFileProcessorOpts) error {
 		go func() {
 			defer wg.Done()
 			for fi := range fileChan {
+				select {
+				case <-done:
+					continue
+				default:
+				}
 				if err := c.processFile(fi, opts, &newFileMap, &oldFileMap, opts.distDir); err != nil {
-					errChan <- err
-					return
+					fail(err)
 				}
 			}
 		}()
 	}
 
-	go func() {
-		wg.Wait()
-		close(errChan)
-	}()
-
-	if err := <-errChan; err != nil {
-		return err
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
 	}
 
 	// Cleanup old moot files if granular updates are enabled
@@ -527,16 +605,37 @@ func (c *Config) processFile(
 		fileIdentifier.Val = fi.relativePath
 		fileIdentifier.IsPrehashed = true
 	} else {
-		var err error
-		name, err := getHashedFilenameFromPath(fi.path, relativePathUnderscores)
+		name, sri, err := getHashedFilenameFromPath(fi.path, relativePathUnderscores)
 		if err != nil {
 			return fmt.Errorf("error getting hashed filename: %w", err)
 		}
 		fileIdentifier.Val = name
+		if opts.basename == PUBLIC {
+			fileIdentifier.SRI = sri
+		}
+	}
+
+	isImageWithVariants := opts.basename == PUBLIC &&
+		!fi.isNoHashDir &&
+		c.is_image_processing_enabled() &&
+		imageFormatFromPath(fi.relativePath) != ""
+
+	if isImageWithVariants {
+		width, err := getImageWidth(fi.path)
+		if err != nil {
+			return fmt.Errorf("error reading image dimensions for %s: %w", fi.relativePath, err)
+		}
+		fileIdentifier.Width = width
 	}
 
 	newFileMap.Store(fi.relativePath, fileIdentifier)
 
+	if isImageWithVariants {
+		if err := c.processImageVariants(fi, fileIdentifier, opts, newFileMap, oldFileMap, distDir); err != nil {
+			return err
+		}
+	}
+
 	// Skip unchanged files if granular updates are enabled
 	if opts.is_dev_rebuild {
 		if oldHash, exists := oldFileMap.Load(fi.relativePath); exists && oldHash == fileIdentifier {
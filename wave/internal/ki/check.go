@@ -0,0 +1,236 @@
+package ki
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/river-now/river/kit/jsonschema"
+	"github.com/river-now/river/wave/internal/ki/configschema"
+)
+
+// A single problem found by CheckConfig. Pointer is a JSON pointer (e.g.,
+// "/Watch/Include/0/Pattern") to the offending value in the config file.
+type ConfigProblem = jsonschema.ValidationError
+
+// Validates a Wave config file against the config schema, then checks that
+// every referenced path exists (relative to rootDir, i.e., the directory you
+// run Wave commands from) and that every glob pattern is valid. Each overlay
+// in the config's Env section is also checked, as merged over the base
+// config. Reports all problems in one pass. Returns nil if the config is
+// valid.
+func CheckConfig(configBytes []byte, rootDir string) []ConfigProblem {
+	var raw any
+	if err := json.Unmarshal(configBytes, &raw); err != nil {
+		return []ConfigProblem{{Message: describe_json_error(configBytes, err)}}
+	}
+
+	problems := check_config(raw, configBytes, rootDir)
+
+	rawObj, _ := raw.(map[string]any)
+	envSection, _ := rawObj[envSectionKey].(map[string]any)
+	if len(envSection) == 0 {
+		return problems
+	}
+
+	// Only report overlay problems that the base config doesn't already have
+	seen := make(map[ConfigProblem]bool, len(problems))
+	for _, p := range problems {
+		seen[p] = true
+	}
+	for _, env := range sorted_keys(envSection) {
+		pointer := "/" + envSectionKey + "/" + jsonschema.EscapePointerToken(env)
+		resolved, err := resolveConfigBytes(configBytes, env, nil)
+		if err != nil {
+			problems = append(problems, ConfigProblem{Pointer: pointer, Message: err.Error()})
+			continue
+		}
+		var resolvedRaw any
+		if err := json.Unmarshal(resolved, &resolvedRaw); err != nil {
+			problems = append(problems, ConfigProblem{Pointer: pointer, Message: err.Error()})
+			continue
+		}
+		for _, p := range check_config(resolvedRaw, resolved, rootDir) {
+			if !seen[p] {
+				problems = append(problems, ConfigProblem{Pointer: pointer, Message: "after merging: " + p.Error()})
+			}
+		}
+	}
+
+	return problems
+}
+
+func check_config(raw any, configBytes []byte, rootDir string) []ConfigProblem {
+	problems := jsonschema.Validate(configschema.Root_Schema, raw)
+
+	// Type mismatches are already reported by the schema validation, and
+	// encoding/json still fills in every field it can, so keep going
+	var uc UserConfig
+	var typeErr *json.UnmarshalTypeError
+	if err := json.Unmarshal(configBytes, &uc); (err != nil && !errors.As(err, &typeErr)) || uc.Core == nil {
+		return problems
+	}
+
+	ch := &configChecker{rootDir: rootDir}
+	ch.check(&uc)
+
+	return append(problems, ch.problems...)
+}
+
+func describe_json_error(configBytes []byte, err error) string {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		before := configBytes[:min(int(syntaxErr.Offset), len(configBytes))]
+		line := bytes.Count(before, []byte("\n")) + 1
+		col := len(before) - bytes.LastIndexByte(before, '\n')
+		return fmt.Sprintf("invalid JSON at line %d, column %d: %v", line, col, err)
+	}
+	return fmt.Sprintf("invalid JSON: %v", err)
+}
+
+type configChecker struct {
+	rootDir  string
+	problems []ConfigProblem
+}
+
+func (ch *configChecker) add(pointer, format string, args ...any) {
+	ch.problems = append(ch.problems, ConfigProblem{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+}
+
+func (ch *configChecker) resolve(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(ch.rootDir, p)
+}
+
+func (ch *configChecker) must_exist(pointer, p string, wantDir bool) {
+	if p == "" {
+		return
+	}
+	info, err := os.Stat(ch.resolve(p))
+	switch {
+	case err != nil:
+		ch.add(pointer, "%q does not exist", p)
+	case wantDir && !info.IsDir():
+		ch.add(pointer, "%q is not a directory", p)
+	case !wantDir && info.IsDir():
+		ch.add(pointer, "%q is a directory, expected a file", p)
+	}
+}
+
+func (ch *configChecker) must_be_valid_pattern(pointer, pattern string) {
+	if pattern != "" && !doublestar.ValidatePattern(filepath.ToSlash(pattern)) {
+		ch.add(pointer, "invalid glob pattern %q", pattern)
+	}
+}
+
+func (ch *configChecker) check(uc *UserConfig) {
+	core := uc.Core
+
+	// CORE
+	if info, err := os.Stat(ch.resolve(core.MainAppEntry)); core.MainAppEntry != "" && err != nil {
+		ch.add("/Core/MainAppEntry", "%q does not exist", core.MainAppEntry)
+	} else if err == nil && !info.IsDir() && filepath.Ext(core.MainAppEntry) != ".go" {
+		ch.add("/Core/MainAppEntry", "%q must be a .go file or a directory", core.MainAppEntry)
+	}
+
+	if !core.ServerOnlyMode {
+		if core.StaticAssetDirs.Private == "" && core.StaticAssetDirs.Public == "" {
+			ch.add("/Core", "missing required property %q (required unless ServerOnlyMode is true)", "StaticAssetDirs")
+		}
+		ch.must_exist("/Core/StaticAssetDirs/Private", core.StaticAssetDirs.Private, true)
+		ch.must_exist("/Core/StaticAssetDirs/Public", core.StaticAssetDirs.Public, true)
+
+		if prefix := core.PublicPathPrefix; prefix != "" && (!strings.HasPrefix(prefix, "/") || !strings.HasSuffix(prefix, "/")) {
+			ch.add("/Core/PublicPathPrefix", "%q must both start and end with a \"/\"", prefix)
+		}
+
+		if base := core.AssetBaseURL; base != "" {
+			if u, err := url.Parse(base); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				ch.add("/Core/AssetBaseURL", "%q must be an absolute http(s) URL", base)
+			}
+		}
+	}
+
+	if core.DistDir != "" {
+		dist := filepath.Clean(core.DistDir)
+		for name, dir := range map[string]string{"Private": core.StaticAssetDirs.Private, "Public": core.StaticAssetDirs.Public} {
+			if dir != "" && filepath.Clean(dir) == dist {
+				ch.add("/Core/DistDir", "must be different from Core.StaticAssetDirs.%s", name)
+			}
+		}
+	}
+
+	ch.must_exist("/Core/CSSEntryFiles/Critical", core.CSSEntryFiles.Critical, false)
+	ch.must_exist("/Core/CSSEntryFiles/NonCritical", core.CSSEntryFiles.NonCritical, false)
+	for _, name := range sorted_keys(core.CSSEntryFiles.Named) {
+		pointer := "/Core/CSSEntryFiles/Named/" + jsonschema.EscapePointerToken(name)
+		if !isValidNamedCSSEntryName(name) {
+			ch.add(pointer, "invalid name %q (letters, digits, and dashes only; not \"critical\" or \"normal\")", name)
+		}
+		ch.must_exist(pointer, core.CSSEntryFiles.Named[name], false)
+	}
+
+	ch.must_be_valid_pattern("/Core/CSSModules/Pattern", core.CSSModules.Pattern)
+
+	for i, format := range core.ImageVariants.Formats {
+		if _, err := resolveImageFormat(format, imageFormatPNG); err != nil {
+			ch.add("/Core/ImageVariants/Formats/"+strconv.Itoa(i), "%v", err)
+		}
+	}
+
+	// RIVER
+	if uc.River != nil {
+		if uc.River.HTMLTemplateLocation != "" && core.StaticAssetDirs.Private != "" {
+			ch.must_exist("/River/HTMLTemplateLocation", filepath.Join(core.StaticAssetDirs.Private, uc.River.HTMLTemplateLocation), false)
+		}
+		ch.must_exist("/River/ClientEntry", uc.River.ClientEntry, false)
+		ch.must_exist("/River/ClientRouteDefsFile", uc.River.ClientRouteDefsFile, false)
+	}
+
+	// VITE
+	if uc.Vite != nil {
+		ch.must_exist("/Vite/JSPackageManagerCmdDir", uc.Vite.JSPackageManagerCmdDir, true)
+		if uc.Vite.ViteConfigFile != "" {
+			ch.must_exist("/Vite/ViteConfigFile", filepath.Join(uc.Vite.JSPackageManagerCmdDir, uc.Vite.ViteConfigFile), false)
+		}
+	}
+
+	// WATCH
+	if uc.Watch != nil {
+		ch.must_exist("/Watch/WatchRoot", uc.Watch.WatchRoot, true)
+		for i, wf := range uc.Watch.Include {
+			pointer := "/Watch/Include/" + strconv.Itoa(i)
+			ch.must_be_valid_pattern(pointer+"/Pattern", wf.Pattern)
+			for j, hook := range wf.OnChangeHooks {
+				for k, exclude := range hook.Exclude {
+					ch.must_be_valid_pattern(fmt.Sprintf("%s/OnChangeHooks/%d/Exclude/%d", pointer, j, k), exclude)
+				}
+			}
+		}
+		for i, p := range uc.Watch.Exclude.Dirs {
+			ch.must_be_valid_pattern("/Watch/Exclude/Dirs/"+strconv.Itoa(i), p)
+		}
+		for i, p := range uc.Watch.Exclude.Files {
+			ch.must_be_valid_pattern("/Watch/Exclude/Files/"+strconv.Itoa(i), p)
+		}
+	}
+}
+
+func sorted_keys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,131 @@
+package ki
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckConfig(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"backend", "static/private", "static/public", "styles"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"backend/main.go", "styles/main.css"} {
+		if err := os.WriteFile(filepath.Join(root, file), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	problemsByPointer := func(problems []ConfigProblem) map[string]string {
+		m := make(map[string]string, len(problems))
+		for _, p := range problems {
+			m[p.Pointer] += p.Message + "; "
+		}
+		return m
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		config := `{
+			"$schema": "irrelevant",
+			"Core": {
+				"MainAppEntry": "backend/main.go",
+				"DistDir": "dist",
+				"DevBuildHook": "go run ./build --dev",
+				"ProdBuildHook": "go run ./build",
+				"StaticAssetDirs": {"Private": "static/private", "Public": "static/public"},
+				"CSSEntryFiles": {"NonCritical": "styles/main.css"}
+			},
+			"Watch": {"Include": [{"Pattern": "**/*.go"}]}
+		}`
+		if problems := CheckConfig([]byte(config), root); len(problems) != 0 {
+			t.Errorf("expected no problems, got %v", problems)
+		}
+	})
+
+	t.Run("ReportsAllProblems", func(t *testing.T) {
+		config := `{
+			"Core": {
+				"MainAppEntry": "backend/missing.go",
+				"DistDir": "static/public",
+				"StaticAssetDirs": {"Private": "static/private", "Public": "static/public"},
+				"PublicPathPrefix": "public",
+				"CSSEntryFiles": {"Critical": "styles", "Named": {"a_b": "styles/main.css"}},
+				"StaticAssetDir": "static"
+			},
+			"River": {"UIVariant": "vue"},
+			"Watch": {
+				"Include": [{"Pattern": "**/*.go"}, {"Pattern": "[unclosed", "OnChangeHooks": [{"Cmd": "x", "Exclude": ["{a,b"]}]}],
+				"Exclude": {"Dirs": [5]}
+			}
+		}`
+		got := problemsByPointer(CheckConfig([]byte(config), root))
+
+		want := map[string]string{
+			"/Core/MainAppEntry":                         "does not exist",
+			"/Core/DistDir":                              "must be different from Core.StaticAssetDirs.Public",
+			"/Core/PublicPathPrefix":                     "must both start and end",
+			"/Core/CSSEntryFiles/Critical":               "is a directory",
+			"/Core/CSSEntryFiles/Named/a_b":              "invalid name",
+			"/Core/StaticAssetDir":                       `unknown property (did you mean "StaticAssetDirs"?)`,
+			"/River":                                     `missing required property "ClientEntry"`,
+			"/River/UIVariant":                           "must be one of",
+			"/Watch/Include/1/Pattern":                   "invalid glob pattern",
+			"/Watch/Include/1/OnChangeHooks/0/Exclude/0": "invalid glob pattern",
+			"/Watch/Exclude/Dirs/0":                      "expected string, got number",
+		}
+		for pointer, substr := range want {
+			if !strings.Contains(got[pointer], substr) {
+				t.Errorf("%s: expected message containing %q, got %q", pointer, substr, got[pointer])
+			}
+		}
+	})
+
+	t.Run("StaticAssetDirsRequiredUnlessServerOnly", func(t *testing.T) {
+		got := problemsByPointer(CheckConfig([]byte(`{"Core": {"MainAppEntry": "backend", "DistDir": "dist", "DevBuildHook": "x", "ProdBuildHook": "x"}}`), root))
+		if !strings.Contains(got["/Core"], "StaticAssetDirs") {
+			t.Errorf("expected StaticAssetDirs problem, got %v", got)
+		}
+
+		config := `{"Core": {"MainAppEntry": "backend", "DistDir": "dist", "DevBuildHook": "x", "ProdBuildHook": "x", "ServerOnlyMode": true}}`
+		if problems := CheckConfig([]byte(config), root); len(problems) != 0 {
+			t.Errorf("expected no problems, got %v", problems)
+		}
+	})
+
+	t.Run("EnvOverlays", func(t *testing.T) {
+		config := `{
+			"Core": {
+				"MainAppEntry": "backend", "DistDir": "dist", "DevBuildHook": "x", "ProdBuildHook": "x",
+				"StaticAssetDirs": {"Private": "static/private", "Public": "static/public"}
+			},
+			"Env": {
+				"ok": {"Watch": {"HealthcheckEndpoint": "/healthz"}},
+				"bad": {"Core": {"MainAppEntry": "nope"}},
+				"cdn": {"Core": {"PublicPathPrefix": "/cdn/"}},
+				"dist": {"Core": {"DistDir": "other"}}
+			}
+		}`
+		problems := CheckConfig([]byte(config), root)
+		if len(problems) != 2 {
+			t.Fatalf("expected problems for the bad and dist overlays, got %v", problems)
+		}
+		if problems[0].Pointer != "/Env/bad" ||
+			!strings.Contains(problems[0].Message, `/Core/MainAppEntry: "nope" does not exist`) {
+			t.Errorf("unexpected problem for the bad overlay: %v", problems[0])
+		}
+		if problems[1].Pointer != "/Env/dist" || !strings.Contains(problems[1].Message, "Core.DistDir") {
+			t.Errorf("unexpected problem for the dist overlay: %v", problems[1])
+		}
+	})
+
+	t.Run("InvalidJSON", func(t *testing.T) {
+		problems := CheckConfig([]byte("{\n  \"Core\": {,\n}"), root)
+		if len(problems) != 1 || !strings.Contains(problems[0].Message, "line 2") {
+			t.Errorf("expected a single syntax error on line 2, got %v", problems)
+		}
+	})
+}
@@ -5,6 +5,8 @@ import (
 	"io/fs"
 	"log/slog"
 	"os/exec"
+	"path"
+	"strings"
 	"sync"
 
 	"github.com/fsnotify/fsnotify"
@@ -31,6 +33,9 @@ type dev struct {
 	naiveIgnoreDirPatterns []string
 	defaultWatchedFiles    []WatchedFile
 	matchResults           *safecache.CacheMap[potentialMatch, string, bool]
+	devProxy               *devProxy
+	devErrors              devErrorState
+	goDepGraph             goDepGraph
 }
 
 /////////////////////////////////////////////////////////////////////
@@ -52,6 +57,8 @@ type runtimeCache struct {
 	stylesheet_link_el *safecache.Cache[*template.HTML]
 	stylesheet_url     *safecache.Cache[string]
 	critical_css       *safecache.Cache[*criticalCSSStatus]
+	named_css_refs     *safecache.Cache[map[string]string]
+	css_modules        *safecache.Cache[cssModuleClassMap]
 
 	// Public URLs
 	public_filemap_from_gob *safecache.Cache[FileMap]
@@ -59,6 +66,8 @@ type runtimeCache struct {
 	public_filemap_details  *safecache.Cache[*publicFileMapDetails]
 	public_urls             *safecache.CacheMap[string, string, string]
 	is_public_asset         *safecache.CacheMap[string, string, bool]
+	public_filemap_by_val   *safecache.Cache[map[string]fileVal]
+	public_srcsets          *safecache.CacheMap[srcSetKey, srcSetKey, string]
 }
 
 func (c *Config) InitRuntimeCache() {
@@ -73,6 +82,8 @@ func (c *Config) InitRuntimeCache() {
 		stylesheet_link_el: safecache.New(c.getInitialStyleSheetLinkElement, GetIsDev),
 		stylesheet_url:     safecache.New(c.getInitialStyleSheetURL, GetIsDev),
 		critical_css:       safecache.New(c.getInitialCriticalCSSStatus, GetIsDev),
+		named_css_refs:     safecache.New(c.getInitialNamedCSSRefs, GetIsDev),
+		css_modules:        safecache.New(c.getInitialCSSModules, GetIsDev),
 
 		// Public URLs
 		public_filemap_from_gob: safecache.New(c.getInitialPublicFileMapFromGobRuntime, GetIsDev),
@@ -84,6 +95,10 @@ func (c *Config) InitRuntimeCache() {
 		is_public_asset: safecache.NewMap(c.getInitialIsPublicAsset, publicURLsKeyMaker, func(string) bool {
 			return GetIsDev()
 		}),
+		public_filemap_by_val: safecache.New(c.getInitialPublicFileMapByVal, GetIsDev),
+		public_srcsets: safecache.NewMap(c.getInitialPublicSrcSet, srcSetKeyMaker, func(srcSetKey) bool {
+			return GetIsDev()
+		}),
 	}
 }
 
@@ -102,6 +117,18 @@ type Config struct {
 	ConfigBytes            []byte
 	Logger                 *slog.Logger
 	FilesToVendor          [][2]string // __TODO move to json config
+	// Run (in order) on each bundled CSS entry after any
+	// Core.CSSTransformCmds, before the output is hashed and written.
+	CSSTransformers []CSSTransformer
+	// Optional config overlays keyed by WAVE_ENV value (e.g., "staging" ->
+	// the bytes of an embedded "wave.staging.json"). The overlay matching the
+	// current WAVE_ENV, if any, is deep-merged over ConfigBytes (after the
+	// config's own Env section, if any) at MainInit. This lets you ship the
+	// same binary to multiple environments (e.g., with a different
+	// Core.AssetBaseURL). Overlays can't change settings that are baked into
+	// the build output (e.g., Core.DistDir or Core.CSSEntryFiles); MainInit
+	// panics if one does.
+	ConfigOverlays map[string][]byte
 
 	dev
 	_runtime
@@ -109,6 +136,7 @@ type Config struct {
 	cleanWatchRoot string
 	_dist          *dirs.Dir[Dist]
 	_uc            *UserConfig
+	cssModules     cssModulesState
 
 	_rebuild_cleanup_chan chan struct{}
 	_vite_dev_ctx         *viteutil.BuildCtx
@@ -120,6 +148,7 @@ type CleanSources struct {
 	PublicStatic        string
 	CriticalCSSEntry    string
 	NonCriticalCSSEntry string
+	NamedCSSEntries     map[string]string
 }
 
 func (c *Config) GetPrivateStaticDir() string {
@@ -139,6 +168,29 @@ func (c *Config) GetPublicPathPrefix() string {
 	return matcher.EnsureLeadingSlash(matcher.EnsureTrailingSlash(p))
 }
 
+// Returns the base that public asset URLs are built from: Core.AssetBaseURL
+// (with a trailing slash) if set, otherwise the public path prefix. In dev
+// mode, assets are always served from the local server, so AssetBaseURL is
+// ignored.
+func (c *Config) GetPublicURLBase() string {
+	if c.use_asset_base_url() {
+		return matcher.EnsureTrailingSlash(c._uc.Core.AssetBaseURL)
+	}
+	return c.GetPublicPathPrefix()
+}
+
+func (c *Config) use_asset_base_url() bool {
+	return c._uc.Core.AssetBaseURL != "" && !GetIsDev()
+}
+
+// Joins a path relative to the public assets root onto the public URL base.
+func (c *Config) to_public_asset_url(elem ...string) string {
+	if c.use_asset_base_url() {
+		return c.GetPublicURLBase() + strings.TrimPrefix(path.Join(elem...), "/")
+	}
+	return matcher.EnsureLeadingSlash(path.Join(append([]string{c._uc.Core.PublicPathPrefix}, elem...)...))
+}
+
 /////////////////////////////////////////////////////////////////////
 /////// USER CONFIG
 /////////////////////////////////////////////////////////////////////
@@ -172,8 +224,21 @@ type UserConfigCore struct {
 	DistDir          string
 	StaticAssetDirs  StaticAssetDirs
 	CSSEntryFiles    CSSEntryFiles
+	CSSTransformCmds []string
+	CSSModules       CSSModules
 	PublicPathPrefix string
+	// Optional absolute URL (e.g., "https://cdn.example.com/app/") to build
+	// public asset URLs from instead of PublicPathPrefix. Files are still
+	// served locally under PublicPathPrefix, so point your CDN's origin at
+	// "<your origin><PublicPathPrefix>". Read at runtime, so it can differ
+	// per environment without a rebuild.
+	AssetBaseURL string
+	// Origins allowed to load public assets cross-origin when AssetBaseURL is
+	// in use. If empty, any origin is allowed.
+	AssetCORSOrigins []string
 	ServerOnlyMode   bool
+	Precompression   Precompression
+	ImageVariants    ImageVariants
 }
 
 func (c *Config) GetConfigFile() string {
@@ -188,6 +253,25 @@ type StaticAssetDirs struct {
 type CSSEntryFiles struct {
 	Critical    string
 	NonCritical string
+	// Additional non-critical stylesheets (e.g., "admin"), each bundled to its
+	// own hashed output. Names may only contain letters, digits, and dashes.
+	Named map[string]string
+}
+
+type CSSModules struct {
+	Pattern string // Doublestar glob, e.g. "**/*.module.css"
+}
+
+type ImageVariants struct {
+	Widths  []int    // Pixels. Images are never upscaled.
+	Formats []string // "original" (default), "jpeg", or "png".
+	Quality int      // JPEG quality (1-100). Defaults to 80.
+}
+
+type Precompression struct {
+	Brotli  bool
+	Gzip    bool
+	MinSize int // Bytes. Defaults to 1024.
 }
 
 type UserConfigVite struct {
@@ -205,6 +289,7 @@ type UserConfigRiver struct {
 	ClientRouteDefsFile        string
 	TSGenOutPath               string // e.g., "./frontend/river.gen.ts"
 	BuildtimePublicURLFuncName string // e.g., "waveURL", "withHash", etc.
+	ExtractCriticalCSS         bool   // Prod only. Inlines per-route critical CSS from the non-critical bundle.
 }
 
 func (c *Config) GetRiverUIVariant() string {
@@ -225,10 +310,14 @@ func (c *Config) GetRiverTSGenOutPath() string {
 func (c *Config) GetRiverBuildtimePublicURLFuncName() string {
 	return c._uc.River.BuildtimePublicURLFuncName
 }
+func (c *Config) GetRiverExtractCriticalCSS() bool {
+	return c._uc.River.ExtractCriticalCSS
+}
 
 type UserConfigWatch struct {
 	WatchRoot           string
 	HealthcheckEndpoint string
+	DevProxy            DevProxy
 	Include             []WatchedFile
 	Exclude             struct {
 		Dirs  []string
@@ -236,6 +325,12 @@ type UserConfigWatch struct {
 	}
 }
 
+type DevProxy struct {
+	Enabled            bool
+	HoldTimeoutMs      int // Defaults to 30000.
+	RebuildPageAfterMs int // Defaults to 2000.
+}
+
 type OnChangeHook struct {
 	Cmd     string
 	Timing  Timing
@@ -0,0 +1,127 @@
+package ki
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"strings"
+)
+
+// Top-level config key holding per-environment overlays, keyed by the value
+// of the WAVE_ENV environment variable. For example:
+//
+//	"Env": { "staging": { "Watch": { "HealthcheckEndpoint": "/healthz" } } }
+const envSectionKey = "Env"
+
+// Settings that are baked into build output (the public file map, CSS
+// bundles, generated files, and so on). The asset base (Core.PublicPathPrefix
+// and Core.AssetBaseURL) is resolved at runtime, so it isn't listed here. Overlays are applied when the
+// app starts, not when it's built, so an overlay that changes one of these
+// would leave the binary disagreeing with its own assets.
+var buildOnlyConfigKeys = [][]string{
+	{"Core", "DistDir"},
+	{"Core", "StaticAssetDirs"},
+	{"Core", "CSSEntryFiles"},
+	{"Core", "CSSTransformCmds"},
+	{"Core", "CSSModules"},
+	{"Core", "Precompression"},
+	{"Core", "ImageVariants"},
+	{"River", "ClientEntry"},
+	{"River", "BuildtimePublicURLFuncName"},
+	{"River", "ExtractCriticalCSS"},
+}
+
+// Resolves the effective user config by deep-merging, over the base config,
+// first the base config's Env section for env (if any), then the overlay file
+// for env (if any). Objects are merged key by key; anything else (strings,
+// numbers, arrays, etc.) in an overlay replaces the base value outright. The
+// Env section itself is dropped from the result. If env is empty, the base
+// config is returned unchanged (minus its Env section). Returns an error if an
+// overlay changes any of the buildOnlyConfigKeys.
+func resolveConfigBytes(base []byte, env string, overlayFiles map[string][]byte) ([]byte, error) {
+	var merged map[string]any
+	if err := json.Unmarshal(base, &merged); err != nil {
+		return nil, err
+	}
+
+	var envSection map[string]any
+	if raw, ok := merged[envSectionKey]; ok {
+		delete(merged, envSectionKey)
+		if envSection, ok = raw.(map[string]any); !ok {
+			return nil, fmt.Errorf("%s must be an object", envSectionKey)
+		}
+	}
+
+	if env == "" {
+		return json.Marshal(merged)
+	}
+
+	baseMerged := merged
+
+	if raw, ok := envSection[env]; ok {
+		overlay, ok := raw.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s.%s must be an object", envSectionKey, env)
+		}
+		merged = deepMergeJSON(merged, overlay)
+	}
+
+	if overlayBytes, ok := overlayFiles[env]; ok {
+		var overlay map[string]any
+		if err := json.Unmarshal(overlayBytes, &overlay); err != nil {
+			return nil, fmt.Errorf("error unmarshalling %q config overlay: %w", env, err)
+		}
+		delete(overlay, envSectionKey)
+		delete(overlay, "$schema")
+		merged = deepMergeJSON(merged, overlay)
+	}
+
+	if err := checkBuildOnlyConfigKeys(baseMerged, merged, env); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(merged)
+}
+
+func checkBuildOnlyConfigKeys(base, merged map[string]any, env string) error {
+	for _, path := range buildOnlyConfigKeys {
+		if !reflect.DeepEqual(lookupJSONPath(base, path), lookupJSONPath(merged, path)) {
+			return fmt.Errorf(
+				"the %q overlay changes %s, which is baked into the build output and can't be overridden at runtime; set it in the base config instead",
+				env, strings.Join(path, "."),
+			)
+		}
+	}
+	return nil
+}
+
+func lookupJSONPath(m map[string]any, path []string) any {
+	var cur any = m
+	for _, k := range path {
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		cur = obj[k]
+	}
+	return cur
+}
+
+// Returns a new map; neither input is modified.
+func deepMergeJSON(base, overlay map[string]any) map[string]any {
+	result := maps.Clone(base)
+	if result == nil {
+		result = make(map[string]any, len(overlay))
+	}
+	for k, overlayVal := range overlay {
+		overlayObj, overlayIsObj := overlayVal.(map[string]any)
+		baseObj, baseIsObj := result[k].(map[string]any)
+		if overlayIsObj && baseIsObj {
+			result[k] = deepMergeJSON(baseObj, overlayObj)
+		} else {
+			result[k] = overlayVal
+		}
+	}
+	return result
+}
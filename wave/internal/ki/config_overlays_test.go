@@ -0,0 +1,168 @@
+package ki
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveConfigBytes(t *testing.T) {
+	base := []byte(`{
+		"Core": {
+			"DistDir": "dist",
+			"PublicPathPrefix": "/public/",
+			"DevBuildHook": "go run ./cmd/build"
+		},
+		"Watch": {
+			"HealthcheckEndpoint": "/health",
+			"Exclude": {"Dirs": ["a", "b"], "Files": ["c"]},
+			"DevProxy": {"Enabled": false, "HoldTimeoutMs": 1000}
+		},
+		"Env": {
+			"staging": {
+				"Watch": {"HealthcheckEndpoint": "/staging-health", "Exclude": {"Dirs": ["x"]}}
+			}
+		}
+	}`)
+
+	resolve := func(t *testing.T, env string, overlays map[string][]byte) map[string]any {
+		t.Helper()
+		out, err := resolveConfigBytes(base, env, overlays)
+		if err != nil {
+			t.Fatalf("resolveConfigBytes: %v", err)
+		}
+		var m map[string]any
+		if err := json.Unmarshal(out, &m); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	t.Run("NoEnv", func(t *testing.T) {
+		m := resolve(t, "", nil)
+		if _, ok := m["Env"]; ok {
+			t.Error("Env section should be dropped")
+		}
+		if got := m["Watch"].(map[string]any)["HealthcheckEndpoint"]; got != "/health" {
+			t.Errorf("HealthcheckEndpoint = %v", got)
+		}
+	})
+
+	t.Run("EnvSection", func(t *testing.T) {
+		watch := resolve(t, "staging", nil)["Watch"].(map[string]any)
+		if got := watch["HealthcheckEndpoint"]; got != "/staging-health" {
+			t.Errorf("HealthcheckEndpoint = %v", got)
+		}
+		exclude := watch["Exclude"].(map[string]any)
+		if got := exclude["Dirs"]; !reflect.DeepEqual(got, []any{"x"}) {
+			t.Errorf("arrays should be replaced, got %v", got)
+		}
+		if got := exclude["Files"]; !reflect.DeepEqual(got, []any{"c"}) {
+			t.Errorf("unrelated keys should be kept, got Files = %v", got)
+		}
+		if got := watch["DevProxy"].(map[string]any)["HoldTimeoutMs"]; got != float64(1000) {
+			t.Errorf("unrelated keys should be kept, got HoldTimeoutMs = %v", got)
+		}
+	})
+
+	t.Run("OverlayFileAppliedAfterEnvSection", func(t *testing.T) {
+		overlays := map[string][]byte{
+			"staging": []byte(`{"$schema": "x", "Core": {"DevBuildHook": "make dev"}, "Watch": {"DevProxy": {"Enabled": true}}}`),
+		}
+		m := resolve(t, "staging", overlays)
+		if _, ok := m["$schema"]; ok {
+			t.Error("overlay $schema should not be merged")
+		}
+		core := m["Core"].(map[string]any)
+		if core["DevBuildHook"] != "make dev" || core["DistDir"] != "dist" {
+			t.Errorf("Core = %v", core)
+		}
+		watch := m["Watch"].(map[string]any)
+		if watch["HealthcheckEndpoint"] != "/staging-health" {
+			t.Errorf("Watch = %v", watch)
+		}
+		proxy := watch["DevProxy"].(map[string]any)
+		if proxy["Enabled"] != true || proxy["HoldTimeoutMs"] != float64(1000) {
+			t.Errorf("DevProxy = %v", proxy)
+		}
+	})
+
+	t.Run("UnknownEnv", func(t *testing.T) {
+		if got := resolve(t, "production", nil)["Watch"].(map[string]any)["HealthcheckEndpoint"]; got != "/health" {
+			t.Errorf("HealthcheckEndpoint = %v", got)
+		}
+	})
+
+	t.Run("BuildOnlyKeysRejected", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			base     string
+			overlays map[string][]byte
+			wantKey  string
+		}{
+			{
+				name:    "DistDirInEnvSection",
+				base:    `{"Core": {"DistDir": "dist"}, "Env": {"staging": {"Core": {"DistDir": "other"}}}}`,
+				wantKey: "Core.DistDir",
+			},
+			{
+				name:     "DistDirInOverlayFile",
+				base:     `{"Core": {"DistDir": "dist"}}`,
+				overlays: map[string][]byte{"staging": []byte(`{"Core": {"DistDir": "other"}}`)},
+				wantKey:  "Core.DistDir",
+			},
+			{
+				name:    "CSSTransformCmdsAddedByOverlay",
+				base:    `{"Core": {}, "Env": {"staging": {"Core": {"CSSTransformCmds": ["x"]}}}}`,
+				wantKey: "Core.CSSTransformCmds",
+			},
+			{
+				name:    "NestedBuildOnlyKey",
+				base:    `{"Core": {"StaticAssetDirs": {"Public": "pub"}}, "Env": {"staging": {"Core": {"StaticAssetDirs": {"Public": "other"}}}}}`,
+				wantKey: "Core.StaticAssetDirs",
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := resolveConfigBytes([]byte(tt.base), "staging", tt.overlays)
+				if err == nil || !strings.Contains(err.Error(), tt.wantKey) {
+					t.Errorf("expected an error naming %s, got %v", tt.wantKey, err)
+				}
+			})
+		}
+
+		// Restating the base value is harmless
+		same := []byte(`{"Core": {"DistDir": "dist"}, "Env": {"staging": {"Core": {"DistDir": "dist"}}}}`)
+		if _, err := resolveConfigBytes(same, "staging", nil); err != nil {
+			t.Errorf("expected no error for an unchanged build-only key, got %v", err)
+		}
+	})
+
+	t.Run("AssetBaseOverridable", func(t *testing.T) {
+		overlays := map[string][]byte{
+			"staging": []byte(`{"Core": {"PublicPathPrefix": "/assets/", "AssetBaseURL": "https://cdn.example.com/"}}`),
+		}
+		core := resolve(t, "staging", overlays)["Core"].(map[string]any)
+		if core["PublicPathPrefix"] != "/assets/" || core["AssetBaseURL"] != "https://cdn.example.com/" {
+			t.Errorf("Core = %v", core)
+		}
+	})
+
+	t.Run("InvalidOverlay", func(t *testing.T) {
+		if _, err := resolveConfigBytes([]byte(`{"Env": {"staging": 5}}`), "staging", nil); err == nil {
+			t.Error("expected an error for a non-object overlay")
+		}
+		if _, err := resolveConfigBytes([]byte(`{}`), "staging", map[string][]byte{"staging": []byte("{")}); err == nil {
+			t.Error("expected an error for an invalid overlay file")
+		}
+	})
+
+	t.Run("MergeDoesNotMutateInputs", func(t *testing.T) {
+		baseMap := map[string]any{"Core": map[string]any{"DistDir": "dist"}}
+		deepMergeJSON(baseMap, map[string]any{"Core": map[string]any{"DistDir": "other"}})
+		if baseMap["Core"].(map[string]any)["DistDir"] != "dist" {
+			t.Error("base map was mutated")
+		}
+	})
+}
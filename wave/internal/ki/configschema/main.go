@@ -34,15 +34,28 @@ var Root_Schema = jsonschema.Entry{
 	Required:    []string{"Core"},
 	Properties: struct {
 		Core  jsonschema.Entry
+		River jsonschema.Entry
 		Vite  jsonschema.Entry
 		Watch jsonschema.Entry
+		Env   jsonschema.Entry
 	}{
 		Core:  Core_Schema,
+		River: River_Schema,
 		Vite:  Vite_Schema,
 		Watch: Watch_Schema,
+		Env:   Env_Schema,
 	},
 }
 
+/////////////////////////////////////////////////////////////////////
+/////// ENV OVERLAYS
+/////////////////////////////////////////////////////////////////////
+
+var Env_Schema = jsonschema.OptionalObject(jsonschema.Def{
+	Description: `Per-environment config overlays, keyed by the value of the WAVE_ENV environment variable. At startup, the overlay matching WAVE_ENV (if any) is deep-merged over the rest of this config: objects are merged key by key, and any other value replaces the base value outright. Settings that are baked into the build output (such as Core.DistDir and the CSS and static asset settings) can't be changed by an overlay. Core.PublicPathPrefix and Core.AssetBaseURL are read at runtime, so overlays can change them.`,
+	Examples:    []string{`{"staging": {"Watch": {"HealthcheckEndpoint": "/healthz"}}}`},
+})
+
 /////////////////////////////////////////////////////////////////////
 /////// CORE SETTINGS
 /////////////////////////////////////////////////////////////////////
@@ -61,26 +74,49 @@ var Core_Schema = jsonschema.RequiredObject(jsonschema.Def{
 		},
 	}},
 	Properties: struct {
+		ConfigLocation   jsonschema.Entry
 		DevBuildHook     jsonschema.Entry
 		ProdBuildHook    jsonschema.Entry
 		MainAppEntry     jsonschema.Entry
 		DistDir          jsonschema.Entry
 		StaticAssetDirs  jsonschema.Entry
 		CSSEntryFiles    jsonschema.Entry
+		CSSTransformCmds jsonschema.Entry
+		CSSModules       jsonschema.Entry
 		PublicPathPrefix jsonschema.Entry
+		AssetBaseURL     jsonschema.Entry
+		AssetCORSOrigins jsonschema.Entry
 		ServerOnlyMode   jsonschema.Entry
+		Precompression   jsonschema.Entry
+		ImageVariants    jsonschema.Entry
 	}{
+		ConfigLocation:   ConfigLocation_Schema,
 		DevBuildHook:     DevBuildHook_Schema,
 		ProdBuildHook:    ProdBuildHook_Schema,
 		MainAppEntry:     MainAppEntry_Schema,
 		DistDir:          DistDir_Schema,
 		StaticAssetDirs:  StaticAssetDirs_Schema,
 		CSSEntryFiles:    CSSEntryFiles_Schema,
+		CSSTransformCmds: CSSTransformCmds_Schema,
+		CSSModules:       CSSModules_Schema,
 		PublicPathPrefix: PublicPathPrefix_Schema,
+		AssetBaseURL:     AssetBaseURL_Schema,
+		AssetCORSOrigins: AssetCORSOrigins_Schema,
 		ServerOnlyMode:   ServerOnlyMode_Schema,
+		Precompression:   Precompression_Schema,
+		ImageVariants:    ImageVariants_Schema,
 	},
 })
 
+/////////////////////////////////////////////////////////////////////
+/////// CORE SETTINGS -- CONFIG LOCATION
+/////////////////////////////////////////////////////////////////////
+
+var ConfigLocation_Schema = jsonschema.OptionalString(jsonschema.Def{
+	Description: `Path to this config file.`,
+	Examples:    []string{"wave.config.json"},
+})
+
 /////////////////////////////////////////////////////////////////////
 /////// CORE SETTINGS -- DEV BUILD HOOK
 /////////////////////////////////////////////////////////////////////
@@ -151,9 +187,11 @@ var CSSEntryFiles_Schema = jsonschema.OptionalObject(jsonschema.Def{
 	Properties: struct {
 		Critical    jsonschema.Entry
 		NonCritical jsonschema.Entry
+		Named       jsonschema.Entry
 	}{
 		Critical:    Critical_Schema,
 		NonCritical: NonCritical_Schema,
+		Named:       Named_Schema,
 	},
 })
 
@@ -167,6 +205,38 @@ var NonCritical_Schema = jsonschema.OptionalString(jsonschema.Def{
 	Examples:    []string{"./styles/main.css"},
 })
 
+var Named_Schema = jsonschema.OptionalObject(jsonschema.Def{
+	Description: `Map of names to additional non-critical CSS entry files, each bundled to its own hashed stylesheet (e.g., to keep admin styles out of your public pages). Names may only contain letters, digits, and dashes. Include them with Wave.GetStyleSheetURLFor(name) or Wave.GetStyleSheetLinkElementFor(name).`,
+	Examples:    []string{`{"admin": "./styles/admin.css"}`},
+})
+
+/////////////////////////////////////////////////////////////////////
+/////// CORE SETTINGS -- CSS TRANSFORM CMDS
+/////////////////////////////////////////////////////////////////////
+
+var CSSTransformCmds_Schema = jsonschema.OptionalArray(jsonschema.Def{
+	Description: `Commands to run, in order, on each bundled CSS entry before it is hashed and written (e.g., for nesting, autoprefixing, or minification). Each command receives the CSS on stdin and must write the transformed CSS to stdout. The WAVE_CSS_NATURE env var is set to "critical" or "normal".`,
+	Examples:    []string{`["node ./scripts/postcss.mjs"]`},
+})
+
+/////////////////////////////////////////////////////////////////////
+/////// CORE SETTINGS -- CSS MODULES
+/////////////////////////////////////////////////////////////////////
+
+var CSSModules_Schema = jsonschema.OptionalObject(jsonschema.Def{
+	Description: `Use this to scope the class names in matching CSS files (e.g., ".card" becomes ".card_1a2b3c4d"). Use :global(...) to opt out. Look up scoped names with Wave.GetCSSModuleClass.`,
+	Properties: struct {
+		Pattern jsonschema.Entry
+	}{
+		Pattern: CSSModulesPattern_Schema,
+	},
+})
+
+var CSSModulesPattern_Schema = jsonschema.OptionalString(jsonschema.Def{
+	Description: `Glob pattern (relative to the directory Wave runs from) for CSS files to treat as CSS modules.`,
+	Examples:    []string{"**/*.module.css"},
+})
+
 /////////////////////////////////////////////////////////////////////
 /////// CORE SETTINGS -- PUBLIC PATH PREFIX
 /////////////////////////////////////////////////////////////////////
@@ -176,6 +246,21 @@ var PublicPathPrefix_Schema = jsonschema.RequiredString(jsonschema.Def{
 	Examples:    []string{"/public/"},
 })
 
+/////////////////////////////////////////////////////////////////////
+/////// CORE SETTINGS -- ASSET BASE URL
+/////////////////////////////////////////////////////////////////////
+
+var AssetBaseURL_Schema = jsonschema.OptionalString(jsonschema.Def{
+	Description: `Absolute URL (e.g., of a CDN) to build public asset URLs from instead of PublicPathPrefix. Files are still served locally under PublicPathPrefix, so point your CDN's origin at your origin plus PublicPathPrefix. Read at runtime, so it can differ per environment (see Env) without a rebuild.`,
+	Examples:    []string{"https://cdn.example.com/app/"},
+})
+
+var AssetCORSOrigins_Schema = jsonschema.OptionalArray(jsonschema.Def{
+	Description: `Only used when AssetBaseURL is set (outside dev). Origins allowed to load public assets cross-origin, which browsers require for integrity-checked scripts and stylesheets served from another host. If empty, any origin is allowed ("Access-Control-Allow-Origin: *"). If set, the request's Origin is echoed back when it's in the list and responses vary on Origin, so make sure your CDN forwards the Origin header.`,
+	Items:       jsonschema.Entry{Type: jsonschema.TypeString},
+	Examples:    []string{`["https://example.com", "https://www.example.com"]`},
+})
+
 /////////////////////////////////////////////////////////////////////
 /////// CORE SETTINGS -- SERVER ONLY
 /////////////////////////////////////////////////////////////////////
@@ -185,6 +270,139 @@ var ServerOnlyMode_Schema = jsonschema.OptionalBoolean(jsonschema.Def{
 	Default:     false,
 })
 
+/////////////////////////////////////////////////////////////////////
+/////// CORE SETTINGS -- PRECOMPRESSION
+/////////////////////////////////////////////////////////////////////
+
+var Precompression_Schema = jsonschema.OptionalObject(jsonschema.Def{
+	Description: `Use this to write precompressed siblings (e.g., "app.js.br") of your public assets at prod build time. Wave's static handler serves them to clients that accept the encoding.`,
+	Properties: struct {
+		Brotli  jsonschema.Entry
+		Gzip    jsonschema.Entry
+		MinSize jsonschema.Entry
+	}{
+		Brotli:  PrecompressionBrotli_Schema,
+		Gzip:    PrecompressionGzip_Schema,
+		MinSize: PrecompressionMinSize_Schema,
+	},
+})
+
+var PrecompressionBrotli_Schema = jsonschema.OptionalBoolean(jsonschema.Def{
+	Description: `If true, writes a ".br" sibling for each compressible public asset.`,
+	Default:     false,
+})
+
+var PrecompressionGzip_Schema = jsonschema.OptionalBoolean(jsonschema.Def{
+	Description: `If true, writes a ".gz" sibling for each compressible public asset.`,
+	Default:     false,
+})
+
+var PrecompressionMinSize_Schema = jsonschema.OptionalNumber(jsonschema.Def{
+	Description: `Files smaller than this many bytes are not precompressed.`,
+	Default:     1024,
+})
+
+/////////////////////////////////////////////////////////////////////
+/////// CORE SETTINGS -- IMAGE VARIANTS
+/////////////////////////////////////////////////////////////////////
+
+var ImageVariants_Schema = jsonschema.OptionalObject(jsonschema.Def{
+	Description: `Use this to generate resized and/or re-encoded variants of the PNG and JPEG images in your public static dir. Get a srcset for an image with Wave.GetPublicSrcSet.`,
+	Properties: struct {
+		Widths  jsonschema.Entry
+		Formats jsonschema.Entry
+		Quality jsonschema.Entry
+	}{
+		Widths:  ImageVariantsWidths_Schema,
+		Formats: ImageVariantsFormats_Schema,
+		Quality: ImageVariantsQuality_Schema,
+	},
+})
+
+var ImageVariantsWidths_Schema = jsonschema.OptionalArray(jsonschema.Def{
+	Description: `Target widths in pixels. Images are never upscaled.`,
+	Examples:    []string{"[480, 960, 1600]"},
+})
+
+var ImageVariantsFormats_Schema = jsonschema.OptionalArray(jsonschema.Def{
+	Description: `Output formats: "original", "jpeg", or "png". WebP and AVIF are not supported because no pure-Go encoders are available for them.`,
+	Examples:    []string{`["original", "jpeg"]`},
+	Default:     []string{"original"},
+})
+
+var ImageVariantsQuality_Schema = jsonschema.OptionalNumber(jsonschema.Def{
+	Description: `JPEG quality (1-100).`,
+	Default:     80,
+})
+
+/////////////////////////////////////////////////////////////////////
+/////// RIVER SETTINGS
+/////////////////////////////////////////////////////////////////////
+
+var River_Schema = jsonschema.OptionalObject(jsonschema.Def{
+	Description: `Use this if you are using the River framework.`,
+	Properties: struct {
+		IncludeDefaults            jsonschema.Entry
+		UIVariant                  jsonschema.Entry
+		HTMLTemplateLocation       jsonschema.Entry
+		ClientEntry                jsonschema.Entry
+		ClientRouteDefsFile        jsonschema.Entry
+		TSGenOutPath               jsonschema.Entry
+		BuildtimePublicURLFuncName jsonschema.Entry
+		ExtractCriticalCSS         jsonschema.Entry
+	}{
+		IncludeDefaults:            IncludeDefaults_Schema,
+		UIVariant:                  UIVariant_Schema,
+		HTMLTemplateLocation:       HTMLTemplateLocation_Schema,
+		ClientEntry:                ClientEntry_Schema,
+		ClientRouteDefsFile:        ClientRouteDefsFile_Schema,
+		TSGenOutPath:               TSGenOutPath_Schema,
+		BuildtimePublicURLFuncName: BuildtimePublicURLFuncName_Schema,
+		ExtractCriticalCSS:         ExtractCriticalCSS_Schema,
+	},
+	RequiredChildren: []string{"UIVariant", "HTMLTemplateLocation", "ClientEntry", "ClientRouteDefsFile", "TSGenOutPath"},
+})
+
+var IncludeDefaults_Schema = jsonschema.OptionalBoolean(jsonschema.Def{
+	Description: `Whether to add River's default watched files (HTML template, route defs file, Go files, etc.).`,
+	Default:     true,
+})
+
+var UIVariant_Schema = jsonschema.RequiredString(jsonschema.Def{
+	Description: `The UI library you are using with River.`,
+	Enum:        []string{"react", "preact", "solid"},
+})
+
+var HTMLTemplateLocation_Schema = jsonschema.RequiredString(jsonschema.Def{
+	Description: `Path to your root HTML template, relative to Core.StaticAssetDirs.Private.`,
+	Examples:    []string{"entry.go.html"},
+})
+
+var ClientEntry_Schema = jsonschema.RequiredString(jsonschema.Def{
+	Description: `Path to your client entry file.`,
+	Examples:    []string{"frontend/entry.tsx"},
+})
+
+var ClientRouteDefsFile_Schema = jsonschema.RequiredString(jsonschema.Def{
+	Description: `Path to your client route definitions file.`,
+	Examples:    []string{"frontend/routes.ts"},
+})
+
+var TSGenOutPath_Schema = jsonschema.RequiredString(jsonschema.Def{
+	Description: `Path to write River's generated TypeScript to.`,
+	Examples:    []string{"frontend/river.gen.ts"},
+})
+
+var BuildtimePublicURLFuncName_Schema = jsonschema.OptionalString(jsonschema.Def{
+	Description: `Name of the function you use in your client code to resolve public URLs at build time.`,
+	Examples:    []string{"getPublicURLBuildtime"},
+})
+
+var ExtractCriticalCSS_Schema = jsonschema.OptionalBoolean(jsonschema.Def{
+	Description: `If true, at prod build time River determines which rules from your non-critical CSS bundle each route uses, and inlines them in that route's HTML.`,
+	Default:     false,
+})
+
 /////////////////////////////////////////////////////////////////////
 /////// VITE SETTINGS
 /////////////////////////////////////////////////////////////////////
@@ -251,11 +469,13 @@ var Watch_Schema = jsonschema.OptionalObject(jsonschema.Def{
 	Properties: struct {
 		WatchRoot           jsonschema.Entry
 		HealthcheckEndpoint jsonschema.Entry
+		DevProxy            jsonschema.Entry
 		Include             jsonschema.Entry
 		Exclude             jsonschema.Entry
 	}{
 		WatchRoot:           WatchRoot_Schema,
 		HealthcheckEndpoint: HealthcheckEndpoint_Schema,
+		DevProxy:            DevProxy_Schema,
 		Include:             Include_Schema,
 		Exclude:             Exclude_Schema,
 	},
@@ -280,6 +500,38 @@ var HealthcheckEndpoint_Schema = jsonschema.OptionalString(jsonschema.Def{
 	Default:     "/",
 })
 
+/////////////////////////////////////////////////////////////////////
+/////// WATCH SETTINGS -- DEV PROXY
+/////////////////////////////////////////////////////////////////////
+
+var DevProxy_Schema = jsonschema.OptionalObject(jsonschema.Def{
+	Description: `If enabled, Wave serves your app's dev port through a reverse proxy that holds incoming requests while your app restarts, forwarding them once your HealthcheckEndpoint responds. If the rebuild fails, held and new requests get the build error right away instead. Your app itself is moved to an internal port (via the PORT env var).`,
+	Properties: struct {
+		Enabled            jsonschema.Entry
+		HoldTimeoutMs      jsonschema.Entry
+		RebuildPageAfterMs jsonschema.Entry
+	}{
+		Enabled:            DevProxyEnabled_Schema,
+		HoldTimeoutMs:      DevProxyHoldTimeoutMs_Schema,
+		RebuildPageAfterMs: DevProxyRebuildPageAfterMs_Schema,
+	},
+})
+
+var DevProxyEnabled_Schema = jsonschema.OptionalBoolean(jsonschema.Def{
+	Description: `Whether to run the dev proxy.`,
+	Default:     false,
+})
+
+var DevProxyHoldTimeoutMs_Schema = jsonschema.OptionalNumber(jsonschema.Def{
+	Description: `How long a request may be held while waiting for your app before the proxy responds with a 503.`,
+	Default:     30000,
+})
+
+var DevProxyRebuildPageAfterMs_Schema = jsonschema.OptionalNumber(jsonschema.Def{
+	Description: `How long a browser navigation may be held before the proxy responds with a self-refreshing "rebuilding" page instead.`,
+	Default:     2000,
+})
+
 /////////////////////////////////////////////////////////////////////
 /////// WATCH SETTINGS -- INCLUDE
 /////////////////////////////////////////////////////////////////////
@@ -4,11 +4,14 @@ import (
 	"fmt"
 	"html/template"
 	"io/fs"
+	"net/url"
+	"os"
 	"path"
+	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/river-now/river/kit/htmlutil"
-	"github.com/river-now/river/kit/matcher"
 )
 
 const (
@@ -32,6 +35,11 @@ func (c *Config) getInitialStyleSheetLinkElement() (*template.HTML, error) {
 		sb.WriteString(url)
 		sb.WriteString(`" id="`)
 		sb.WriteString(StyleSheetElementID)
+		if sri := c.GetPublicSRI(url); sri != "" {
+			sb.WriteString(`" integrity="`)
+			sb.WriteString(sri)
+			sb.WriteString(`" crossorigin="anonymous`)
+		}
 		sb.WriteString(`" />`)
 		result = template.HTML(sb.String())
 	}
@@ -62,9 +70,22 @@ func (c *Config) getInitialStyleSheetURL() (string, error) {
 		return "", err
 	}
 
-	return matcher.EnsureLeadingSlash(
-		path.Join(c._uc.Core.PublicPathPrefix, string(content)),
-	), nil
+	return c.to_public_asset_url(string(content)), nil
+}
+
+// Returns the on-disk path of the latest non-critical CSS bundle, or an empty
+// string if there is no non-critical CSS entry. Unlike GetStyleSheetURL, this
+// reads straight from the dist directory, so it is safe to call from build
+// hooks that run after Wave has processed CSS.
+func (c *Config) GetStyleSheetPathBuildtime() (string, error) {
+	if c._uc.Core.CSSEntryFiles.NonCritical == "" {
+		return "", nil
+	}
+	content, err := os.ReadFile(c._dist.S().Static.S().Internal.S().NormalCSSFileRefDotTXT.FullPath())
+	if err != nil {
+		return "", fmt.Errorf("error reading normal CSS file ref: %w", err)
+	}
+	return filepath.Join(c.GetStaticPublicOutDir(), string(content)), nil
 }
 
 func (c *Config) GetStyleSheetLinkElement() template.HTML {
@@ -119,7 +140,7 @@ func (c *Config) getInitialCriticalCSSStatus() (*criticalCSSStatus, error) {
 		return result, nil
 	}
 
-	result.code_str = string(content)
+	result.code_str = c.ResolveCSSURLs(string(content))
 
 	el := htmlutil.Element{
 		Tag:                 "style",
@@ -145,6 +166,28 @@ func (c *Config) getInitialCriticalCSSStatus() (*criticalCSSStatus, error) {
 	return result, nil
 }
 
+var css_url_re = regexp.MustCompile(`url\(\s*(["']?)([^"')\s]+)(["']?)\s*\)`)
+
+// Built CSS references public assets by paths relative to the public root,
+// where the bundles are served from, so that the public URL base can change
+// at runtime. ResolveCSSURLs prefixes those paths with the public URL base,
+// for CSS that is inlined into pages instead (e.g., critical CSS).
+func (c *Config) ResolveCSSURLs(css string) string {
+	return resolve_css_urls(css, c.GetPublicURLBase())
+}
+
+func resolve_css_urls(css, base string) string {
+	return css_url_re.ReplaceAllStringFunc(css, func(match string) string {
+		m := css_url_re.FindStringSubmatch(match)
+		ref := m[2]
+		if u, err := url.Parse(ref); err != nil || u.Scheme != "" ||
+			strings.HasPrefix(ref, "/") || strings.HasPrefix(ref, "#") {
+			return match
+		}
+		return "url(" + m[1] + base + ref + m[3] + ")"
+	})
+}
+
 func (c *Config) GetCriticalCSS() string {
 	result, _ := c.runtime_cache.critical_css.Get()
 	return result.code_str
@@ -0,0 +1,132 @@
+package ki
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Named stylesheets get an element ID of NamedStyleSheetElementIDPrefix + name
+// (e.g., "wave-css-admin"), which the dev refresh script uses to hot swap them.
+const NamedStyleSheetElementIDPrefix = "wave-css-"
+
+var named_css_entry_name_re = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+
+// Names end up in hashed filenames ("<name>_<hash>.css"), so they can't
+// contain underscores (or anything else that could collide with another
+// entry's output), and can't shadow the built-in "critical" and "normal"
+// entries.
+func isValidNamedCSSEntryName(name string) bool {
+	return name != "critical" && name != "normal" && named_css_entry_name_re.MatchString(name)
+}
+
+var namedCSSRefsMu sync.Mutex
+
+// Records the latest hashed output filename for a named CSS entry in
+// dist/static/internal/named_css_file_refs.json, and removes that entry's
+// previous output file, if any.
+func (c *Config) saveNamedCSSRef(name, outputFileName string) error {
+	namedCSSRefsMu.Lock()
+	defer namedCSSRefsMu.Unlock()
+
+	refsFile := c._dist.S().Static.S().Internal.S().NamedCSSFileRefsDotJSON.FullPath()
+
+	refs := map[string]string{}
+	if content, err := os.ReadFile(refsFile); err == nil {
+		if err := json.Unmarshal(content, &refs); err != nil {
+			return fmt.Errorf("error unmarshalling named CSS file refs: %w", err)
+		}
+	}
+
+	if old := refs[name]; old != "" && old != outputFileName {
+		err := os.Remove(filepath.Join(c._dist.S().Static.S().Assets.S().Public.FullPath(), old))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing old %s CSS file: %w", name, err)
+		}
+	}
+
+	// Drop entries that are no longer configured
+	for existing, old := range refs {
+		if _, ok := c.cleanSources.NamedCSSEntries[existing]; !ok {
+			os.Remove(filepath.Join(c._dist.S().Static.S().Assets.S().Public.FullPath(), old))
+			delete(refs, existing)
+		}
+	}
+	refs[name] = outputFileName
+
+	bytes, err := json.Marshal(refs)
+	if err != nil {
+		return fmt.Errorf("error marshalling named CSS file refs: %w", err)
+	}
+	return os.WriteFile(refsFile, bytes, 0644)
+}
+
+func (c *Config) getInitialNamedCSSRefs() (map[string]string, error) {
+	if len(c._uc.Core.CSSEntryFiles.Named) == 0 {
+		return map[string]string{}, nil
+	}
+
+	base_fs, err := c.GetBaseFS()
+	if err != nil {
+		c.Logger.Error(fmt.Sprintf("error getting FS: %v", err))
+		return nil, err
+	}
+
+	dist_wave_internal := c._dist.S().Static.S().Internal
+
+	// __LOCATION_ASSUMPTION: Inside "dist/static"
+	content, err := fs.ReadFile(base_fs, path.Join(
+		dist_wave_internal.LastSegment(),
+		dist_wave_internal.S().NamedCSSFileRefsDotJSON.LastSegment(),
+	))
+	if err != nil {
+		c.Logger.Error(fmt.Sprintf("error reading named CSS file refs: %v", err))
+		return nil, err
+	}
+
+	var refs map[string]string
+	if err := json.Unmarshal(content, &refs); err != nil {
+		c.Logger.Error(fmt.Sprintf("error unmarshalling named CSS file refs: %v", err))
+		return nil, err
+	}
+	return refs, nil
+}
+
+// Returns the public URL of the named CSS entry (as configured under
+// Core.CSSEntryFiles.Named), or an empty string if there is no such entry.
+func (c *Config) GetStyleSheetURLFor(name string) string {
+	refs, _ := c.runtime_cache.named_css_refs.Get()
+	ref := refs[name]
+	if ref == "" {
+		return ""
+	}
+	return c.to_public_asset_url(ref)
+}
+
+// Returns a stylesheet link element for the named CSS entry, or an empty
+// string if there is no such entry.
+func (c *Config) GetStyleSheetLinkElementFor(name string) template.HTML {
+	url := c.GetStyleSheetURLFor(name)
+	if url == "" {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(`<link rel="stylesheet" href="`)
+	sb.WriteString(url)
+	sb.WriteString(`" id="`)
+	sb.WriteString(NamedStyleSheetElementIDPrefix + name)
+	if sri := c.GetPublicSRI(url); sri != "" {
+		sb.WriteString(`" integrity="`)
+		sb.WriteString(sri)
+		sb.WriteString(`" crossorigin="anonymous`)
+	}
+	sb.WriteString(`" />`)
+	return template.HTML(sb.String())
+}
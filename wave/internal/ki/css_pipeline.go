@@ -0,0 +1,333 @@
+package ki
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/bmatcuk/doublestar/v4"
+	esbuild "github.com/evanw/esbuild/pkg/api"
+)
+
+/////////////////////////////////////////////////////////////////////
+/////// TRANSFORMS
+/////////////////////////////////////////////////////////////////////
+
+// Receives a bundled CSS entry ("critical" or "normal") and returns the
+// transformed CSS. Register these on Config.CSSTransformers.
+type CSSTransformer func(css []byte, nature string) ([]byte, error)
+
+const cssNatureEnvKey = "WAVE_CSS_NATURE"
+
+// Runs each bundled CSS entry through the configured transform chain before
+// it is hashed and written: first the Core.CSSTransformCmds commands (each
+// gets the CSS on stdin, must write the transformed CSS to stdout, and can
+// read the entry's nature from the WAVE_CSS_NATURE env var), then any Go
+// transformers set on Config.CSSTransformers, in order.
+func (c *Config) run_css_transforms(css []byte, nature string) ([]byte, error) {
+	for _, cmd := range c._uc.Core.CSSTransformCmds {
+		fields := strings.Fields(cmd)
+		if len(fields) == 0 {
+			continue
+		}
+		var stdout bytes.Buffer
+		execCmd := exec.Command(fields[0], fields[1:]...)
+		execCmd.Stdin = bytes.NewReader(css)
+		execCmd.Stdout = &stdout
+		execCmd.Stderr = os.Stderr
+		execCmd.Env = append(os.Environ(), cssNatureEnvKey+"="+nature)
+		if err := execCmd.Run(); err != nil {
+			return nil, fmt.Errorf("error running CSS transform command %q: %w", cmd, err)
+		}
+		css = stdout.Bytes()
+	}
+	for i, transformer := range c.CSSTransformers {
+		var err error
+		css, err = transformer(css, nature)
+		if err != nil {
+			return nil, fmt.Errorf("error running CSS transformer %d: %w", i, err)
+		}
+	}
+	return css, nil
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// CSS MODULES
+/////////////////////////////////////////////////////////////////////
+
+// file (relative, slash-separated) -> original class -> scoped class
+type cssModuleClassMap map[string]map[string]string
+
+// The class maps from the latest build of each CSS entry, keyed by nature,
+// so that rebuilding one entry doesn't drop the others' classes.
+type cssModulesState struct {
+	mu       sync.Mutex
+	byNature map[string]cssModuleClassMap
+}
+
+// Returns the esbuild plugin that scopes the class names in every imported
+// file matching Core.CSSModules.Pattern (relative to the working directory),
+// recording the renames in classMap. Returns nil if no pattern is set.
+func (c *Config) css_modules_plugins(classMap cssModuleClassMap) []esbuild.Plugin {
+	pattern := c._uc.Core.CSSModules.Pattern
+	if pattern == "" {
+		return nil
+	}
+	var mu sync.Mutex
+	return []esbuild.Plugin{{
+		Name: "wave-css-modules",
+		Setup: func(build esbuild.PluginBuild) {
+			build.OnLoad(esbuild.OnLoadOptions{Filter: `\.css$`, Namespace: "file"},
+				func(args esbuild.OnLoadArgs) (esbuild.OnLoadResult, error) {
+					key, err := css_module_key(args.Path)
+					if err != nil {
+						return esbuild.OnLoadResult{}, err
+					}
+					isMatch, err := doublestar.Match(pattern, key)
+					if err != nil || !isMatch {
+						return esbuild.OnLoadResult{}, err
+					}
+					src, err := os.ReadFile(args.Path)
+					if err != nil {
+						return esbuild.OnLoadResult{}, err
+					}
+					scoped, classes := scope_css_module(src, css_module_suffix(key))
+					mu.Lock()
+					classMap[key] = classes
+					mu.Unlock()
+					contents := string(scoped)
+					return esbuild.OnLoadResult{
+						Contents:   &contents,
+						Loader:     esbuild.LoaderCSS,
+						ResolveDir: filepath.Dir(args.Path),
+					}, nil
+				},
+			)
+		},
+	}}
+}
+
+func css_module_key(absPath string) (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(cwd, absPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func css_module_suffix(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return fmt.Sprintf("%x", sum[:4])
+}
+
+// Saves the class maps from the latest build of each entry to
+// dist/static/internal/css_modules.json.
+func (c *Config) save_css_modules(nature string, classMap cssModuleClassMap) error {
+	c.cssModules.mu.Lock()
+	if c.cssModules.byNature == nil {
+		c.cssModules.byNature = map[string]cssModuleClassMap{}
+	}
+	c.cssModules.byNature[nature] = classMap
+	merged := cssModuleClassMap{}
+	for _, m := range c.cssModules.byNature {
+		maps.Copy(merged, m)
+	}
+	c.cssModules.mu.Unlock()
+
+	bytes, err := json.Marshal(merged)
+	if err != nil {
+		return fmt.Errorf("error marshalling CSS modules map: %w", err)
+	}
+	return os.WriteFile(c._dist.S().Static.S().Internal.S().CSSModulesDotJSON.FullPath(), bytes, 0644)
+}
+
+func (c *Config) getInitialCSSModules() (cssModuleClassMap, error) {
+	base_fs, err := c.GetBaseFS()
+	if err != nil {
+		c.Logger.Error(fmt.Sprintf("error getting FS: %v", err))
+		return nil, err
+	}
+
+	dist_wave_internal := c._dist.S().Static.S().Internal
+
+	// __LOCATION_ASSUMPTION: Inside "dist/static"
+	content, err := fs.ReadFile(base_fs, path.Join(
+		dist_wave_internal.LastSegment(),
+		dist_wave_internal.S().CSSModulesDotJSON.LastSegment(),
+	))
+	if err != nil {
+		// Not using CSS modules
+		return cssModuleClassMap{}, nil
+	}
+
+	var classMap cssModuleClassMap
+	if err := json.Unmarshal(content, &classMap); err != nil {
+		c.Logger.Error(fmt.Sprintf("error unmarshalling CSS modules map: %v", err))
+		return nil, err
+	}
+	return classMap, nil
+}
+
+// Returns the scoped name for a class defined in a CSS module file (given
+// relative to the directory Wave runs from, e.g. "styles/card.module.css").
+// If the file or class is unknown, the class is returned unchanged.
+func (c *Config) GetCSSModuleClass(file, class string) string {
+	if scoped, ok := c.GetCSSModuleClasses(file)[class]; ok {
+		return scoped
+	}
+	return class
+}
+
+// Returns all original -> scoped class names for a CSS module file.
+func (c *Config) GetCSSModuleClasses(file string) map[string]string {
+	classMap, _ := c.runtime_cache.css_modules.Get()
+	return classMap[path.Clean(filepath.ToSlash(file))]
+}
+
+// Renames every class selector in src (e.g. ".card" -> ".card_1a2b3c4d"),
+// except within :global(...), which is unwrapped. Only rule preludes are
+// touched; declarations, at-rule preludes, comments and strings are copied
+// as is.
+func scope_css_module(src []byte, suffix string) ([]byte, map[string]string) {
+	classes := map[string]string{}
+	var out, seg bytes.Buffer
+	s := string(src)
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				seg.WriteString(s[i:])
+				i = len(s)
+				continue
+			}
+			seg.WriteString(s[i : i+2+end+2])
+			i += 2 + end + 1
+		case ch == '"' || ch == '\'':
+			j := skip_css_string(s, i)
+			seg.WriteString(s[i:j])
+			i = j - 1
+		case ch == '{':
+			prelude := seg.String()
+			if strings.HasPrefix(strings.TrimSpace(prelude), "@") {
+				out.WriteString(prelude)
+			} else {
+				out.WriteString(scope_css_prelude(prelude, suffix, classes))
+			}
+			out.WriteByte('{')
+			seg.Reset()
+		case ch == ';' || ch == '}':
+			out.Write(seg.Bytes())
+			out.WriteByte(ch)
+			seg.Reset()
+		default:
+			seg.WriteByte(ch)
+		}
+	}
+	out.Write(seg.Bytes())
+	return out.Bytes(), classes
+}
+
+func scope_css_prelude(prelude, suffix string, classes map[string]string) string {
+	var sb strings.Builder
+	s := prelude
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				sb.WriteString(s[i:])
+				return sb.String()
+			}
+			sb.WriteString(s[i : i+2+end+2])
+			i += 2 + end + 1
+		case ch == '"' || ch == '\'':
+			j := skip_css_string(s, i)
+			sb.WriteString(s[i:j])
+			i = j - 1
+		case ch == '\\' && i+1 < len(s):
+			sb.WriteString(s[i : i+2])
+			i++
+		case ch == '[':
+			j := i + 1
+			for j < len(s) && s[j] != ']' {
+				if s[j] == '"' || s[j] == '\'' {
+					j = skip_css_string(s, j)
+					continue
+				}
+				j++
+			}
+			j = min(j+1, len(s))
+			sb.WriteString(s[i:j])
+			i = j - 1
+		case strings.HasPrefix(s[i:], ":global("):
+			start := i + len(":global(")
+			depth, j := 1, start
+			for j < len(s) && depth > 0 {
+				switch s[j] {
+				case '(':
+					depth++
+				case ')':
+					depth--
+				}
+				j++
+			}
+			sb.WriteString(s[start : j-1])
+			i = j - 1
+		case ch == '.' && i+1 < len(s) && is_css_ident_start(s[i+1]) && (i == 0 || !is_css_ident_char(s[i-1])):
+			j := i + 1
+			for j < len(s) && is_css_ident_char(s[j]) {
+				j++
+			}
+			class := s[i+1 : j]
+			scoped := class + "_" + suffix
+			classes[class] = scoped
+			sb.WriteByte('.')
+			sb.WriteString(scoped)
+			i = j - 1
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	return sb.String()
+}
+
+// Returns the index just past the closing quote of the string starting at i.
+func skip_css_string(s string, i int) int {
+	quote := s[i]
+	j := i + 1
+	for j < len(s) {
+		if s[j] == '\\' {
+			j += 2
+			continue
+		}
+		if s[j] == quote {
+			return j + 1
+		}
+		j++
+	}
+	return len(s)
+}
+
+func is_css_ident_start(b byte) bool {
+	return b == '_' || b == '-' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || b >= 0x80
+}
+
+func is_css_ident_char(b byte) bool {
+	return is_css_ident_start(b) || (b >= '0' && b <= '9')
+}
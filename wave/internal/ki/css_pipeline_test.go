@@ -0,0 +1,175 @@
+package ki
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScopeCSSModule(t *testing.T) {
+	src := `/* .comment {} */
+.card, .card:hover > .title_2 { color: red; }
+:global(.external) .card { content: ".not-a-class"; }
+a[href$=".pdf"] { color: blue; }
+@media (min-width: 10.5em) { .card { margin: .5rem; } }
+`
+	out, classes := scope_css_module([]byte(src), "abcd1234")
+	got := string(out)
+
+	for _, want := range []string{
+		`/* .comment {} */`,
+		`.card_abcd1234, .card_abcd1234:hover > .title_2_abcd1234 {`,
+		`.external .card_abcd1234 { content: ".not-a-class"; }`,
+		`a[href$=".pdf"]`,
+		`@media (min-width: 10.5em) { .card_abcd1234 { margin: .5rem; } }`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+
+	if len(classes) != 2 || classes["card"] != "card_abcd1234" || classes["title_2"] != "title_2_abcd1234" {
+		t.Errorf("classes = %v", classes)
+	}
+}
+
+func TestRunCSSTransforms(t *testing.T) {
+	t.Run("GoTransformers", func(t *testing.T) {
+		c := &Config{_uc: &UserConfig{Core: &UserConfigCore{}}}
+		var natures []string
+		c.CSSTransformers = []CSSTransformer{
+			func(css []byte, nature string) ([]byte, error) {
+				natures = append(natures, nature)
+				return bytes.ReplaceAll(css, []byte("red"), []byte("blue")), nil
+			},
+			func(css []byte, nature string) ([]byte, error) {
+				return append(css, "/* done */"...), nil
+			},
+		}
+		out, err := c.run_css_transforms([]byte("p{color:red}"), "normal")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != "p{color:blue}/* done */" {
+			t.Errorf("out = %q", out)
+		}
+		if len(natures) != 1 || natures[0] != "normal" {
+			t.Errorf("natures = %v", natures)
+		}
+	})
+
+	t.Run("Commands", func(t *testing.T) {
+		if _, err := exec.LookPath("sed"); err != nil {
+			t.Skip("sed not available")
+		}
+		c := &Config{_uc: &UserConfig{Core: &UserConfigCore{
+			CSSTransformCmds: []string{"sed s/red/green/"},
+		}}}
+		c.CSSTransformers = []CSSTransformer{
+			func(css []byte, nature string) ([]byte, error) {
+				return bytes.ToUpper(css), nil
+			},
+		}
+		out, err := c.run_css_transforms([]byte("p{color:red}"), "critical")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != "P{COLOR:GREEN}" {
+			t.Errorf("out = %q, want commands to run before Go transformers", out)
+		}
+	})
+
+	t.Run("CommandFailure", func(t *testing.T) {
+		c := &Config{_uc: &UserConfig{Core: &UserConfigCore{
+			CSSTransformCmds: []string{"definitely-not-a-real-css-tool"},
+		}}}
+		if _, err := c.run_css_transforms([]byte("p{}"), "normal"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func TestBuildCSSModules(t *testing.T) {
+	env := setupTestEnv(t)
+	defer teardownTestEnv(t)
+
+	env.config._uc.Core.CSSModules.Pattern = "**/*.module.css"
+	env.createTestFile(t, "critical.css", "body { color: red; }")
+	env.createTestFile(t, "card.module.css", ".card { color: red; }")
+	env.createTestFile(t, "main.css", `@import "./card.module.css"; .card { color: blue; }`)
+
+	if err := env.config.buildCSS(); err != nil {
+		t.Fatalf("buildCSS() error = %v", err)
+	}
+
+	key := filepath.ToSlash(filepath.Join(testRootDir, "card.module.css"))
+	scoped := "card_" + css_module_suffix(key)
+
+	if got := env.config.GetCSSModuleClass(key, "card"); got != scoped {
+		t.Errorf("GetCSSModuleClass() = %q, want %q", got, scoped)
+	}
+	if got := env.config.GetCSSModuleClass("./"+key, "unknown"); got != "unknown" {
+		t.Errorf("unknown class should be returned unchanged, got %q", got)
+	}
+
+	ref, err := os.ReadFile(filepath.Join(testRootDir, "dist/static/internal/normal_css_file_ref.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	css, err := os.ReadFile(filepath.Join(testRootDir, "dist/static/assets/public", string(ref)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The module is scoped, while the non-module entry file is left alone.
+	if !strings.Contains(string(css), "."+scoped+"{color:red}") || !strings.Contains(string(css), ".card{color:#00f}") {
+		t.Errorf("unexpected CSS output: %s", css)
+	}
+}
+
+func TestSaveCSSModules(t *testing.T) {
+	env := setupTestEnv(t)
+	defer teardownTestEnv(t)
+
+	read := func(t *testing.T) cssModuleClassMap {
+		t.Helper()
+		b, err := os.ReadFile(filepath.Join(testRootDir, "dist/static/internal/css_modules.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var m cssModuleClassMap
+		if err := json.Unmarshal(b, &m); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	a := cssModuleClassMap{"a.module.css": {"a": "a_1"}}
+	b := cssModuleClassMap{"b.module.css": {"b": "b_1"}}
+
+	t.Run("MergesEntries", func(t *testing.T) {
+		if err := env.config.save_css_modules("normal", a); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.config.save_css_modules("critical", b); err != nil {
+			t.Fatal(err)
+		}
+		if got := read(t); len(got) != 2 {
+			t.Errorf("expected both entries' classes, got %v", got)
+		}
+	})
+
+	t.Run("NotSharedBetweenConfigs", func(t *testing.T) {
+		other := &Config{_dist: env.config._dist}
+		if err := other.save_css_modules("critical", b); err != nil {
+			t.Fatal(err)
+		}
+		if got := read(t); !reflect.DeepEqual(got, b) {
+			t.Errorf("expected only the other config's classes, got %v", got)
+		}
+	})
+}
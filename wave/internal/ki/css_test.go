@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/river-now/river/kit/htmltestutil"
+	"github.com/river-now/river/kit/safecache"
 )
 
 func TestGetCriticalCSS(t *testing.T) {
@@ -126,3 +127,100 @@ func TestBuildCSS(t *testing.T) {
 		t.Errorf("Processed normal CSS = %v, want: %v", string(processedNormalCSS), minimizedNormalCSS)
 	}
 }
+
+func TestBuildNamedCSS(t *testing.T) {
+	env := setupTestEnv(t)
+	defer teardownTestEnv(t)
+
+	named := map[string]string{"admin": filepath.Join(testRootDir, "admin.css")}
+	env.config._uc.Core.CSSEntryFiles.Named = named
+	env.config.cleanSources.NamedCSSEntries = named
+
+	env.createTestFile(t, "critical.css", "body { color: red; }")
+	env.createTestFile(t, "main.css", "p { font-size: 16px; }")
+	env.createTestFile(t, "admin.css", "table { width: 100%; }")
+
+	if err := env.config.buildCSS(); err != nil {
+		t.Fatalf("buildCSS() error = %v", err)
+	}
+
+	url := env.config.GetStyleSheetURLFor("admin")
+	if !strings.HasPrefix(url, "/bob/admin_") || !strings.HasSuffix(url, ".css") {
+		t.Fatalf("GetStyleSheetURLFor() = %v", url)
+	}
+	publicDir := filepath.Join(testRootDir, "dist/static/assets/public")
+	firstFile := filepath.Join(publicDir, strings.TrimPrefix(url, "/bob/"))
+	content, err := os.ReadFile(firstFile)
+	if err != nil {
+		t.Fatalf("Failed to read named CSS output: %v", err)
+	}
+	if string(content) != "table{width:100%}\n" {
+		t.Errorf("named CSS = %q", content)
+	}
+
+	el := env.config.GetStyleSheetLinkElementFor("admin")
+	expected := template.HTML(`<link rel="stylesheet" href="` + url + `" id="` + NamedStyleSheetElementIDPrefix + `admin" />`)
+	if el != expected {
+		t.Errorf("GetStyleSheetLinkElementFor() = %v, want: %v", el, expected)
+	}
+
+	if env.config.GetStyleSheetURLFor("nope") != "" || env.config.GetStyleSheetLinkElementFor("nope") != "" {
+		t.Error("unknown names should return empty values")
+	}
+
+	// Rebuilding with changed content replaces the old output file
+	env.createTestFile(t, "admin.css", "table { width: 50%; }")
+	if err := env.config.processCSSNamed("admin"); err != nil {
+		t.Fatalf("processCSSNamed() error = %v", err)
+	}
+	env.config.runtime_cache.named_css_refs = safecache.New(env.config.getInitialNamedCSSRefs, nil)
+	if newURL := env.config.GetStyleSheetURLFor("admin"); newURL == url {
+		t.Errorf("expected a new hashed URL after rebuild, got %v", newURL)
+	}
+	if _, err := os.Stat(firstFile); !os.IsNotExist(err) {
+		t.Errorf("old named CSS file should have been removed, stat err = %v", err)
+	}
+}
+
+func TestIsValidNamedCSSEntryName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"admin":       true,
+		"admin-area2": true,
+		"admin_area":  false,
+		"normal":      false,
+		"critical":    false,
+		"":            false,
+		"a/b":         false,
+	} {
+		if got := isValidNamedCSSEntryName(name); got != want {
+			t.Errorf("isValidNamedCSSEntryName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestResolveCSSURLs(t *testing.T) {
+	tests := []struct {
+		name string
+		css  string
+		base string
+		want string
+	}{
+		{"Relative", `a{background:url(img_abc.png)}`, "/public/", `a{background:url(/public/img_abc.png)}`},
+		{"Quoted", `a{background:url( "img_abc.png" )}`, "/public/", `a{background:url("/public/img_abc.png")}`},
+		{"SingleQuoted", `a{background:url('img_abc.png')}`, "/public/", `a{background:url('/public/img_abc.png')}`},
+		{"AssetBaseURL", `a{background:url(img_abc.png)}`, "https://cdn.example.com/app/", `a{background:url(https://cdn.example.com/app/img_abc.png)}`},
+		{"DataURI", `a{background:url(data:image/png;base64,AAAA)}`, "/public/", `a{background:url(data:image/png;base64,AAAA)}`},
+		{"Absolute", `a{background:url(https://example.com/x.png)}`, "/public/", `a{background:url(https://example.com/x.png)}`},
+		{"RootRelative", `a{background:url(/x.png)}`, "/public/", `a{background:url(/x.png)}`},
+		{"ProtocolRelative", `a{background:url(//example.com/x.png)}`, "/public/", `a{background:url(//example.com/x.png)}`},
+		{"Fragment", `a{filter:url(#blur)}`, "/public/", `a{filter:url(#blur)}`},
+		{"Multiple", `@font-face{src:url(a.woff2),url("b.woff")}`, "/p/", `@font-face{src:url(/p/a.woff2),url("/p/b.woff")}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolve_css_urls(tt.css, tt.base); got != tt.want {
+				t.Errorf("resolve_css_urls() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
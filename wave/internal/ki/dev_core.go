@@ -28,6 +28,10 @@ func (c *Config) MustStartDev(_opts ...must_start_dev_opts) {
 
 	MustGetAppPort() // Warm port right away, in case default is unavailable. Also, env needs to be set in this scope.
 
+	if c._uc.Watch.DevProxy.Enabled && c.devProxy == nil {
+		c.must_start_dev_proxy()
+	}
+
 	refresh_server_port, err := netutil.GetFreePort(default_refresh_server_port)
 	if err != nil {
 		c.panic("failed to get free port", err)
@@ -0,0 +1,332 @@
+package ki
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/river-now/river/kit/middleware"
+)
+
+type devErrorKind string
+
+const (
+	devErrorKindBuild devErrorKind = "build"
+	devErrorKindHook  devErrorKind = "hook"
+	devErrorKindPanic devErrorKind = "panic"
+)
+
+const (
+	dev_error_max_locations    = 10
+	dev_error_max_output_bytes = 16 * 1024
+	dev_error_snippet_context  = 2
+)
+
+// Sent to the browser (via the refresh websocket) to render the dev error
+// overlay.
+type devError struct {
+	Kind      devErrorKind       `json:"kind"`
+	Title     string             `json:"title"`
+	Output    string             `json:"output"`
+	Locations []devErrorLocation `json:"locations,omitempty"`
+}
+
+type devErrorLocation struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message,omitempty"`
+	Snippet string `json:"snippet,omitempty"`
+}
+
+type devErrorState struct {
+	mu   sync.Mutex
+	last *devError
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// REPORTING
+/////////////////////////////////////////////////////////////////////
+
+// Remembers the error (so that tabs that connect later, e.g. after a manual
+// reload, still see it) and pushes it to all connected tabs.
+func (c *Config) report_dev_error(e *devError) {
+	if !c.is_using_browser() || c.browserTabManager == nil {
+		return
+	}
+	c.devErrors.mu.Lock()
+	c.devErrors.last = e
+	c.devErrors.mu.Unlock()
+	c.browserTabManager.broadcast <- refreshFilePayload{ChangeType: changeTypeError, Error: e}
+}
+
+// Clears the current error if it is of one of the given kinds, and tells
+// connected tabs to dismiss the overlay.
+func (c *Config) clear_dev_error(kinds ...devErrorKind) {
+	if !c.is_using_browser() || c.browserTabManager == nil {
+		return
+	}
+	c.devErrors.mu.Lock()
+	shouldClear := c.devErrors.last != nil && slices.Contains(kinds, c.devErrors.last.Kind)
+	if shouldClear {
+		c.devErrors.last = nil
+	}
+	c.devErrors.mu.Unlock()
+	if shouldClear {
+		c.browserTabManager.broadcast <- refreshFilePayload{ChangeType: changeTypeErrorCleared}
+	}
+}
+
+func (c *Config) get_last_dev_error() *devError {
+	c.devErrors.mu.Lock()
+	defer c.devErrors.mu.Unlock()
+	return c.devErrors.last
+}
+
+func new_build_dev_error(output string) *devError {
+	return &devError{
+		Kind:      devErrorKindBuild,
+		Title:     "Go build failed",
+		Output:    truncate_dev_error_output(output),
+		Locations: parse_error_locations(output),
+	}
+}
+
+func new_hook_dev_error(cmd string, err error, output string) *devError {
+	return &devError{
+		Kind:      devErrorKindHook,
+		Title:     fmt.Sprintf("OnChangeHook failed: %s (%v)", cmd, err),
+		Output:    truncate_dev_error_output(output),
+		Locations: parse_error_locations(output),
+	}
+}
+
+func new_panic_dev_error(message, stack string) *devError {
+	e := &devError{
+		Kind:   devErrorKindPanic,
+		Title:  "panic: " + message,
+		Output: truncate_dev_error_output(stack),
+	}
+	if loc, ok := parse_panic_location(stack); ok {
+		e.Locations = []devErrorLocation{loc}
+	}
+	return e
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// PARSING
+/////////////////////////////////////////////////////////////////////
+
+// Matches compiler-style locations, e.g. "./app/main.go:12:5: undefined: x"
+var error_location_re = regexp.MustCompile(`^\s*(\S+?\.[A-Za-z0-9]+):(\d+)(?::(\d+))?:\s*(.*)$`)
+
+func parse_error_locations(output string) []devErrorLocation {
+	var locations []devErrorLocation
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() && len(locations) < dev_error_max_locations {
+		m := error_location_re.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		line, _ := strconv.Atoi(m[2])
+		col, _ := strconv.Atoi(m[3])
+		locations = append(locations, devErrorLocation{
+			File:    m[1],
+			Line:    line,
+			Column:  col,
+			Message: m[4],
+			Snippet: read_snippet(m[1], line),
+		})
+	}
+	return locations
+}
+
+// In a debug.Stack() trace, the frame that panicked is the first non-runtime
+// frame after the "panic(...)" frame (runtime errors such as nil dereferences
+// add runtime.sigpanic etc. in between). Each frame is a function line
+// followed by a tab-indented "file:line +0x..." line.
+func parse_panic_location(stack string) (devErrorLocation, bool) {
+	lines := strings.Split(stack, "\n")
+	for i, l := range lines {
+		if !strings.HasPrefix(l, "panic(") {
+			continue
+		}
+		i += 2
+		for i+1 < len(lines) && strings.HasPrefix(lines[i], "runtime.") {
+			i += 2
+		}
+		if i+1 >= len(lines) {
+			return devErrorLocation{}, false
+		}
+		fileAndLine, _, _ := strings.Cut(strings.TrimSpace(lines[i+1]), " ")
+		idx := strings.LastIndexByte(fileAndLine, ':')
+		if idx < 0 {
+			return devErrorLocation{}, false
+		}
+		line, err := strconv.Atoi(fileAndLine[idx+1:])
+		if err != nil {
+			return devErrorLocation{}, false
+		}
+		file := fileAndLine[:idx]
+		return devErrorLocation{
+			File:    file,
+			Line:    line,
+			Message: strings.TrimSpace(lines[i]),
+			Snippet: read_snippet(file, line),
+		}, true
+	}
+	return devErrorLocation{}, false
+}
+
+// e.g.:
+//
+//	  10 | func main() {
+//	> 11 | 	x := y
+//	  12 | }
+//
+// Only files inside the project root are read, since locations can come from
+// reports posted to the refresh server.
+func read_snippet(file string, line int) string {
+	if !is_in_project_root(file) {
+		return ""
+	}
+	content, err := os.ReadFile(file)
+	if err != nil || line < 1 {
+		return ""
+	}
+	lines := strings.Split(string(content), "\n")
+	if line > len(lines) {
+		return ""
+	}
+	start := max(1, line-dev_error_snippet_context)
+	end := min(len(lines), line+dev_error_snippet_context)
+	width := len(strconv.Itoa(end))
+	var sb strings.Builder
+	for n := start; n <= end; n++ {
+		marker := "  "
+		if n == line {
+			marker = "> "
+		}
+		fmt.Fprintf(&sb, "%s%*d | %s\n", marker, width, n, lines[n-1])
+	}
+	return sb.String()
+}
+
+// The project root is the working directory that Wave runs from. Symlinks are
+// resolved on both sides, so a link inside the root can't point outside of it.
+func is_in_project_root(file string) bool {
+	root, err := os.Getwd()
+	if err != nil {
+		return false
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return false
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return false
+	}
+	if abs, err = filepath.EvalSymlinks(abs); err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func truncate_dev_error_output(output string) string {
+	if len(output) <= dev_error_max_output_bytes {
+		return output
+	}
+	return output[:dev_error_max_output_bytes] + "\n... (truncated)"
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// PANIC REPORTING (RUNS IN THE APP PROCESS)
+/////////////////////////////////////////////////////////////////////
+
+type devPanicReport struct {
+	Message string `json:"message"`
+	Stack   string `json:"stack"`
+}
+
+const report_error_path = "/report-error"
+
+// Reports only ever come from the app process (see send_dev_panic_report), so
+// anything sent by a browser (which always sets Origin on cross-origin POSTs)
+// is refused. Requiring a JSON content type also means a browser can't send a
+// report without a CORS preflight, which this server never approves.
+func (c *Config) handle_dev_panic_report(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Origin") != "" {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		http.Error(w, "unsupported media type", http.StatusUnsupportedMediaType)
+		return
+	}
+	var report devPanicReport
+	if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.report_dev_error(new_panic_dev_error(report.Message, report.Stack))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// In dev mode, reports recovered handler panics to the Wave dev server so
+// that they show up in the browser's error overlay, then re-panics so that
+// your own recovery middleware still handles the response. Register it
+// inside (after) your recoverer. In prod, this is a no-op.
+func (c *Config) ReportPanicsToDevOverlay() middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		if !GetIsDev() {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if rec := recover(); rec != nil {
+					if rec != http.ErrAbortHandler {
+						send_dev_panic_report(fmt.Sprint(rec), string(debug.Stack()))
+					}
+					panic(rec)
+				}
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func send_dev_panic_report(message, stack string) {
+	port := getRefreshServerPort()
+	if port == 0 {
+		return
+	}
+	body, err := json.Marshal(devPanicReport{Message: message, Stack: stack})
+	if err != nil {
+		return
+	}
+	client := &http.Client{Timeout: time.Second}
+	resp, err := client.Post(
+		fmt.Sprintf("http://localhost:%d%s", port, report_error_path),
+		"application/json",
+		bytes.NewReader(body),
+	)
+	if err == nil {
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,228 @@
+package ki
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseErrorLocations(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir) // snippets are only read from inside the project root
+	file := filepath.Join(dir, "main.go")
+	src := "package main\n\nfunc main() {\n\tx := y\n}\n"
+	if err := os.WriteFile(file, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output := "# example.com/app\n" + file + ":4:7: undefined: y\nsome other line\n"
+	locations := parse_error_locations(output)
+	if len(locations) != 1 {
+		t.Fatalf("got %d locations, want 1", len(locations))
+	}
+	loc := locations[0]
+	if loc.File != file || loc.Line != 4 || loc.Column != 7 || loc.Message != "undefined: y" {
+		t.Errorf("location = %+v", loc)
+	}
+	wantSnippet := "  2 | \n  3 | func main() {\n> 4 | \tx := y\n  5 | }\n  6 | \n"
+	if loc.Snippet != wantSnippet {
+		t.Errorf("snippet = %q, want %q", loc.Snippet, wantSnippet)
+	}
+}
+
+func TestReadSnippetOutsideProjectRoot(t *testing.T) {
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("secret\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(root)
+
+	for _, file := range []string{outside, "../" + filepath.Base(filepath.Dir(outside)) + "/secret.txt", "link.txt"} {
+		if snippet := read_snippet(file, 1); snippet != "" {
+			t.Errorf("read_snippet(%q) = %q, want empty", file, snippet)
+		}
+	}
+}
+
+func panicAndCaptureStack() (stack string) {
+	defer func() {
+		recover()
+		stack = string(debug.Stack())
+	}()
+	var m map[string]int
+	m["boom"] = 1 // runtime error, so runtime frames sit between panic() and here
+	return ""
+}
+
+func TestParsePanicLocation(t *testing.T) {
+	stack := panicAndCaptureStack()
+	loc, ok := parse_panic_location(stack)
+	if !ok {
+		t.Fatalf("could not parse stack:\n%s", stack)
+	}
+	if filepath.Base(loc.File) != "dev_errors_test.go" {
+		t.Errorf("file = %s, want dev_errors_test.go", loc.File)
+	}
+	if !strings.Contains(loc.Message, "panicAndCaptureStack") {
+		t.Errorf("message = %q, want the panicking function", loc.Message)
+	}
+	if !strings.Contains(loc.Snippet, `> `+strconv.Itoa(loc.Line)) || !strings.Contains(loc.Snippet, `m["boom"] = 1`) {
+		t.Errorf("snippet = %q", loc.Snippet)
+	}
+}
+
+func TestDevErrorReportAndClear(t *testing.T) {
+	c := &Config{_uc: &UserConfig{Core: &UserConfigCore{}}}
+	c.browserTabManager = newClientManager()
+	received := make(chan refreshFilePayload, 10)
+	go func() {
+		for msg := range c.browserTabManager.broadcast {
+			received <- msg
+		}
+	}()
+
+	c.report_dev_error(new_build_dev_error("oops"))
+	if msg := <-received; msg.ChangeType != changeTypeError || msg.Error.Kind != devErrorKindBuild {
+		t.Errorf("got %+v, want build error", msg)
+	}
+	if c.get_last_dev_error() == nil {
+		t.Fatal("expected last error to be remembered")
+	}
+
+	c.clear_dev_error(devErrorKindHook)
+	if c.get_last_dev_error() == nil {
+		t.Error("clearing a different kind should not clear the error")
+	}
+
+	c.clear_dev_error(devErrorKindBuild)
+	if msg := <-received; msg.ChangeType != changeTypeErrorCleared {
+		t.Errorf("got %+v, want errorcleared", msg)
+	}
+	if c.get_last_dev_error() != nil {
+		t.Error("expected error to be cleared")
+	}
+
+	select {
+	case msg := <-received:
+		t.Errorf("unexpected extra message %+v", msg)
+	default:
+	}
+}
+
+func TestHandleDevPanicReport(t *testing.T) {
+	c := &Config{_uc: &UserConfig{Core: &UserConfigCore{}}}
+	c.browserTabManager = newClientManager()
+	go func() {
+		for range c.browserTabManager.broadcast {
+		}
+	}()
+
+	body := `{"message":"boom","stack":"goroutine 1"}`
+	tests := []struct {
+		name        string
+		origin      string
+		contentType string
+		wantStatus  int
+	}{
+		{"FromAppProcess", "", "application/json", http.StatusNoContent},
+		{"FromBrowser", "http://evil.example", "application/json", http.StatusForbidden},
+		{"SimpleRequest", "", "text/plain", http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, report_error_path, strings.NewReader(body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rr := httptest.NewRecorder()
+			c.handle_dev_panic_report(rr, req)
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestWebsocketCheckOrigin(t *testing.T) {
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},
+		{"http://localhost:3000", true},
+		{"http://127.0.0.1:3000", true},
+		{"http://[::1]:3000", true},
+		{"https://evil.example", false},
+		{"http://localhost.evil.example", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/events", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if got := upgrader.CheckOrigin(req); got != tt.want {
+			t.Errorf("CheckOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestReportPanicsToDevOverlay(t *testing.T) {
+	reports := make(chan devPanicReport, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != report_error_path {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		var report devPanicReport
+		json.NewDecoder(r.Body).Decode(&report)
+		reports <- report
+	}))
+	defer srv.Close()
+
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	t.Setenv(refreshServerPortKey, port)
+	t.Setenv(modeKey, devModeVal)
+
+	c := &Config{}
+	handler := c.ReportPanicsToDevOverlay()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("kaboom")
+	}))
+
+	func() {
+		defer func() {
+			if rec := recover(); rec != "kaboom" {
+				t.Errorf("expected re-panic with original value, got %v", rec)
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+
+	report := <-reports
+	if report.Message != "kaboom" || !strings.Contains(report.Stack, "goroutine") {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+func TestGetRefreshServerOrigins(t *testing.T) {
+	t.Setenv(refreshServerPortKey, "10001")
+	c := &Config{}
+	if got := c.GetRefreshServerOrigins(); got != nil {
+		t.Errorf("outside dev, got %v, want nil", got)
+	}
+	t.Setenv(modeKey, devModeVal)
+	got := c.GetRefreshServerOrigins()
+	if len(got) != 2 || got[0] != "http://localhost:10001" || got[1] != "ws://localhost:10001" {
+		t.Errorf("got %v", got)
+	}
+}
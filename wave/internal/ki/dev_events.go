@@ -128,6 +128,8 @@ func (c *Config) process_batched_events(events []fsnotify.Event) {
 		err := c.mustHandleFileChange(evtDetails, hasMultipleEvents)
 		if err != nil {
 			c.Logger.Error(fmt.Sprintf("error: failed to handle file change: %v", err))
+			_ = eg.Wait() // Let any shutdown finish first, so it can't mark the proxy unready again
+			c.fail_dev_proxy(err)
 			return
 		}
 	}
@@ -160,7 +162,7 @@ func getNeedsHardReloadEvenIfNonGo(wfc *WatchedFile) bool {
 func (c *Config) mustHandleFileChange(
 	evtDetails *EvtDetails,
 	isPartOfBatch bool,
-) error {
+) (err error) {
 	wfc := evtDetails.wfc
 	if wfc == nil {
 		wfc = &WatchedFile{}
@@ -183,6 +185,13 @@ func (c *Config) mustHandleFileChange(
 			c.kill_running_go_binary()
 			return nil
 		})
+		// If the build fails, the app stays down, so make sure the
+		// shutdown is done before the caller reports the failure.
+		defer func() {
+			if err != nil {
+				_ = killAndRestartEG.Wait()
+			}
+		}()
 	}
 
 	sortedOnChanges := sortOnChangeCallbacks(wfc.OnChangeHooks)
@@ -234,6 +243,10 @@ func (c *Config) mustHandleFileChange(
 		return err
 	}
 
+	if sortedOnChanges.exists {
+		c.clear_dev_error(devErrorKindHook)
+	}
+
 	if needsKillAndRestart {
 		if err := killAndRestartEG.Wait(); err != nil {
 			c.Logger.Error(fmt.Sprintf("error: failed to kill app: %v", err))
@@ -272,6 +285,25 @@ func (c *Config) mustHandleFileChange(
 	}
 	// At this point, we know it's a CSS file
 
+	for _, name := range evtDetails.namedCSS {
+		c.must_reload_broadcast(
+			refreshFilePayload{
+				ChangeType: changeTypeNamedCSS,
+				// Must be called AFTER ProcessCSS
+				NamedCSS: &namedCSSPayload{Name: name, URL: c.GetStyleSheetURLFor(name)},
+			},
+			must_reload_broadcast_opts{
+				wait_for_app:  false,
+				wait_for_vite: false,
+				message:       fmt.Sprintf("Hot reloading browser (CSS: %s)", name),
+			},
+		)
+	}
+
+	if !evtDetails.isCriticalCSS && !evtDetails.isNormalCSS {
+		return nil
+	}
+
 	cssType := changeTypeNormalCSS
 	if evtDetails.isCriticalCSS {
 		cssType = changeTypeCriticalCSS
@@ -295,7 +327,10 @@ func (c *Config) mustHandleFileChange(
 
 func (c *Config) callback(wfc *WatchedFile, evtDetails *EvtDetails) error {
 	if evtDetails.isGo {
-		return c.compile_go_binary()
+		err := c.compile_go_binary()
+		// Imports may have changed, whether or not the build succeeded
+		go c.refresh_go_dep_graph()
+		return err
 	}
 
 	if evtDetails.isWaveCSS {
@@ -304,9 +339,12 @@ func (c *Config) callback(wfc *WatchedFile, evtDetails *EvtDetails) error {
 		}
 		if evtDetails.isCriticalCSS {
 			c.processCSSCritical()
-		} else {
+		} else if evtDetails.isNormalCSS {
 			c.processCSSNormal()
 		}
+		for _, name := range evtDetails.namedCSS {
+			c.processCSSNamed(name)
+		}
 	}
 
 	return c.runOtherFileBuild(wfc, evtDetails)
@@ -0,0 +1,300 @@
+package ki
+
+import (
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/river-now/river/kit/netutil"
+)
+
+const (
+	default_dev_proxy_hold_timeout       = 30 * time.Second
+	default_dev_proxy_rebuild_page_after = 2 * time.Second
+	dev_proxy_health_poll_interval       = 50 * time.Millisecond
+)
+
+// Sits on the public dev port and forwards to the app, which listens on an
+// internal port. While the app is being restarted, incoming requests are held
+// until the app's healthcheck endpoint responds again, so clients never see a
+// refused connection. Browser navigations that wait longer than
+// RebuildPageAfterMs get a self-refreshing "rebuilding" page instead. If the
+// rebuild fails while the app is down, held and new requests get the build
+// error right away until the next restart.
+type devProxy struct {
+	mu         sync.Mutex
+	ready      bool
+	failure    *devProxyFailure // set if the rebuild failed while the app was down
+	readyCh    chan struct{}    // closed when the app becomes ready or the rebuild fails
+	generation uint64
+
+	rp               *httputil.ReverseProxy
+	holdTimeout      time.Duration
+	rebuildPageAfter time.Duration
+}
+
+func newDevProxy(target *url.URL, opts DevProxy) *devProxy {
+	p := &devProxy{
+		readyCh:          make(chan struct{}),
+		holdTimeout:      default_dev_proxy_hold_timeout,
+		rebuildPageAfter: default_dev_proxy_rebuild_page_after,
+	}
+	if opts.HoldTimeoutMs > 0 {
+		p.holdTimeout = time.Duration(opts.HoldTimeoutMs) * time.Millisecond
+	}
+	if opts.RebuildPageAfterMs > 0 {
+		p.rebuildPageAfter = time.Duration(opts.RebuildPageAfterMs) * time.Millisecond
+	}
+	p.rp = httputil.NewSingleHostReverseProxy(target)
+	p.rp.FlushInterval = -1 // don't buffer streaming responses
+	p.rp.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		http.Error(w, fmt.Sprintf("wave dev proxy: app unavailable: %v", err), http.StatusBadGateway)
+	}
+	return p
+}
+
+type devProxyFailure struct {
+	message       string
+	refreshScript string // if set, included in the error page
+}
+
+func (p *devProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	ready, readyCh := p.ready, p.readyCh
+	p.mu.Unlock()
+	if !ready && !p.hold(w, r, readyCh) {
+		return
+	}
+	p.rp.ServeHTTP(w, r)
+}
+
+func (p *devProxy) get_failure() *devProxyFailure {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.failure
+}
+
+// Returns true if the app became ready and the request should be forwarded.
+// Otherwise, a response has already been written (or the client went away).
+func (p *devProxy) hold(w http.ResponseWriter, r *http.Request, readyCh <-chan struct{}) bool {
+	holdTimer := time.NewTimer(p.holdTimeout)
+	defer holdTimer.Stop()
+
+	var rebuildPageC <-chan time.Time
+	if is_navigation_request(r) {
+		rebuildPageTimer := time.NewTimer(p.rebuildPageAfter)
+		defer rebuildPageTimer.Stop()
+		rebuildPageC = rebuildPageTimer.C
+	}
+
+	select {
+	case <-readyCh:
+		if f := p.get_failure(); f != nil {
+			serve_failure(w, r, f)
+			return false
+		}
+		return true
+	case <-rebuildPageC:
+		serve_rebuild_page(w)
+	case <-holdTimer.C:
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "wave dev proxy: timed out waiting for app to restart", http.StatusServiceUnavailable)
+	case <-r.Context().Done():
+	}
+	return false
+}
+
+// Marks the app as unavailable. Returns the new generation, which must be
+// passed to mark_ready so that a stale healthcheck can't mark a newer
+// process as ready.
+func (p *devProxy) mark_unready() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.generation++
+	if p.ready || p.failure != nil {
+		p.ready = false
+		p.failure = nil
+		p.readyCh = make(chan struct{})
+	}
+	return p.generation
+}
+
+func (p *devProxy) mark_ready(generation uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if generation != p.generation || p.ready || p.failure != nil {
+		return
+	}
+	p.ready = true
+	close(p.readyCh)
+}
+
+// Releases held requests with the given error, and answers new ones with it
+// instead of holding them, until the app is next marked unready (i.e.,
+// restarted). A no-op if the app is up, since it's still serving the last
+// good build.
+func (p *devProxy) mark_failed(f *devProxyFailure) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ready || p.failure != nil {
+		return
+	}
+	p.generation++ // Stops any pending healthcheck
+	p.failure = f
+	close(p.readyCh)
+}
+
+// Polls healthURL in the background until it responds with a 200, then
+// releases any held requests. Gives up silently if the app is restarted
+// again in the meantime.
+func (p *devProxy) await_app(healthURL string) {
+	generation := p.mark_unready()
+	go func() {
+		client := &http.Client{Timeout: time.Second}
+		for {
+			p.mu.Lock()
+			stale := generation != p.generation
+			p.mu.Unlock()
+			if stale {
+				return
+			}
+			resp, err := client.Get(healthURL)
+			if err == nil {
+				resp.Body.Close()
+				if resp.StatusCode == http.StatusOK {
+					p.mark_ready(generation)
+					return
+				}
+			}
+			time.Sleep(dev_proxy_health_poll_interval)
+		}
+	}()
+}
+
+func is_navigation_request(r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	if mode := r.Header.Get("Sec-Fetch-Mode"); mode != "" {
+		return mode == "navigate"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+const dev_proxy_rebuild_page = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="1">
+<title>Rebuilding...</title>
+<style>
+body { font-family: system-ui, sans-serif; display: flex; align-items: center; justify-content: center; height: 100vh; margin: 0; color: #555; }
+</style>
+</head>
+<body><p>Wave is rebuilding your app. This page will refresh automatically.</p></body>
+</html>`
+
+// The refresh script shows the error overlay (it's sent the last error when
+// it connects) and reloads the page once a rebuild succeeds.
+const dev_proxy_failure_page_fmt = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Build failed</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem; color: #333; }
+pre { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>Build failed</h1>
+<pre>%s</pre>
+<p>Fix the error and save. This page will reload once the app is rebuilt.</p>
+%s
+</body>
+</html>`
+
+func serve_failure(w http.ResponseWriter, r *http.Request, f *devProxyFailure) {
+	w.Header().Set("Cache-Control", "no-store")
+	if !is_navigation_request(r) {
+		http.Error(w, "wave dev proxy: build failed: "+f.message, http.StatusInternalServerError)
+		return
+	}
+	var script string
+	if f.refreshScript != "" {
+		script = "<script>" + f.refreshScript + "</script>"
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	fmt.Fprintf(w, dev_proxy_failure_page_fmt, html.EscapeString(f.message), script)
+}
+
+func serve_rebuild_page(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Retry-After", "1")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	w.Write([]byte(dev_proxy_rebuild_page))
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// START DEV PROXY
+/////////////////////////////////////////////////////////////////////
+
+// Takes over the public app port and moves the app itself to a free
+// internal port (by resetting the PORT env var, which the app binary
+// inherits). Only called once per dev session.
+func (c *Config) must_start_dev_proxy() {
+	publicPort := MustGetAppPort()
+
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(publicPort))
+	if err != nil {
+		c.panic("failed to listen on dev proxy port", err)
+	}
+
+	appPort, err := netutil.GetFreePort(publicPort + 1)
+	if err != nil {
+		c.panic("failed to get free port", err)
+	}
+	setPort(appPort)
+
+	target := &url.URL{Scheme: "http", Host: "localhost:" + strconv.Itoa(appPort)}
+	c.devProxy = newDevProxy(target, c._uc.Watch.DevProxy)
+
+	server := &http.Server{Handler: c.devProxy, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		c.Logger.Info("Starting dev proxy...", "port", publicPort, "app_port", appPort)
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			c.panic("dev proxy failed", err)
+		}
+	}()
+}
+
+// Called when a dev rebuild fails, so that requests held while the app was
+// down get the error instead of timing out.
+func (c *Config) fail_dev_proxy(err error) {
+	if c.devProxy == nil {
+		return
+	}
+	f := &devProxyFailure{message: err.Error()}
+	if c.is_using_browser() {
+		f.refreshScript = GetRefreshScriptInner(getRefreshServerPort())
+	}
+	c.devProxy.mark_failed(f)
+}
+
+func (c *Config) get_app_healthcheck_url() string {
+	return fmt.Sprintf(
+		"http://localhost:%d%s",
+		MustGetAppPort(),
+		c._uc.Watch.HealthcheckEndpoint,
+	)
+}
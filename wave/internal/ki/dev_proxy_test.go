@@ -0,0 +1,204 @@
+package ki
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestDevProxy(t *testing.T, opts DevProxy) (*devProxy, *httptest.Server, *atomic.Bool) {
+	t.Helper()
+	healthy := new(atomic.Bool)
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" {
+			if !healthy.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.Write([]byte("app:" + r.URL.Path))
+	}))
+	t.Cleanup(app.Close)
+	target, _ := url.Parse(app.URL)
+	return newDevProxy(target, opts), app, healthy
+}
+
+func TestDevProxy(t *testing.T) {
+	t.Run("HoldsUntilHealthy", func(t *testing.T) {
+		p, app, healthy := newTestDevProxy(t, DevProxy{})
+		p.await_app(app.URL + "/healthz")
+
+		done := make(chan *httptest.ResponseRecorder)
+		go func() {
+			rec := httptest.NewRecorder()
+			p.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/thing", nil))
+			done <- rec
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("request should be held while the app is unhealthy")
+		case <-time.After(150 * time.Millisecond):
+		}
+
+		healthy.Store(true)
+
+		select {
+		case rec := <-done:
+			if rec.Code != http.StatusOK || rec.Body.String() != "app:/api/thing" {
+				t.Errorf("got %d %q, want forwarded response", rec.Code, rec.Body.String())
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("request was never forwarded")
+		}
+	})
+
+	t.Run("ForwardsImmediatelyWhenReady", func(t *testing.T) {
+		p, _, _ := newTestDevProxy(t, DevProxy{})
+		p.mark_ready(p.mark_unready())
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/x", nil))
+		if rec.Body.String() != "app:/x" {
+			t.Errorf("body = %q, want forwarded response", rec.Body.String())
+		}
+	})
+
+	t.Run("RebuildPageForSlowNavigations", func(t *testing.T) {
+		p, _, _ := newTestDevProxy(t, DevProxy{RebuildPageAfterMs: 20})
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Sec-Fetch-Mode", "navigate")
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, req)
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("status = %d, want 503", rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), `http-equiv="refresh"`) {
+			t.Errorf("expected self-refreshing rebuild page, got %q", rec.Body.String())
+		}
+	})
+
+	t.Run("HoldTimeout", func(t *testing.T) {
+		p, _, _ := newTestDevProxy(t, DevProxy{HoldTimeoutMs: 20, RebuildPageAfterMs: 1000})
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api", nil))
+		if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+			t.Errorf("got %d (Retry-After %q), want 503 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+		}
+	})
+
+	t.Run("StaleHealthcheckIgnored", func(t *testing.T) {
+		p, _, _ := newTestDevProxy(t, DevProxy{})
+		stale := p.mark_unready()
+		p.mark_unready()
+		p.mark_ready(stale)
+		p.mu.Lock()
+		ready := p.ready
+		p.mu.Unlock()
+		if ready {
+			t.Error("a stale generation should not mark the proxy ready")
+		}
+	})
+
+	t.Run("UnreadyAgainAfterKill", func(t *testing.T) {
+		p, app, healthy := newTestDevProxy(t, DevProxy{HoldTimeoutMs: 50})
+		healthy.Store(true)
+		p.await_app(app.URL + "/healthz")
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			p.mu.Lock()
+			ready := p.ready
+			p.mu.Unlock()
+			if ready {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("proxy never became ready")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		p.mark_unready()
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api", nil))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("status = %d, want 503 after being marked unready", rec.Code)
+		}
+	})
+
+	t.Run("FailureReleasesHeldRequests", func(t *testing.T) {
+		p, app, _ := newTestDevProxy(t, DevProxy{})
+		p.await_app(app.URL + "/healthz")
+
+		done := make(chan *httptest.ResponseRecorder)
+		go func() {
+			rec := httptest.NewRecorder()
+			p.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/thing", nil))
+			done <- rec
+		}()
+		time.Sleep(50 * time.Millisecond)
+
+		p.mark_failed(&devProxyFailure{message: "main.go:1:1: undefined: x"})
+
+		select {
+		case rec := <-done:
+			if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "undefined: x") {
+				t.Errorf("got %d %q, want 500 with the build error", rec.Code, rec.Body.String())
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("held request was not released")
+		}
+	})
+
+	t.Run("FailurePageForNavigations", func(t *testing.T) {
+		p, _, _ := newTestDevProxy(t, DevProxy{RebuildPageAfterMs: 1000})
+		p.mark_unready()
+		p.mark_failed(&devProxyFailure{message: "<oops>", refreshScript: "connect()"})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Sec-Fetch-Mode", "navigate")
+		rec := httptest.NewRecorder()
+		start := time.Now()
+		p.ServeHTTP(rec, req)
+		if time.Since(start) > 500*time.Millisecond {
+			t.Error("requests should not be held after a failure")
+		}
+		body := rec.Body.String()
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d, want 500", rec.Code)
+		}
+		if strings.Contains(body, `http-equiv="refresh"`) {
+			t.Error("failure page should not refresh itself")
+		}
+		if !strings.Contains(body, "&lt;oops&gt;") || !strings.Contains(body, "<script>connect()</script>") {
+			t.Errorf("expected escaped error and refresh script, got %q", body)
+		}
+	})
+
+	t.Run("FailureIgnoredWhileAppIsUp", func(t *testing.T) {
+		p, _, _ := newTestDevProxy(t, DevProxy{})
+		p.mark_ready(p.mark_unready())
+		p.mark_failed(&devProxyFailure{message: "x"})
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/x", nil))
+		if rec.Body.String() != "app:/x" {
+			t.Errorf("body = %q, want forwarded response", rec.Body.String())
+		}
+	})
+
+	t.Run("RestartClearsFailure", func(t *testing.T) {
+		p, app, healthy := newTestDevProxy(t, DevProxy{})
+		p.mark_unready()
+		p.mark_failed(&devProxyFailure{message: "x"})
+		healthy.Store(true)
+		p.await_app(app.URL + "/healthz")
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/x", nil))
+		if rec.Body.String() != "app:/x" {
+			t.Errorf("body = %q, want forwarded response", rec.Body.String())
+		}
+	})
+}
@@ -31,6 +31,9 @@ type DistWaveInternal struct {
 	CriticalDotCSS             *dirs.File
 	NormalCSSFileRefDotTXT     *dirs.File
 	PublicFileMapFileRefDotTXT *dirs.File
+	CSSModulesDotJSON          *dirs.File
+	NamedCSSFileRefsDotJSON    *dirs.File
+	AssetManifestDotJSON       *dirs.File
 }
 
 func toDistLayout(cleanDistDir string) *dirs.Dir[Dist] {
@@ -49,6 +52,9 @@ func toDistLayout(cleanDistDir string) *dirs.Dir[Dist] {
 				CriticalDotCSS:             dirs.ToFile("critical.css"),
 				NormalCSSFileRefDotTXT:     dirs.ToFile("normal_css_file_ref.txt"),
 				PublicFileMapFileRefDotTXT: dirs.ToFile("public_file_map_file_ref.txt"),
+				CSSModulesDotJSON:          dirs.ToFile("css_modules.json"),
+				NamedCSSFileRefsDotJSON:    dirs.ToFile("named_css_file_refs.json"),
+				AssetManifestDotJSON:       dirs.ToFile("asset_manifest.json"),
 			}),
 			Keep: dirs.ToFile(".keep"),
 		}),
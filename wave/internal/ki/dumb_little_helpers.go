@@ -1,8 +1,10 @@
 package ki
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/exec"
@@ -40,9 +42,11 @@ func (c *Config) setup_browser_refresh_mux() {
 
 	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		websocketHandler(c.browserTabManager)(w, r)
+		websocketHandler(c.browserTabManager, c.get_last_dev_error)(w, r)
 	})
 
+	mux.HandleFunc("POST "+report_error_path, c.handle_dev_panic_report)
+
 	mux.HandleFunc("/get-refresh-script-inner", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Content-Type", "text/javascript")
@@ -109,11 +113,7 @@ func (c *Config) add_directory_to_watcher(path string) error {
 /////////////////////////////////////////////////////////////////////
 
 func (c *Config) wait_for_app_readiness() bool {
-	return c.wait_for_readiness(fmt.Sprintf(
-		"http://localhost:%d%s",
-		MustGetAppPort(),
-		c._uc.Watch.HealthcheckEndpoint,
-	))
+	return c.wait_for_readiness(c.get_app_healthcheck_url())
 }
 
 func (c *Config) wait_for_vite_readiness() bool {
@@ -154,6 +154,9 @@ func (c *Config) wait_for_readiness(url string) bool {
 func (c *Config) kill_running_go_binary() {
 	c.dev.mu.Lock()
 	defer c.dev.mu.Unlock()
+	if c.devProxy != nil {
+		c.devProxy.mark_unready()
+	}
 	if c.lastBuildCmd != nil {
 		if err := grace.TerminateProcess(c.lastBuildCmd.Process, 5*time.Second, c.Logger); err != nil {
 			c.panic("failed to terminate process", err)
@@ -177,6 +180,10 @@ func (c *Config) run_go_binary() {
 		c.panic("failed to start app binary", err)
 	}
 	c.Logger.Info("Running app binary...", "pid", c.lastBuildCmd.Process.Pid)
+	c.clear_dev_error(devErrorKindPanic)
+	if c.devProxy != nil {
+		c.devProxy.await_app(c.get_app_healthcheck_url())
+	}
 }
 
 /////////////////////////////////////////////////////////////////////
@@ -237,12 +244,15 @@ func (c *Config) compile_go_binary() error {
 	buildDest := c.get_binary_output_path()
 	in := fmt.Sprintf(".%c%s", filepath.Separator, filepath.Clean(c._uc.Core.MainAppEntry))
 	buildCmd := exec.Command("go", "build", "-o", buildDest, in)
+	var stderr bytes.Buffer
 	buildCmd.Stdout = os.Stdout
-	buildCmd.Stderr = os.Stderr
+	buildCmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
 	err := buildCmd.Run()
 	if err != nil {
+		c.report_dev_error(new_build_dev_error(stderr.String()))
 		return fmt.Errorf("error compiling binary: %w", err)
 	}
+	c.clear_dev_error(devErrorKindBuild, devErrorKindPanic)
 	c.Logger.Info("DONE compiling Go binary", "duration", time.Since(a))
 	return nil
 }
@@ -8,6 +8,7 @@ import (
 
 const (
 	modeKey              = "WAVE_MODE"
+	envKey               = "WAVE_ENV"
 	devModeVal           = "development"
 	portKey              = "PORT"
 	portHasBeenSetKey    = "WAVE_PORT_HAS_BEEN_SET"
@@ -19,6 +20,12 @@ func GetIsDev() bool {
 	return os.Getenv(modeKey) == devModeVal
 }
 
+// Returns the value of the WAVE_ENV environment variable (e.g., "staging"),
+// which selects the config overlay to apply at MainInit, if any.
+func GetEnv() string {
+	return os.Getenv(envKey)
+}
+
 func setPort(port int) {
 	os.Setenv(portKey, fmt.Sprintf("%d", port))
 }
@@ -15,6 +15,7 @@ type EvtDetails struct {
 	isOther             bool
 	isCriticalCSS       bool
 	isNormalCSS         bool
+	namedCSS            []string // names of the named CSS entries affected
 	isWaveCSS           bool
 	wfc                 *WatchedFile
 	isNonEmptyCHMODOnly bool
@@ -29,14 +30,22 @@ func (c *Config) getEvtDetails(evt fsnotify.Event) *EvtDetails {
 	cssImportURLsMu.RLock()
 	_, isImportedCritical := criticalReliedUponFiles[evt.Name]
 	_, isImportedNormal := normalReliedUponFiles[evt.Name]
+	var namedCSS []string
+	for name, entry := range c.cleanSources.NamedCSSEntries {
+		_, isImportedNamed := namedReliedUponFiles[name][evt.Name]
+		if evt.Name == entry || isImportedNamed {
+			namedCSS = append(namedCSS, name)
+		}
+	}
 	cssImportURLsMu.RUnlock()
 
 	isCriticalCSS := evt.Name == c.cleanSources.CriticalCSSEntry || isImportedCritical
 	isNormalCSS := evt.Name == c.cleanSources.NonCriticalCSSEntry || isImportedNormal
 
-	isWaveCSS := isCriticalCSS || isNormalCSS
+	isWaveCSS := isCriticalCSS || isNormalCSS || len(namedCSS) > 0
 
 	var matchingWatchedFile *WatchedFile
+	isDefaultWatchedFile := false
 
 	for _, wfc := range c._uc.Watch.Include {
 		isMatch := c.get_is_match(potentialMatch{pattern: wfc.Pattern, path: evt.Name})
@@ -51,6 +60,7 @@ func (c *Config) getEvtDetails(evt fsnotify.Event) *EvtDetails {
 			isMatch := c.get_is_match(potentialMatch{pattern: wfc.Pattern, path: evt.Name})
 			if isMatch {
 				matchingWatchedFile = &wfc
+				isDefaultWatchedFile = true
 				break
 			}
 		}
@@ -61,6 +71,22 @@ func (c *Config) getEvtDetails(evt fsnotify.Event) *EvtDetails {
 		isGo = false
 	}
 
+	// Go files that can't affect the app binary (test files, and files
+	// outside of MainAppEntry's import graph) never recompile or restart the
+	// app. They're ignored unless a user-defined WatchedFile matches them, in
+	// which case they're handled like any other non-Go file.
+	if isGo && !c.is_go_file_in_app(evt.Name) {
+		isGo = false
+		if isDefaultWatchedFile {
+			matchingWatchedFile = nil
+		} else if matchingWatchedFile != nil {
+			wfc := *matchingWatchedFile
+			wfc.RecompileGoBinary = false
+			wfc.RestartApp = false
+			matchingWatchedFile = &wfc
+		}
+	}
+
 	isOther := !isGo && !isWaveCSS
 
 	isIgnored := c.get_is_ignored(evt.Name, c.ignoredFilePatterns)
@@ -86,6 +112,7 @@ func (c *Config) getEvtDetails(evt fsnotify.Event) *EvtDetails {
 		isIgnored:           isIgnored,
 		isCriticalCSS:       isCriticalCSS,
 		isNormalCSS:         isNormalCSS,
+		namedCSS:            namedCSS,
 		wfc:                 matchingWatchedFile,
 		isNonEmptyCHMODOnly: c.getIsNonEmptyCHMODOnly(evt),
 		is_full_dev_reset:   is_full_dev_reset,
@@ -2,6 +2,7 @@ package ki
 
 import (
 	"crypto/sha256"
+	"encoding/base64"
 	"fmt"
 	"hash"
 	"io"
@@ -10,10 +11,11 @@ import (
 	"strings"
 )
 
-func getHashedFilenameFromPath(filePath string, originalFileName string) (string, error) {
+// Also returns a Subresource Integrity value for the file's contents.
+func getHashedFilenameFromPath(filePath string, originalFileName string) (string, string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	defer file.Close()
 
@@ -28,11 +30,22 @@ func getHashedFilenameFromPath(filePath string, originalFileName string) (string
 			break
 		}
 		if err != nil {
-			return "", err
+			return "", "", err
 		}
 	}
 
-	return toOutputFileName(hash, originalFileName), nil
+	return toOutputFileName(hash, originalFileName), toSRI(hash), nil
+}
+
+// e.g., "sha256-47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+func toSRI(hash hash.Hash) string {
+	return "sha256-" + base64.StdEncoding.EncodeToString(hash.Sum(nil))
+}
+
+func getSRI(content []byte) string {
+	hash := sha256.New()
+	hash.Write(content)
+	return toSRI(hash)
 }
 
 func getHashedFilename(content []byte, originalFileName string) string {
@@ -15,7 +15,6 @@ import (
 
 	"github.com/river-now/river/kit/fsutil"
 	"github.com/river-now/river/kit/htmlutil"
-	"github.com/river-now/river/kit/matcher"
 )
 
 const (
@@ -107,11 +106,15 @@ func (c *Config) getInitialPublicFileMapDetails() (*publicFileMapDetails, error)
 		Tag:        "link",
 		Attributes: map[string]string{"rel": "modulepreload", "href": publicFileMapURL},
 	}
+	if sri := c.GetPublicSRI(publicFileMapURL); sri != "" {
+		linkEl.Attributes["integrity"] = sri
+		linkEl.Attributes["crossorigin"] = "anonymous"
+	}
 
 	scriptEl := htmlutil.Element{
 		Tag:                "script",
 		Attributes:         map[string]string{"type": "module"},
-		DangerousInnerHTML: fmt.Sprintf(innerHTMLFormatStr, publicFileMapURL, c._uc.Core.PublicPathPrefix),
+		DangerousInnerHTML: fmt.Sprintf(innerHTMLFormatStr, publicFileMapURL, c.GetPublicURLBase()),
 	}
 
 	sha256Hash, err := htmlutil.AddSha256HashInline(&scriptEl)
@@ -156,12 +159,9 @@ func (c *Config) getInitialPublicFileMapURL() (string, error) {
 		return "", err
 	}
 
-	return matcher.EnsureLeadingSlash(
-		path.Join(
-			c._uc.Core.PublicPathPrefix,
-			c._dist.S().Static.S().Assets.S().Public.LastSegment(),
-			string(content),
-		),
+	return c.to_public_asset_url(
+		c._dist.S().Static.S().Assets.S().Public.LastSegment(),
+		string(content),
 	), nil
 }
 
@@ -223,3 +223,16 @@ func (c *Config) GetSimplePublicFileMapBuildtime() (map[string]string, error) {
 
 	return simpleStrMap, nil
 }
+
+// Indexes the runtime public file map by hashed file name (Val).
+func (c *Config) getInitialPublicFileMapByVal() (map[string]fileVal, error) {
+	fileMap, err := c.runtime_cache.public_filemap_from_gob.Get()
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]fileVal, len(fileMap))
+	for _, v := range fileMap {
+		index[v.Val] = v
+	}
+	return index, nil
+}
@@ -0,0 +1,96 @@
+package ki
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// The set of package directories that MainAppEntry (transitively) imports,
+// per "go list -deps". Used in dev to skip recompiling and restarting the app
+// for Go files that can't affect the binary (e.g., in unrelated CLI tools or
+// scripts in the same module).
+type goDepGraph struct {
+	mu   sync.RWMutex
+	dirs map[string]struct{} // Absolute, cleaned. Nil if not (yet) known.
+	seq  uint64              // Of the latest refresh, so that a slower, older one can't win
+}
+
+// Safe to call whether or not the app currently builds. If the graph can't be
+// fully resolved (e.g., a file doesn't parse, or imports a package that
+// doesn't exist yet), it's reset to unknown, so every Go file counts as part
+// of the app until the next successful refresh.
+func (c *Config) refresh_go_dep_graph() {
+	c.goDepGraph.mu.Lock()
+	c.goDepGraph.seq++
+	seq := c.goDepGraph.seq
+	c.goDepGraph.mu.Unlock()
+
+	dirs, err := list_go_dep_dirs("", c._uc.Core.MainAppEntry)
+	if err != nil && !errors.Is(err, errIncompleteGoDepGraph) {
+		c.Logger.Warn(fmt.Sprintf("failed to list Go dependencies of MainAppEntry: %v", err))
+	}
+
+	c.goDepGraph.mu.Lock()
+	defer c.goDepGraph.mu.Unlock()
+	if seq == c.goDepGraph.seq {
+		c.goDepGraph.dirs = dirs
+	}
+}
+
+var errIncompleteGoDepGraph = errors.New("incomplete import graph")
+
+// Runs "go list -e -deps" on mainAppEntry (relative to dir, or to the current
+// working directory if dir is empty) and returns the directories of every
+// package in its import graph, including its own. With -e, packages that
+// don't compile are still listed, but if any package couldn't be loaded at
+// all, the graph may be missing some of its imports, so errIncompleteGoDepGraph
+// is returned instead.
+func list_go_dep_dirs(dir, mainAppEntry string) (map[string]struct{}, error) {
+	in := fmt.Sprintf(".%c%s", filepath.Separator, filepath.Clean(mainAppEntry))
+	cmd := exec.Command("go", "list", "-e", "-deps", "-f", "{{.Dir}}\t{{if .Error}}{{.ImportPath}}{{end}}", in)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	dirs := make(map[string]struct{})
+	for line := range strings.Lines(string(out)) {
+		pkgDir, errPkg, _ := strings.Cut(strings.TrimRight(line, "\r\n"), "\t")
+		if errPkg != "" {
+			return nil, fmt.Errorf("%w: failed to load %s", errIncompleteGoDepGraph, errPkg)
+		}
+		if pkgDir != "" {
+			dirs[filepath.Clean(pkgDir)] = struct{}{}
+		}
+	}
+	return dirs, nil
+}
+
+// Reports whether a change to the given Go file can affect the app binary.
+// Test files never can. Any other file can if it lives in the directory of a
+// package in MainAppEntry's import graph, or if the graph isn't known.
+func (c *Config) is_go_file_in_app(name string) bool {
+	if strings.HasSuffix(name, "_test.go") {
+		return false
+	}
+
+	c.goDepGraph.mu.RLock()
+	defer c.goDepGraph.mu.RUnlock()
+
+	if c.goDepGraph.dirs == nil {
+		return true
+	}
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return true
+	}
+	_, ok := c.goDepGraph.dirs[filepath.Dir(abs)]
+	return ok
+}
@@ -0,0 +1,105 @@
+package ki
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeGoDepTestModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/app\n\ngo 1.24\n"
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestGoDepGraph(t *testing.T) {
+	dir := writeGoDepTestModule(t, map[string]string{
+		"backend/main.go":        "package main\n\nimport _ \"example.com/app/lib\"\n\nfunc main() {}\n",
+		"lib/lib.go":             "package lib\n",
+		"lib/lib_test.go":        "package lib\n",
+		"tools/gen/main.go":      "package main\n\nfunc main() {}\n",
+		"tools/gen/unrelated.go": "package main\n",
+	})
+
+	dirs, err := list_go_dep_dirs(dir, "backend")
+	if err != nil {
+		t.Fatalf("list_go_dep_dirs() error = %v", err)
+	}
+
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"backend", "lib"} {
+		if _, ok := dirs[filepath.Join(realDir, want)]; !ok {
+			t.Errorf("expected %s in dep dirs, got %v", want, dirs)
+		}
+	}
+	if _, ok := dirs[filepath.Join(realDir, "tools", "gen")]; ok {
+		t.Error("tools/gen should not be in dep dirs")
+	}
+
+	c := &Config{}
+
+	t.Run("UnknownGraph", func(t *testing.T) {
+		if !c.is_go_file_in_app(filepath.Join(realDir, "tools/gen/main.go")) {
+			t.Error("every non-test file should count as part of the app when the graph is unknown")
+		}
+		if c.is_go_file_in_app(filepath.Join(realDir, "lib/lib_test.go")) {
+			t.Error("test files should never count as part of the app")
+		}
+	})
+
+	t.Run("KnownGraph", func(t *testing.T) {
+		c.goDepGraph.dirs = dirs
+		tests := map[string]bool{
+			"backend/main.go":        true,
+			"lib/lib.go":             true,
+			"lib/new_file.go":        true,
+			"lib/lib_test.go":        false,
+			"tools/gen/main.go":      false,
+			"tools/gen/unrelated.go": false,
+		}
+		for name, want := range tests {
+			if got := c.is_go_file_in_app(filepath.Join(realDir, name)); got != want {
+				t.Errorf("is_go_file_in_app(%s) = %v, want %v", name, got, want)
+			}
+		}
+	})
+
+	t.Run("BuildErrorsStillListed", func(t *testing.T) {
+		dir := writeGoDepTestModule(t, map[string]string{
+			"backend/main.go": "package main\n\nimport _ \"example.com/app/lib\"\n\nfunc main() { undefined() }\n",
+			"lib/lib.go":      "package lib\n\nvar x int = \"not an int\"\n",
+		})
+		dirs, err := list_go_dep_dirs(dir, "backend")
+		if err != nil {
+			t.Fatalf("list_go_dep_dirs() error = %v", err)
+		}
+		realDir, _ := filepath.EvalSymlinks(dir)
+		if _, ok := dirs[filepath.Join(realDir, "lib")]; !ok {
+			t.Errorf("expected lib in dep dirs, got %v", dirs)
+		}
+	})
+
+	t.Run("UnloadablePackage", func(t *testing.T) {
+		dir := writeGoDepTestModule(t, map[string]string{
+			"backend/main.go": "package main\n\nimport _ \"example.com/app/missing\"\n\nfunc main() {}\n",
+		})
+		dirs, err := list_go_dep_dirs(dir, "backend")
+		if !errors.Is(err, errIncompleteGoDepGraph) || dirs != nil {
+			t.Errorf("got %v, %v; want nil, errIncompleteGoDepGraph", dirs, err)
+		}
+	})
+}
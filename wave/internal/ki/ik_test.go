@@ -86,9 +86,13 @@ func setupTestEnv(t *testing.T) *testEnv {
 		stylesheet_link_el:      safecache.New(c.getInitialStyleSheetLinkElement, GetIsDev),
 		stylesheet_url:          safecache.New(c.getInitialStyleSheetURL, GetIsDev),
 		critical_css:            safecache.New(c.getInitialCriticalCSSStatus, GetIsDev),
+		named_css_refs:          safecache.New(c.getInitialNamedCSSRefs, GetIsDev),
+		css_modules:             safecache.New(c.getInitialCSSModules, GetIsDev),
 		public_filemap_from_gob: safecache.New(c.getInitialPublicFileMapFromGobRuntime, nil),
 		public_filemap_url:      safecache.New(c.getInitialPublicFileMapURL, GetIsDev),
 		public_urls:             safecache.NewMap(c.getInitialPublicURL, publicURLsKeyMaker, nil),
+		public_filemap_by_val:   safecache.New(c.getInitialPublicFileMapByVal, nil),
+		public_srcsets:          safecache.NewMap(c.getInitialPublicSrcSet, srcSetKeyMaker, nil),
 	}
 
 	// Initialize dev cache if needed
@@ -0,0 +1,295 @@
+package ki
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/river-now/river/kit/typed"
+	"golang.org/x/image/draw"
+)
+
+const (
+	imageFormatJPEG = "jpeg"
+	imageFormatPNG  = "png"
+
+	defaultImageQuality = 80
+)
+
+func (c *Config) is_image_processing_enabled() bool {
+	return len(c._uc.Core.ImageVariants.Widths) > 0
+}
+
+func imageFormatFromPath(p string) string {
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".jpg", ".jpeg":
+		return imageFormatJPEG
+	case ".png":
+		return imageFormatPNG
+	}
+	return ""
+}
+
+func imageFormatExt(format string) string {
+	if format == imageFormatJPEG {
+		return ".jpg"
+	}
+	return "." + format
+}
+
+// Resolves a user-supplied format name against the source image's format.
+func resolveImageFormat(format, sourceFormat string) (string, error) {
+	switch strings.ToLower(format) {
+	case "", "original":
+		return sourceFormat, nil
+	case "jpeg", "jpg":
+		return imageFormatJPEG, nil
+	case "png":
+		return imageFormatPNG, nil
+	case "webp", "avif":
+		return "", fmt.Errorf("image format %q is not supported (no pure-Go encoder is available)", format)
+	}
+	return "", fmt.Errorf("unknown image format %q", format)
+}
+
+// Checks the configured formats once at startup, so that a bad value fails
+// fast instead of in every build worker.
+func (v ImageVariants) validate() error {
+	for _, format := range v.Formats {
+		if _, err := resolveImageFormat(format, imageFormatPNG); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// e.g., "images/hero.png" at 480px as JPEG -> "images/hero.png@480w.jpg"
+func imageVariantKey(originalKey string, width int, format string) string {
+	return originalKey + "@" + strconv.Itoa(width) + "w" + imageFormatExt(format)
+}
+
+func getImageWidth(filePath string) (int, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return 0, err
+	}
+	return cfg.Width, nil
+}
+
+// Registers (and, unless unchanged since the last dev rebuild, writes) the
+// resized and/or re-encoded variants of a public image. Variant names are
+// derived from the original's content hash plus the variant settings, so they
+// can be computed without encoding anything.
+func (c *Config) processImageVariants(
+	fi fileInfo,
+	original fileVal,
+	opts *staticFileProcessorOpts,
+	newFileMap,
+	oldFileMap *typed.SyncMap[string, fileVal],
+	distDir string,
+) error {
+	settings := c._uc.Core.ImageVariants
+	quality := settings.Quality
+	if quality <= 0 || quality > 100 {
+		quality = defaultImageQuality
+	}
+	sourceFormat := imageFormatFromPath(fi.relativePath)
+
+	formats := settings.Formats
+	if len(formats) == 0 {
+		formats = []string{"original"}
+	}
+
+	var src image.Image
+
+	for _, rawFormat := range formats {
+		format, err := resolveImageFormat(rawFormat, sourceFormat)
+		if err != nil {
+			return fmt.Errorf("error processing image variants for %s: %w", fi.relativePath, err)
+		}
+
+		widths := make([]int, 0, len(settings.Widths)+1)
+		for _, w := range settings.Widths {
+			if w > 0 && w < original.Width {
+				widths = append(widths, w)
+			}
+		}
+		// A re-encoded copy at full size is the largest candidate in
+		// the srcset for that format.
+		if format != sourceFormat {
+			widths = append(widths, original.Width)
+		}
+		slices.Sort(widths)
+		widths = slices.Compact(widths)
+
+		for _, width := range widths {
+			key := imageVariantKey(fi.relativePath, width, format)
+			variant := fileVal{
+				Val: getHashedFilename(
+					[]byte(original.Val+"|"+key+"|"+strconv.Itoa(quality)),
+					strings.ReplaceAll(key, "/", "_"),
+				),
+				Width:     width,
+				VariantOf: fi.relativePath,
+				Format:    format,
+			}
+
+			// The SRI depends on the encoded output, so it's excluded when
+			// checking whether the variant changed and carried over if not.
+			if opts.is_dev_rebuild {
+				if old, exists := oldFileMap.Load(key); exists {
+					oldWithoutSRI := old
+					oldWithoutSRI.SRI = ""
+					if oldWithoutSRI == variant {
+						newFileMap.Store(key, old)
+						continue
+					}
+				}
+			}
+
+			if src == nil {
+				src, err = decodeImage(fi.path)
+				if err != nil {
+					return fmt.Errorf("error decoding image %s: %w", fi.relativePath, err)
+				}
+			}
+
+			variant.SRI, err = writeImageVariant(
+				filepath.Join(distDir, variant.Val), src, width, format, quality,
+			)
+			if err != nil {
+				return fmt.Errorf("error writing image variant %s: %w", key, err)
+			}
+			newFileMap.Store(key, variant)
+		}
+	}
+
+	return nil
+}
+
+func decodeImage(filePath string) (image.Image, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
+// Returns the SRI value of the written file.
+func writeImageVariant(distPath string, src image.Image, width int, format string, quality int) (string, error) {
+	b := src.Bounds()
+	height := max(1, (b.Dy()*width+b.Dx()/2)/b.Dx())
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if format == imageFormatJPEG {
+		// JPEG has no alpha channel, so flatten onto white
+		draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	}
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+
+	// Encode fully before touching dist, so a failed encode never leaves a
+	// truncated variant behind.
+	var buf bytes.Buffer
+	var err error
+	if format == imageFormatJPEG {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: quality})
+	} else {
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error encoding image: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(distPath), 0755); err != nil {
+		return "", fmt.Errorf("error creating directory: %w", err)
+	}
+	if err := os.WriteFile(distPath, buf.Bytes(), 0644); err != nil {
+		return "", err
+	}
+	return getSRI(buf.Bytes()), nil
+}
+
+/////////////////////////////////////////////////////////////////////
+/////// SRCSET
+/////////////////////////////////////////////////////////////////////
+
+type srcSetKey struct {
+	originalPublicURL string
+	format            string
+}
+
+func srcSetKeyMaker(k srcSetKey) srcSetKey { return k }
+
+// Returns a srcset attribute value (e.g., "/public/hero_abc.png 480w,
+// /public/hero_def.png 1200w") built from the variants generated for the
+// given public image in its original format. Returns an empty string if
+// the image has no known variants.
+func (c *Config) GetPublicSrcSet(originalPublicURL string) string {
+	return c.GetPublicSrcSetForFormat(originalPublicURL, "")
+}
+
+// Same as GetPublicSrcSet, but for variants re-encoded into the given format
+// ("jpeg" or "png"), for use in <picture> <source> elements.
+func (c *Config) GetPublicSrcSetForFormat(originalPublicURL, format string) string {
+	srcSet, _ := c.runtime_cache.public_srcsets.Get(srcSetKey{originalPublicURL, format})
+	return srcSet
+}
+
+func (c *Config) getInitialPublicSrcSet(k srcSetKey) (string, error) {
+	fileMapFromGob, err := c.runtime_cache.public_filemap_from_gob.Get()
+	if err != nil {
+		c.Logger.Error(fmt.Sprintf(
+			"error getting public file map from gob for srcset %s: %v", k.originalPublicURL, err,
+		))
+		return "", err
+	}
+	return c.buildSrcSet(k.originalPublicURL, k.format, fileMapFromGob), nil
+}
+
+func (c *Config) buildSrcSet(originalPublicURL, format string, fileMap FileMap) string {
+	originalKey := cleanURL(originalPublicURL)
+	original, exists := fileMap[originalKey]
+	if !exists {
+		return ""
+	}
+	sourceFormat := imageFormatFromPath(originalKey)
+	format, err := resolveImageFormat(format, sourceFormat)
+	if err != nil {
+		return ""
+	}
+
+	candidates := make([]fileVal, 0, 4)
+	for _, v := range fileMap {
+		if v.VariantOf == originalKey && v.Format == format {
+			candidates = append(candidates, v)
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	if format == sourceFormat && original.Width > 0 {
+		candidates = append(candidates, original)
+	}
+	slices.SortFunc(candidates, func(a, b fileVal) int { return cmp.Compare(a.Width, b.Width) })
+
+	parts := make([]string, 0, len(candidates))
+	for _, v := range candidates {
+		url := c.to_public_asset_url(v.Val)
+		parts = append(parts, url+" "+strconv.Itoa(v.Width)+"w")
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,173 @@
+package ki
+
+import (
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestPNG(t *testing.T, relativePath string, width, height int) {
+	t.Helper()
+	fullPath := filepath.Join(testRootDir, relativePath)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := range width {
+		for y := range height {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	f, err := os.Create(fullPath)
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+}
+
+func TestImageVariants(t *testing.T) {
+	env := setupTestEnv(t)
+	defer teardownTestEnv(t)
+
+	env.config._uc.Core.ImageVariants = ImageVariants{
+		Widths:  []int{50, 100, 400},
+		Formats: []string{"original", "jpeg"},
+	}
+
+	writeTestPNG(t, "public-static/images/hero.png", 200, 100)
+	env.createTestFile(t, "public-static/notes.txt", "not an image")
+
+	if err := env.config.handlePublicFiles(false); err != nil {
+		t.Fatalf("handlePublicFiles() error = %v", err)
+	}
+
+	fileMap, err := env.config.loadMapFromGob(PublicFileMapGobName, true)
+	if err != nil {
+		t.Fatalf("loadMapFromGob() error = %v", err)
+	}
+	publicDir := filepath.Join(testRootDir, "dist/static/assets/public")
+
+	t.Run("RegistersVariants", func(t *testing.T) {
+		if v := fileMap["images/hero.png"]; v.Width != 200 || v.VariantOf != "" {
+			t.Errorf("original = %+v, want width 200", v)
+		}
+		expected := map[string]struct {
+			width  int
+			format string
+		}{
+			"images/hero.png@50w.png":  {50, "png"},
+			"images/hero.png@100w.png": {100, "png"},
+			"images/hero.png@50w.jpg":  {50, "jpeg"},
+			"images/hero.png@100w.jpg": {100, "jpeg"},
+			"images/hero.png@200w.jpg": {200, "jpeg"},
+		}
+		for key, want := range expected {
+			v, ok := fileMap[key]
+			if !ok {
+				t.Errorf("missing variant %s", key)
+				continue
+			}
+			if v.Width != want.width || v.Format != want.format || v.VariantOf != "images/hero.png" {
+				t.Errorf("%s = %+v, want width %d format %s", key, v, want.width, want.format)
+			}
+			if _, err := os.Stat(filepath.Join(publicDir, v.Val)); err != nil {
+				t.Errorf("variant %s not written to dist: %v", key, err)
+			}
+		}
+		for _, key := range []string{"images/hero.png@400w.png", "images/hero.png@200w.png", "images/hero.png@400w.jpg"} {
+			if _, ok := fileMap[key]; ok {
+				t.Errorf("unexpected variant %s", key)
+			}
+		}
+		if _, ok := fileMap["notes.txt@50w.png"]; ok {
+			t.Errorf("non-image should not get variants")
+		}
+	})
+
+	t.Run("ResizesAndReencodes", func(t *testing.T) {
+		f, err := os.Open(filepath.Join(publicDir, fileMap["images/hero.png@100w.jpg"].Val))
+		if err != nil {
+			t.Fatalf("open error = %v", err)
+		}
+		defer f.Close()
+		cfg, err := jpeg.DecodeConfig(f)
+		if err != nil {
+			t.Fatalf("expected a JPEG: %v", err)
+		}
+		if cfg.Width != 100 || cfg.Height != 50 {
+			t.Errorf("size = %dx%d, want 100x50", cfg.Width, cfg.Height)
+		}
+	})
+
+	t.Run("SrcSet", func(t *testing.T) {
+		want := strings.Join([]string{
+			"/bob/" + fileMap["images/hero.png@50w.png"].Val + " 50w",
+			"/bob/" + fileMap["images/hero.png@100w.png"].Val + " 100w",
+			"/bob/" + fileMap["images/hero.png"].Val + " 200w",
+		}, ", ")
+		if got := env.config.GetPublicSrcSet("/images/hero.png"); got != want {
+			t.Errorf("GetPublicSrcSet() = %q, want %q", got, want)
+		}
+
+		wantJPEG := strings.Join([]string{
+			"/bob/" + fileMap["images/hero.png@50w.jpg"].Val + " 50w",
+			"/bob/" + fileMap["images/hero.png@100w.jpg"].Val + " 100w",
+			"/bob/" + fileMap["images/hero.png@200w.jpg"].Val + " 200w",
+		}, ", ")
+		if got := env.config.GetPublicSrcSetForFormat("images/hero.png", "jpeg"); got != wantJPEG {
+			t.Errorf("GetPublicSrcSetForFormat() = %q, want %q", got, wantJPEG)
+		}
+
+		if got := env.config.GetPublicSrcSet("notes.txt"); got != "" {
+			t.Errorf("GetPublicSrcSet(non-image) = %q, want empty", got)
+		}
+	})
+
+	t.Run("DevRebuildKeepsVariants", func(t *testing.T) {
+		if err := env.config.handlePublicFiles(true); err != nil {
+			t.Fatalf("handlePublicFiles(true) error = %v", err)
+		}
+		rebuilt, err := env.config.loadMapFromGob(PublicFileMapGobName, true)
+		if err != nil {
+			t.Fatalf("loadMapFromGob() error = %v", err)
+		}
+		for key, v := range fileMap {
+			if rebuilt[key] != v {
+				t.Errorf("%s changed across rebuild: %+v -> %+v", key, v, rebuilt[key])
+			}
+			if _, err := os.Stat(filepath.Join(publicDir, v.Val)); err != nil {
+				t.Errorf("%s missing after rebuild: %v", key, err)
+			}
+		}
+	})
+
+	t.Run("ValidateFormats", func(t *testing.T) {
+		valid := ImageVariants{Formats: []string{"original", "jpg", "PNG"}}
+		if err := valid.validate(); err != nil {
+			t.Errorf("expected valid formats, got %v", err)
+		}
+		for _, format := range []string{"webp", "gif"} {
+			if err := (ImageVariants{Formats: []string{format}}).validate(); err == nil {
+				t.Errorf("expected error for format %q", format)
+			}
+		}
+	})
+
+	// Bypasses config validation to exercise the failing-worker path, which
+	// must stop and wait for every goroutine before returning.
+	t.Run("UnsupportedFormat", func(t *testing.T) {
+		env.config._uc.Core.ImageVariants.Formats = []string{"webp"}
+		err := env.config.handlePublicFiles(false)
+		if err == nil || !strings.Contains(err.Error(), "no pure-Go encoder") {
+			t.Errorf("expected unsupported format error, got %v", err)
+		}
+	})
+}
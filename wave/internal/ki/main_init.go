@@ -2,6 +2,7 @@ package ki
 
 import (
 	"encoding/json"
+	"fmt"
 	"path/filepath"
 
 	"github.com/fsnotify/fsnotify"
@@ -31,9 +32,16 @@ func (c *Config) MainInit(opts MainInitOptions, calledFrom string) {
 
 	// USER CONFIG
 	c._uc = new(UserConfig)
-	if err := json.Unmarshal(c.ConfigBytes, c._uc); err != nil {
+	configBytes, err := resolveConfigBytes(c.ConfigBytes, GetEnv(), c.ConfigOverlays)
+	if err != nil {
+		c.panic("failed to resolve user config overlays", err)
+	}
+	if err := json.Unmarshal(configBytes, c._uc); err != nil {
 		c.panic("failed to unmarshal user config", err)
 	}
+	if err := c._uc.Core.ImageVariants.validate(); err != nil {
+		c.panic("invalid Core.ImageVariants config", err)
+	}
 
 	// CLEAN SOURCES
 	c.cleanSources = CleanSources{
@@ -47,6 +55,18 @@ func (c *Config) MainInit(opts MainInitOptions, calledFrom string) {
 	if c._uc.Core.CSSEntryFiles.NonCritical != "" {
 		c.cleanSources.NonCriticalCSSEntry = filepath.Clean(c._uc.Core.CSSEntryFiles.NonCritical)
 	}
+	if len(c._uc.Core.CSSEntryFiles.Named) > 0 {
+		c.cleanSources.NamedCSSEntries = make(map[string]string, len(c._uc.Core.CSSEntryFiles.Named))
+		for name, entry := range c._uc.Core.CSSEntryFiles.Named {
+			if !isValidNamedCSSEntryName(name) {
+				c.panic("invalid CSSEntryFiles.Named name", fmt.Errorf(
+					"%q: names may only contain letters, digits, and dashes, and may not be %q or %q",
+					name, "critical", "normal",
+				))
+			}
+			c.cleanSources.NamedCSSEntries[name] = filepath.Clean(entry)
+		}
+	}
 
 	// DIST LAYOUT
 	c._dist = toDistLayout(c.cleanSources.Dist)
@@ -67,6 +87,8 @@ func (c *Config) MainInit(opts MainInitOptions, calledFrom string) {
 
 	c.cleanWatchRoot = filepath.Clean(c._uc.Watch.WatchRoot)
 
+	go c.refresh_go_dep_graph()
+
 	SetModeToDev()
 
 	// HEALTH CHECK ENDPOINT
@@ -70,7 +70,7 @@ func (c *Config) runConcurrentOnChangeCallbacks(onChanges []OnChangeHook, evtNam
 				continue
 			}
 			eg.Go(func() error {
-				err := executil.RunCmd(strings.Fields(c.resolveCmd(o.Cmd))...)
+				err := c.run_on_change_hook_cmd(c.resolveCmd(o.Cmd))
 				if err != nil {
 					c.Logger.Error(fmt.Sprintf("error running on-change callback: %v", err))
 					return err
@@ -99,7 +99,7 @@ func (c *Config) simpleRunOnChangeCallbacks(onChanges []OnChangeHook, evtName st
 		if c.get_is_ignored(evtName, o.Exclude) {
 			continue
 		}
-		err := executil.RunCmd(strings.Fields(c.resolveCmd(o.Cmd))...)
+		err := c.run_on_change_hook_cmd(c.resolveCmd(o.Cmd))
 		if err != nil {
 			c.Logger.Error(fmt.Sprintf("error running on-change callback: %v", err))
 			return err
@@ -107,3 +107,13 @@ func (c *Config) simpleRunOnChangeCallbacks(onChanges []OnChangeHook, evtName st
 	}
 	return nil
 }
+
+// Runs the command with its output streamed to the terminal as usual, but
+// also captured so that failures can be shown in the browser error overlay.
+func (c *Config) run_on_change_hook_cmd(cmd string) error {
+	output, err := executil.RunCmdCapturingOutput(strings.Fields(cmd)...)
+	if err != nil {
+		c.report_dev_error(new_hook_dev_error(cmd, err, output))
+	}
+	return err
+}
@@ -0,0 +1,209 @@
+package ki
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/river-now/river/kit/middleware/compress"
+	"golang.org/x/sync/errgroup"
+)
+
+const defaultPrecompressionMinSize = 1024
+
+func (c *Config) is_precompression_enabled() bool {
+	p := c._uc.Core.Precompression
+	return p.Brotli || p.Gzip
+}
+
+type precompressedFile struct {
+	relativePath string
+	hasBrotli    bool
+	hasGzip      bool
+}
+
+// Walks the public dist directory (which by now contains Wave's hashed
+// public files, the normal CSS bundle, the public file map module, and any
+// Vite output) and writes brotli and/or gzip siblings for every compressible
+// file at or above the configured size threshold. The results are recorded in
+// the public file map so that the static handler can serve the best variant
+// without touching the file system. Files that Wave did not itself place into
+// the public file map (e.g., Vite chunks) are added as prehashed entries.
+func (c *Config) precompressPublicAssets() error {
+	settings := c._uc.Core.Precompression
+	minSize := settings.MinSize
+	if minSize <= 0 {
+		minSize = defaultPrecompressionMinSize
+	}
+
+	publicOutDir := c.GetStaticPublicOutDir()
+
+	var candidates []string
+	err := filepath.WalkDir(publicOutDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || isPrecompressedSibling(path) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() < int64(minSize) {
+			return nil
+		}
+		if !compress.IsCompressibleContentType(mime.TypeByExtension(filepath.Ext(path))) {
+			return nil
+		}
+		candidates = append(candidates, path)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error walking public dist dir: %w", err)
+	}
+
+	var mu sync.Mutex
+	results := make([]precompressedFile, 0, len(candidates))
+
+	var eg errgroup.Group
+	eg.SetLimit(runtime.NumCPU())
+	for _, path := range candidates {
+		eg.Go(func() error {
+			result, err := precompressFile(path, settings.Brotli, settings.Gzip)
+			if err != nil {
+				return fmt.Errorf("error precompressing %s: %w", path, err)
+			}
+			relativePath, err := filepath.Rel(publicOutDir, path)
+			if err != nil {
+				return err
+			}
+			result.relativePath = filepath.ToSlash(relativePath)
+			mu.Lock()
+			results = append(results, result)
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+
+	fileMap, err := c.loadMapFromGob(PublicFileMapGobName, true)
+	if err != nil {
+		return fmt.Errorf("error loading public file map: %w", err)
+	}
+	keysByVal := make(map[string]string, len(fileMap))
+	for k, v := range fileMap {
+		keysByVal[v.Val] = k
+	}
+	for _, r := range results {
+		if !r.hasBrotli && !r.hasGzip {
+			continue
+		}
+		key, exists := keysByVal[r.relativePath]
+		if !exists {
+			key = r.relativePath
+			fileMap[key] = fileVal{Val: r.relativePath, IsPrehashed: true}
+		}
+		v := fileMap[key]
+		v.HasBrotli = r.hasBrotli
+		v.HasGzip = r.hasGzip
+		fileMap[key] = v
+	}
+
+	if err := c.saveMapToGob(fileMap, PublicFileMapGobName); err != nil {
+		return fmt.Errorf("error saving public file map: %w", err)
+	}
+
+	c.Logger.Info("Precompressed public assets", "candidates", len(candidates))
+
+	return nil
+}
+
+// Siblings are only kept if they are actually smaller than the original.
+func precompressFile(path string, withBrotli, withGzip bool) (precompressedFile, error) {
+	var result precompressedFile
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return result, err
+	}
+	if withBrotli {
+		result.hasBrotli, err = writeCompressedSibling(path, compress.Brotli, original, func(w io.Writer) io.WriteCloser {
+			return brotli.NewWriterLevel(w, brotli.BestCompression)
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+	if withGzip {
+		result.hasGzip, err = writeCompressedSibling(path, compress.Gzip, original, func(w io.Writer) io.WriteCloser {
+			gw, _ := gzip.NewWriterLevel(w, gzip.BestCompression)
+			return gw
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func writeCompressedSibling(
+	path, encoding string, original []byte, newWriter func(io.Writer) io.WriteCloser,
+) (bool, error) {
+	var buf bytes.Buffer
+	w := newWriter(&buf)
+	if _, err := w.Write(original); err != nil {
+		return false, err
+	}
+	if err := w.Close(); err != nil {
+		return false, err
+	}
+	if buf.Len() >= len(original) {
+		return false, nil
+	}
+	if err := os.WriteFile(path+compress.FileExtension(encoding), buf.Bytes(), 0644); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Returns the precompressed encodings recorded in the public file map for the
+// given (already hashed) file name. The second return value is false if the
+// file isn't in the map, in which case the caller should probe for siblings.
+func (c *Config) getPrecompressedVariants(name string) ([]string, bool) {
+	index, err := c.runtime_cache.public_filemap_by_val.Get()
+	if err != nil {
+		return nil, false
+	}
+	v, ok := index[name]
+	if !ok {
+		return nil, false
+	}
+	available := make([]string, 0, 2)
+	if v.HasBrotli {
+		available = append(available, compress.Brotli)
+	}
+	if v.HasGzip {
+		available = append(available, compress.Gzip)
+	}
+	return available, true
+}
+
+func isPrecompressedSibling(path string) bool {
+	for _, encoding := range precompressedEncodings {
+		if strings.HasSuffix(path, compress.FileExtension(encoding)) {
+			return true
+		}
+	}
+	return false
+}
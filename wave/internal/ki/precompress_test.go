@@ -0,0 +1,97 @@
+package ki
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrecompressPublicAssets(t *testing.T) {
+	env := setupTestEnv(t)
+	defer teardownTestEnv(t)
+
+	env.config._uc.Core.Precompression = Precompression{Brotli: true, Gzip: true}
+
+	bigJS := strings.Repeat("console.log('hello world');\n", 200)
+	env.createTestFile(t, "dist/static/assets/public/app_abc123.js", bigJS)
+	env.createTestFile(t, "dist/static/assets/public/river_out_chunk.js", bigJS)
+	env.createTestFile(t, "dist/static/assets/public/small_def456.js", "tiny")
+	env.createTestFile(t, "dist/static/assets/public/image_ghi789.png", strings.Repeat("x", 4096))
+
+	fileMap := FileMap{
+		"app.js":    {Val: "app_abc123.js"},
+		"small.js":  {Val: "small_def456.js"},
+		"image.png": {Val: "image_ghi789.png"},
+	}
+	if err := env.config.saveMapToGob(fileMap, PublicFileMapGobName); err != nil {
+		t.Fatalf("saveMapToGob() error = %v", err)
+	}
+
+	if err := env.config.precompressPublicAssets(); err != nil {
+		t.Fatalf("precompressPublicAssets() error = %v", err)
+	}
+
+	publicDir := filepath.Join(testRootDir, "dist/static/assets/public")
+
+	t.Run("WritesSiblings", func(t *testing.T) {
+		for _, name := range []string{"app_abc123.js.br", "app_abc123.js.gz", "river_out_chunk.js.br", "river_out_chunk.js.gz"} {
+			if _, err := os.Stat(filepath.Join(publicDir, name)); err != nil {
+				t.Errorf("expected %s to exist: %v", name, err)
+			}
+		}
+		for _, name := range []string{"small_def456.js.br", "small_def456.js.gz", "image_ghi789.png.br"} {
+			if _, err := os.Stat(filepath.Join(publicDir, name)); err == nil {
+				t.Errorf("expected %s not to exist", name)
+			}
+		}
+	})
+
+	t.Run("RecordsVariantsInFileMap", func(t *testing.T) {
+		updated, err := env.config.loadMapFromGob(PublicFileMapGobName, true)
+		if err != nil {
+			t.Fatalf("loadMapFromGob() error = %v", err)
+		}
+		if v := updated["app.js"]; !v.HasBrotli || !v.HasGzip || v.IsPrehashed {
+			t.Errorf("app.js = %+v, want brotli and gzip variants", v)
+		}
+		if v := updated["small.js"]; v.HasBrotli || v.HasGzip {
+			t.Errorf("small.js = %+v, want no variants", v)
+		}
+		v, ok := updated["river_out_chunk.js"]
+		if !ok || !v.IsPrehashed || !v.HasBrotli || !v.HasGzip {
+			t.Errorf("river_out_chunk.js = %+v (present: %v), want prehashed entry with variants", v, ok)
+		}
+	})
+
+	t.Run("ServesFromFileMap", func(t *testing.T) {
+		// A stray sibling that the file map doesn't know about should be ignored
+		env.createTestFile(t, "dist/static/assets/public/small_def456.js.br", "stale")
+
+		handler, err := env.config.GetServeStaticHandler(false)
+		if err != nil {
+			t.Fatalf("GetServeStaticHandler() error = %v", err)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/bob/app_abc123.js", nil)
+		req.Header.Set("Accept-Encoding", "br")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Content-Encoding"); got != "br" {
+			t.Errorf("Content-Encoding = %q, want br", got)
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "/bob/small_def456.js", nil)
+		req.Header.Set("Accept-Encoding", "br")
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("Content-Encoding = %q, want none", got)
+		}
+		if rec.Body.String() != "tiny" {
+			t.Errorf("body = %q, want %q", rec.Body.String(), "tiny")
+		}
+	})
+}
@@ -3,7 +3,10 @@ package ki
 import (
 	"fmt"
 	"html/template"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 
 	"github.com/gorilla/websocket"
 	"github.com/river-now/river/kit/bytesutil"
@@ -29,19 +32,29 @@ type client struct {
 type Base64 = string
 
 type refreshFilePayload struct {
-	ChangeType   changeType `json:"changeType"`
-	CriticalCSS  Base64     `json:"criticalCSS"`
-	NormalCSSURL string     `json:"normalCSSURL"`
+	ChangeType   changeType       `json:"changeType"`
+	CriticalCSS  Base64           `json:"criticalCSS"`
+	NormalCSSURL string           `json:"normalCSSURL"`
+	NamedCSS     *namedCSSPayload `json:"namedCSS,omitempty"`
+	Error        *devError        `json:"error,omitempty"`
+}
+
+type namedCSSPayload struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
 }
 
 type changeType string
 
 const (
-	changeTypeNormalCSS   changeType = "normal"
-	changeTypeCriticalCSS changeType = "critical"
-	changeTypeOther       changeType = "other"
-	changeTypeRebuilding  changeType = "rebuilding"
-	changeTypeRevalidate  changeType = "revalidate"
+	changeTypeNormalCSS    changeType = "normal"
+	changeTypeCriticalCSS  changeType = "critical"
+	changeTypeNamedCSS     changeType = "named"
+	changeTypeOther        changeType = "other"
+	changeTypeRebuilding   changeType = "rebuilding"
+	changeTypeRevalidate   changeType = "revalidate"
+	changeTypeError        changeType = "error"
+	changeTypeErrorCleared changeType = "errorcleared"
 )
 
 func newClientManager() *clientManager {
@@ -85,6 +98,16 @@ func (c *Config) GetRefreshScriptSha256Hash() string {
 	return bytesutil.ToBase64(hash)
 }
 
+// Returns the origins the refresh script connects to (its websocket and
+// error reporting endpoint), for use in a Content-Security-Policy.
+func (c *Config) GetRefreshServerOrigins() []string {
+	if !GetIsDev() {
+		return nil
+	}
+	port := strconv.Itoa(getRefreshServerPort())
+	return []string{"http://localhost:" + port, "ws://localhost:" + port}
+}
+
 func (c *Config) GetRefreshScript() template.HTML {
 	if !GetIsDev() {
 		return ""
@@ -100,8 +123,8 @@ func GetRefreshScriptInner(port int) string {
 	return fmt.Sprintf(refreshScriptFmt, port)
 }
 
-// changeTypes: "rebuilding", "other", "normal", "critical", "revalidate"
-// Element IDs: "wave-refreshscript-rebuilding", "wave-normal-css", "wave-critical-css"
+// changeTypes: "rebuilding", "other", "normal", "critical", "named", "revalidate", "error", "errorcleared"
+// Element IDs: "wave-refreshscript-rebuilding", "wave-refreshscript-error", "wave-normal-css", "wave-critical-css", "wave-css-{name}"
 const refreshScriptFmt = `
 function base64ToUTF8(base64) {
 	const bytes = Uint8Array.from(atob(base64), (m) => m.codePointAt(0) || 0);
@@ -110,6 +133,65 @@ function base64ToUTF8(base64) {
 function getCurrentEl() {
 	return document.getElementById("wave-refreshscript-rebuilding");
 }
+function removeErrorOverlay() {
+	document.getElementById("wave-refreshscript-error")?.remove();
+}
+function showErrorOverlay(error) {
+	getCurrentEl()?.remove();
+	removeErrorOverlay();
+	const el = document.createElement("div");
+	el.id = "wave-refreshscript-error";
+	el.style.position = "fixed";
+	el.style.inset = "0";
+	el.style.zIndex = "1001";
+	el.style.overflow = "auto";
+	el.style.padding = "24px";
+	el.style.backgroundColor = "#1a1a1aee";
+	el.style.color = "#eee";
+	el.style.fontFamily = "monospace";
+	el.style.fontSize = "14px";
+	const close = document.createElement("button");
+	close.textContent = "×";
+	close.style.float = "right";
+	close.style.fontSize = "24px";
+	close.style.background = "none";
+	close.style.border = "none";
+	close.style.color = "inherit";
+	close.style.cursor = "pointer";
+	close.onclick = removeErrorOverlay;
+	el.appendChild(close);
+	const title = document.createElement("div");
+	title.textContent = error.title;
+	title.style.color = "#ff6b6b";
+	title.style.fontSize = "18px";
+	title.style.fontWeight = "bold";
+	title.style.marginBottom = "16px";
+	el.appendChild(title);
+	for (const loc of error.locations || []) {
+		const heading = document.createElement("div");
+		heading.textContent = loc.file + ":" + loc.line + (loc.column ? ":" + loc.column : "") + (loc.message ? "  " + loc.message : "");
+		heading.style.color = "#ffd166";
+		heading.style.marginTop = "12px";
+		el.appendChild(heading);
+		if (loc.snippet) {
+			const snippet = document.createElement("pre");
+			snippet.textContent = loc.snippet;
+			snippet.style.backgroundColor = "#000";
+			snippet.style.padding = "8px";
+			snippet.style.margin = "4px 0";
+			el.appendChild(snippet);
+		}
+	}
+	if (error.output) {
+		const output = document.createElement("pre");
+		output.textContent = error.output;
+		output.style.marginTop = "16px";
+		output.style.whiteSpace = "pre-wrap";
+		output.style.color = "#aaa";
+		el.appendChild(output);
+	}
+	document.body.appendChild(el);
+}
 const scrollYKey = "__wave_internal__devScrollY";
 const scrollY = sessionStorage.getItem(scrollYKey);
 if (scrollY) {
@@ -121,7 +203,16 @@ if (scrollY) {
 }
 const ws = new WebSocket("ws://localhost:%d/events");
 ws.onmessage = (e) => {
-	const { changeType, criticalCSS, normalCSSURL } = JSON.parse(e.data);
+	const { changeType, criticalCSS, normalCSSURL, namedCSS, error } = JSON.parse(e.data);
+	if (changeType == "error") {
+		console.error("Wave: " + error.title + "\n" + error.output);
+		showErrorOverlay(error);
+		return;
+	}
+	if (changeType == "errorcleared") {
+		removeErrorOverlay();
+		return;
+	}
 	if (changeType == "rebuilding") {
 		console.log("Wave: Rebuilding server...");
 		const currentEl = getCurrentEl();
@@ -168,6 +259,17 @@ ws.onmessage = (e) => {
 		newLink.onload = () => oldLink.remove();
 		oldLink.parentNode.insertBefore(newLink, oldLink.nextSibling);
 	}
+	if (changeType == "named") {
+		const oldLink = document.getElementById("wave-css-" + namedCSS.name);
+		if (oldLink) {
+			const newLink = document.createElement("link");
+			newLink.id = oldLink.id;
+			newLink.rel = "stylesheet";
+			newLink.href = namedCSS.url;
+			newLink.onload = () => oldLink.remove();
+			oldLink.parentNode.insertBefore(newLink, oldLink.nextSibling);
+		}
+	}
 	if (changeType == "critical") {
 		const oldStyle = document.getElementById("wave-critical-css");
 		const newStyle = document.createElement("style");
@@ -207,13 +309,32 @@ window.addEventListener("beforeunload", () => {
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
+	// Dev error payloads include source snippets, so only pages served from
+	// this machine may connect.
 	CheckOrigin: func(r *http.Request) bool {
-		// Allow all connections
-		return true
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+		return is_loopback_host(u.Hostname())
 	},
 }
 
-func websocketHandler(manager *clientManager) http.HandlerFunc {
+func is_loopback_host(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// If getLastError returns an error, it is sent to the client right away so
+// that tabs opened (or reloaded) while the build is broken still see it.
+func websocketHandler(manager *clientManager, getLastError func() *devError) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
@@ -225,6 +346,13 @@ func websocketHandler(manager *clientManager) http.HandlerFunc {
 		client := &client{id: r.RemoteAddr, conn: conn, notify: msg}
 		manager.register <- client
 
+		if lastError := getLastError(); lastError != nil {
+			if err := conn.WriteJSON(refreshFilePayload{ChangeType: changeTypeError, Error: lastError}); err != nil {
+				manager.unregister <- client
+				return
+			}
+		}
+
 		defer func() {
 			manager.unregister <- client
 		}()
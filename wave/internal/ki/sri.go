@@ -0,0 +1,85 @@
+package ki
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/river-now/river/kit/matcher"
+)
+
+// Hashed public files get their SRI values as they are processed. This adds
+// entries for the files that other steps write straight into the public dist
+// directory without going through the file map (Vite output, the normal and
+// named CSS bundles, and the public file map module itself), so that every
+// public asset has an SRI value available at runtime. Entries are keyed by
+// their relative path and marked as prehashed, since that path is already the
+// served name. The client-side public file map module is written before this
+// runs, so these entries only ever land in the gob map; IsPrehashed also keeps
+// them out of GetPublicFileMapKeysBuildtime and GetSimplePublicFileMapBuildtime.
+func (c *Config) addSRIForUnmappedPublicAssets() error {
+	fileMap, err := c.loadMapFromGob(PublicFileMapGobName, true)
+	if err != nil {
+		return fmt.Errorf("error loading public file map: %w", err)
+	}
+	mappedVals := make(map[string]struct{}, len(fileMap))
+	for _, v := range fileMap {
+		mappedVals[v.Val] = struct{}{}
+	}
+
+	publicOutDir := c.GetStaticPublicOutDir()
+
+	err = filepath.WalkDir(publicOutDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || isPrecompressedSibling(path) {
+			return nil
+		}
+		relativePath, err := filepath.Rel(publicOutDir, path)
+		if err != nil {
+			return err
+		}
+		relativePath = filepath.ToSlash(relativePath)
+		if _, isMapped := mappedVals[relativePath]; isMapped {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fileMap[relativePath] = fileVal{
+			Val:         relativePath,
+			IsPrehashed: true,
+			SRI:         getSRI(content),
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error walking public dist dir: %w", err)
+	}
+
+	if err := c.saveMapToGob(fileMap, PublicFileMapGobName); err != nil {
+		return fmt.Errorf("error saving public file map: %w", err)
+	}
+
+	return nil
+}
+
+// Returns the Subresource Integrity value (e.g., "sha256-...") recorded at
+// build time for a public asset, given its hashed URL, with or without the
+// public URL base (e.g., "/public/app_abc123.js",
+// "https://cdn.example.com/app/app_abc123.js", or "app_abc123.js"). Returns
+// an empty string if the asset is unknown.
+func (c *Config) GetPublicSRI(hashedPublicURL string) string {
+	index, err := c.runtime_cache.public_filemap_by_val.Get()
+	if err != nil {
+		return ""
+	}
+	if base := c._uc.Core.AssetBaseURL; base != "" {
+		hashedPublicURL = strings.TrimPrefix(hashedPublicURL, matcher.EnsureTrailingSlash(base))
+	}
+	return index[cleanURL(strings.TrimPrefix(hashedPublicURL, c._uc.Core.PublicPathPrefix))].SRI
+}
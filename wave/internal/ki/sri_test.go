@@ -0,0 +1,80 @@
+package ki
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSRI(t *testing.T) {
+	env := setupTestEnv(t)
+	defer teardownTestEnv(t)
+
+	env.createTestFile(t, "public-static/app.js", "console.log('app');")
+	env.createTestFile(t, "private-static/secret.txt", "secret")
+
+	if err := env.config.handlePublicFiles(false); err != nil {
+		t.Fatalf("handlePublicFiles() error = %v", err)
+	}
+	if err := env.config.copyPrivateFiles(false); err != nil {
+		t.Fatalf("copyPrivateFiles() error = %v", err)
+	}
+
+	// Written directly to the public dist dir, as Vite output and the
+	// normal CSS bundle are
+	env.createTestFile(t, "dist/static/assets/public/river_out_chunk_abc.js", "export {};")
+	env.createTestFile(t, "dist/static/assets/public/normal_def456.css", "body{color:red}")
+	env.createTestFile(t, "dist/static/internal/normal_css_file_ref.txt", "normal_def456.css")
+
+	if err := env.config.addSRIForUnmappedPublicAssets(); err != nil {
+		t.Fatalf("addSRIForUnmappedPublicAssets() error = %v", err)
+	}
+
+	fileMap, err := env.config.loadMapFromGob(PublicFileMapGobName, true)
+	if err != nil {
+		t.Fatalf("loadMapFromGob() error = %v", err)
+	}
+
+	t.Run("HashedPublicFiles", func(t *testing.T) {
+		v := fileMap["app.js"]
+		if want := getSRI([]byte("console.log('app');")); v.SRI != want {
+			t.Errorf("SRI = %q, want %q", v.SRI, want)
+		}
+		if got := env.config.GetPublicSRI("/bob/" + v.Val); got != v.SRI {
+			t.Errorf("GetPublicSRI(with prefix) = %q, want %q", got, v.SRI)
+		}
+		if got := env.config.GetPublicSRI(v.Val); got != v.SRI {
+			t.Errorf("GetPublicSRI(without prefix) = %q, want %q", got, v.SRI)
+		}
+	})
+
+	t.Run("UnmappedPublicFiles", func(t *testing.T) {
+		v, ok := fileMap["river_out_chunk_abc.js"]
+		if !ok || !v.IsPrehashed {
+			t.Fatalf("expected prehashed entry for Vite chunk, got %+v", v)
+		}
+		if got, want := env.config.GetPublicSRI("river_out_chunk_abc.js"), getSRI([]byte("export {};")); got != want {
+			t.Errorf("GetPublicSRI() = %q, want %q", got, want)
+		}
+		simple, err := env.config.GetSimplePublicFileMapBuildtime()
+		if err != nil {
+			t.Fatalf("GetSimplePublicFileMapBuildtime() error = %v", err)
+		}
+		if _, leaked := simple["river_out_chunk_abc.js"]; leaked {
+			t.Errorf("prehashed SRI entries should not appear in the client-side map")
+		}
+	})
+
+	t.Run("StyleSheetLinkElement", func(t *testing.T) {
+		el := string(env.config.GetStyleSheetLinkElement())
+		want := `integrity="` + getSRI([]byte("body{color:red}")) + `" crossorigin="anonymous"`
+		if !strings.Contains(el, want) {
+			t.Errorf("link element = %s, want it to contain %s", el, want)
+		}
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		if got := env.config.GetPublicSRI("nope.js"); got != "" {
+			t.Errorf("GetPublicSRI(unknown) = %q, want empty", got)
+		}
+	})
+}
@@ -2,17 +2,32 @@ package ki
 
 import (
 	"fmt"
+	"io"
 	"io/fs"
+	"mime"
 	"net/http"
 	"path"
+	"slices"
 	"strings"
 
-	"github.com/river-now/river/kit/matcher"
+	"github.com/river-now/river/kit/middleware/compress"
 )
 
 type fileVal struct {
 	Val         string
 	IsPrehashed bool
+	// Set at prod build time if a precompressed sibling
+	// (Val + ".br" / Val + ".gz") was written to dist.
+	HasBrotli bool
+	HasGzip   bool
+	// Subresource Integrity value (e.g., "sha256-..."). Set for hashed
+	// public files and, at prod build time, for Vite output.
+	SRI string
+	// Set for public images when Core.ImageVariants is configured. For
+	// generated variants, VariantOf is the original's file map key.
+	Width     int
+	VariantOf string
+	Format    string
 }
 
 type FileMap map[string]fileVal
@@ -24,13 +39,116 @@ func (c *Config) GetServeStaticHandler(addImmutableCacheHeaders bool) (http.Hand
 		c.Logger.Error(wrapped.Error())
 		return nil, wrapped
 	}
+	handler := http.StripPrefix(c.GetPublicPathPrefix(), newStaticFileHandler(publicFS, c.getPrecompressedVariants))
+	if c.use_asset_base_url() {
+		handler = withAssetCORS(handler, c._uc.Core.AssetCORSOrigins)
+	}
 	if addImmutableCacheHeaders {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
-			http.StripPrefix(c.GetPublicPathPrefix(), http.FileServer(http.FS(publicFS))).ServeHTTP(w, r)
+			handler.ServeHTTP(w, r)
 		}), nil
 	}
-	return http.StripPrefix(c.GetPublicPathPrefix(), http.FileServer(http.FS(publicFS))), nil
+	return handler, nil
+}
+
+// Assets served via AssetBaseURL are cross-origin to your pages (and carry
+// integrity attributes with crossorigin="anonymous"), so browsers won't use
+// them without CORS headers. With no allowed origins, any origin may read
+// them; otherwise an allowed Origin is echoed back.
+func withAssetCORS(next http.Handler, allowedOrigins []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(allowedOrigins) == 0 {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			// The response depends on Origin, so caches (including the CDN)
+			// must key on it.
+			w.Header().Add("Vary", "Origin")
+			if origin := r.Header.Get("Origin"); origin != "" && slices.Contains(allowedOrigins, origin) {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Encodings for which precompressed siblings (e.g., "app.js.br") are looked
+// up, in order of server preference.
+var precompressedEncodings = []string{compress.Brotli, compress.Zstd, compress.Gzip}
+
+// Wraps a file server so that, if a precompressed sibling of the requested
+// file exists and the client accepts its encoding, the sibling is served
+// instead (with the original file's content type). If lookupVariants knows
+// about the requested file (i.e., it was precompressed at build time), its
+// answer is trusted; otherwise the file system is probed for siblings.
+func newStaticFileHandler(
+	publicFS fs.FS, lookupVariants func(name string) ([]string, bool),
+) http.Handler {
+	fileServer := http.FileServer(http.FS(publicFS))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			if servePrecompressed(w, r, publicFS, lookupVariants) {
+				return
+			}
+		}
+		fileServer.ServeHTTP(w, r)
+	})
+}
+
+func servePrecompressed(
+	w http.ResponseWriter, r *http.Request, publicFS fs.FS, lookupVariants func(string) ([]string, bool),
+) bool {
+	name := cleanURL(r.URL.Path)
+	if name == "" || name == "." || strings.HasSuffix(r.URL.Path, "/") {
+		return false
+	}
+	if lookupVariants != nil {
+		if available, ok := lookupVariants(name); ok {
+			if len(available) == 0 {
+				return false
+			}
+			return serveEncodedVariant(w, r, publicFS, name, available)
+		}
+	}
+	available := make([]string, 0, len(precompressedEncodings))
+	for _, encoding := range precompressedEncodings {
+		if info, err := fs.Stat(publicFS, name+compress.FileExtension(encoding)); err == nil && !info.IsDir() {
+			available = append(available, encoding)
+		}
+	}
+	if len(available) == 0 {
+		return false
+	}
+	return serveEncodedVariant(w, r, publicFS, name, available)
+}
+
+func serveEncodedVariant(w http.ResponseWriter, r *http.Request, publicFS fs.FS, name string, available []string) bool {
+	w.Header().Add("Vary", "Accept-Encoding")
+	encoding := compress.Negotiate(r.Header.Get("Accept-Encoding"), available)
+	if encoding == "" {
+		return false
+	}
+	f, err := publicFS.Open(name + compress.FileExtension(encoding))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		return false
+	}
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Encoding", encoding)
+	http.ServeContent(w, r, name, info.ModTime(), rs)
+	return true
 }
 
 func (c *Config) getInitialPublicFileMapFromGobBuildtime() (FileMap, error) {
@@ -61,18 +179,33 @@ func (c *Config) MustGetPublicURLBuildtime(originalPublicURL string) string {
 	return url
 }
 
+// Like MustGetPublicURLBuildtime, but returns the hashed path relative to the
+// public assets root, without the public URL base.
+func (c *Config) mustGetPublicPathBuildtime(originalPublicURL string) string {
+	fileMapFromGob, err := c.getInitialPublicFileMapFromGobBuildtime()
+	if err != nil {
+		c.Logger.Error(fmt.Sprintf(
+			"error getting public file map from gob (buildtime) for originalPublicURL %s: %v", originalPublicURL, err,
+		))
+		panic(err)
+	}
+	if hashedURL, existsInFileMap := fileMapFromGob[cleanURL(originalPublicURL)]; existsInFileMap {
+		return strings.TrimPrefix(hashedURL.Val, "/")
+	}
+	c.Logger.Info(fmt.Sprintf(
+		"GetPublicURL: no hashed URL found for %s, returning original URL",
+		originalPublicURL,
+	))
+	return cleanURL(originalPublicURL)
+}
+
 func (c *Config) getInitialPublicURL(originalPublicURL string) (string, error) {
 	fileMapFromGob, err := c.runtime_cache.public_filemap_from_gob.Get()
 	if err != nil {
 		c.Logger.Error(fmt.Sprintf(
 			"error getting public file map from gob for originalPublicURL %s: %v", originalPublicURL, err,
 		))
-		return matcher.EnsureLeadingSlash(
-			path.Join(
-				c._uc.Core.PublicPathPrefix,
-				originalPublicURL,
-			),
-		), err
+		return c.to_public_asset_url(originalPublicURL), err
 	}
 
 	return c.getInitialPublicURLInner(originalPublicURL, fileMapFromGob)
@@ -84,9 +217,7 @@ func (c *Config) getInitialPublicURLInner(originalPublicURL string, fileMapFromG
 	}
 
 	if hashedURL, existsInFileMap := fileMapFromGob[cleanURL(originalPublicURL)]; existsInFileMap {
-		return matcher.EnsureLeadingSlash(
-			path.Join(c._uc.Core.PublicPathPrefix, hashedURL.Val),
-		), nil
+		return c.to_public_asset_url(hashedURL.Val), nil
 	}
 
 	// If no hashed URL found, return the original URL
@@ -95,9 +226,7 @@ func (c *Config) getInitialPublicURLInner(originalPublicURL string, fileMapFromG
 		originalPublicURL,
 	))
 
-	return matcher.EnsureLeadingSlash(
-		path.Join(c._uc.Core.PublicPathPrefix, originalPublicURL),
-	), nil
+	return c.to_public_asset_url(originalPublicURL), nil
 }
 
 func publicURLsKeyMaker(x string) string { return x }
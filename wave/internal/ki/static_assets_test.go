@@ -0,0 +1,171 @@
+package ki
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestServeStaticPrecompressed(t *testing.T) {
+	env := setupTestEnv(t)
+	defer teardownTestEnv(t)
+
+	env.createTestFile(t, "dist/static/assets/public/app.js", "raw")
+	env.createTestFile(t, "dist/static/assets/public/app.js.br", "brotli")
+	env.createTestFile(t, "dist/static/assets/public/app.js.gz", "gzip")
+	env.createTestFile(t, "dist/static/assets/public/plain.txt", "plain")
+
+	handler, err := env.config.GetServeStaticHandler(true)
+	if err != nil {
+		t.Fatalf("GetServeStaticHandler() error = %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		path             string
+		acceptEncoding   string
+		expectedBody     string
+		expectedEncoding string
+		expectVary       bool
+	}{
+		{"prefers brotli", "/bob/app.js", "gzip, br", "brotli", "br", true},
+		{"falls back to gzip", "/bob/app.js", "gzip", "gzip", "gzip", true},
+		{"identity when nothing accepted", "/bob/app.js", "", "raw", "", true},
+		{"no variants", "/bob/plain.txt", "br", "plain", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rec.Code)
+			}
+			if rec.Body.String() != tt.expectedBody {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.expectedBody)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != tt.expectedEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.expectedEncoding)
+			}
+			if got := rec.Header().Get("Vary") == "Accept-Encoding"; got != tt.expectVary {
+				t.Errorf("Vary: Accept-Encoding present = %v, want %v", got, tt.expectVary)
+			}
+			if tt.expectedEncoding != "" && rec.Header().Get("Content-Type") != "text/javascript; charset=utf-8" {
+				t.Errorf("Content-Type = %q, want original file's type", rec.Header().Get("Content-Type"))
+			}
+			if rec.Header().Get("Cache-Control") != "public, max-age=31536000, immutable" {
+				t.Errorf("expected immutable cache headers")
+			}
+		})
+	}
+}
+
+func TestAssetBaseURL(t *testing.T) {
+	env := setupTestEnv(t)
+	defer teardownTestEnv(t)
+
+	env.config._uc.Core.AssetBaseURL = "https://cdn.example.com/app"
+
+	env.createTestFile(t, "public-static/app.js", "console.log('app');")
+	if err := env.config.handlePublicFiles(false); err != nil {
+		t.Fatalf("handlePublicFiles() error = %v", err)
+	}
+
+	hashed := env.config.GetPublicURL("app.js")
+	if !strings.HasPrefix(hashed, "https://cdn.example.com/app/app_") || !strings.HasSuffix(hashed, ".js") {
+		t.Errorf("GetPublicURL() = %q, want a CDN URL", hashed)
+	}
+	if got := env.config.GetPublicURLBase(); got != "https://cdn.example.com/app/" {
+		t.Errorf("GetPublicURLBase() = %q", got)
+	}
+	if got := env.config.GetPublicSRI(hashed); got == "" {
+		t.Error("GetPublicSRI() should accept CDN URLs")
+	}
+
+	t.Run("StillServedLocally", func(t *testing.T) {
+		handler, err := env.config.GetServeStaticHandler(true)
+		if err != nil {
+			t.Fatalf("GetServeStaticHandler() error = %v", err)
+		}
+		rec := httptest.NewRecorder()
+		localPath := "/bob/" + strings.TrimPrefix(hashed, "https://cdn.example.com/app/")
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, localPath, nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != "console.log('app');" {
+			t.Errorf("GET %s = %d %q", localPath, rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("CORS", func(t *testing.T) {
+		localPath := "/bob/" + strings.TrimPrefix(hashed, "https://cdn.example.com/app/")
+		crossOriginGet := func(t *testing.T, origin string) *httptest.ResponseRecorder {
+			t.Helper()
+			handler, err := env.config.GetServeStaticHandler(true)
+			if err != nil {
+				t.Fatalf("GetServeStaticHandler() error = %v", err)
+			}
+			req := httptest.NewRequest(http.MethodGet, localPath, nil)
+			req.Header.Set("Origin", origin)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("GET %s = %d", localPath, rec.Code)
+			}
+			return rec
+		}
+
+		t.Run("AnyOrigin", func(t *testing.T) {
+			rec := crossOriginGet(t, "https://example.com")
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+				t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+			}
+			if got := rec.Header().Values("Vary"); slices.Contains(got, "Origin") {
+				t.Errorf("Vary = %v, should not vary on Origin for *", got)
+			}
+		})
+
+		t.Run("AllowedOrigins", func(t *testing.T) {
+			env.config._uc.Core.AssetCORSOrigins = []string{"https://example.com"}
+			defer func() { env.config._uc.Core.AssetCORSOrigins = nil }()
+
+			rec := crossOriginGet(t, "https://example.com")
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://example.com" {
+				t.Errorf("Access-Control-Allow-Origin = %q, want the request origin", got)
+			}
+			if got := rec.Header().Values("Vary"); !slices.Contains(got, "Origin") {
+				t.Errorf("Vary = %v, want Origin", got)
+			}
+
+			rec = crossOriginGet(t, "https://evil.example")
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+				t.Errorf("Access-Control-Allow-Origin = %q for a disallowed origin", got)
+			}
+			if got := rec.Header().Values("Vary"); !slices.Contains(got, "Origin") {
+				t.Errorf("Vary = %v, want Origin", got)
+			}
+		})
+
+		t.Run("NotSetInDev", func(t *testing.T) {
+			t.Setenv(modeKey, devModeVal)
+			if got := crossOriginGet(t, "https://example.com").Header().Get("Access-Control-Allow-Origin"); got != "" {
+				t.Errorf("Access-Control-Allow-Origin = %q in dev", got)
+			}
+		})
+	})
+
+	t.Run("IgnoredInDev", func(t *testing.T) {
+		t.Setenv(modeKey, devModeVal)
+		if got := env.config.GetPublicURLBase(); got != "/bob/" {
+			t.Errorf("GetPublicURLBase() = %q, want %q", got, "/bob/")
+		}
+		if got := env.config.to_public_asset_url("x.js"); got != "/bob/x.js" {
+			t.Errorf("to_public_asset_url() = %q", got)
+		}
+	})
+}
@@ -10,11 +10,17 @@ import (
 )
 
 type (
-	Wave        struct{ c *Config }
-	Config      = ki.Config
-	FileMap     = ki.FileMap
-	WatchedFile = ki.WatchedFile
-	OnChangeCmd = ki.OnChangeHook
+	Wave           struct{ c *Config }
+	Config         = ki.Config
+	FileMap        = ki.FileMap
+	WatchedFile    = ki.WatchedFile
+	OnChangeCmd    = ki.OnChangeHook
+	CSSTransformer = ki.CSSTransformer
+	ConfigProblem  = ki.ConfigProblem
+
+	AssetManifest      = ki.AssetManifest
+	AssetManifestEntry = ki.AssetManifestEntry
+	AssetManifestDiff  = ki.AssetManifestDiff
 )
 
 const (
@@ -29,6 +35,12 @@ var (
 	MustGetPort  = ki.MustGetAppPort
 	GetIsDev     = ki.GetIsDev
 	SetModeToDev = ki.SetModeToDev
+	CheckConfig  = ki.CheckConfig
+	GetEnv       = ki.GetEnv
+
+	ReadAssetManifest  = ki.ReadAssetManifest
+	LoadAssetManifest  = ki.LoadAssetManifest
+	DiffAssetManifests = ki.DiffAssetManifests
 )
 
 func New(c *ki.Config) *Wave {
@@ -70,24 +82,54 @@ func (k Wave) MustGetPrivateFS() fs.FS {
 func (k Wave) GetPublicURL(originalPublicURL string) string {
 	return k.c.GetPublicURL(originalPublicURL)
 }
+func (k Wave) GetPublicSRI(hashedPublicURL string) string {
+	return k.c.GetPublicSRI(hashedPublicURL)
+}
+func (k Wave) GetPublicSrcSet(originalPublicURL string) string {
+	return k.c.GetPublicSrcSet(originalPublicURL)
+}
+func (k Wave) GetPublicSrcSetForFormat(originalPublicURL, format string) string {
+	return k.c.GetPublicSrcSetForFormat(originalPublicURL, format)
+}
 func (k Wave) MustGetPublicURLBuildtime(originalPublicURL string) string {
 	return k.c.MustGetPublicURLBuildtime(originalPublicURL)
 }
 func (k Wave) MustStartDev() {
 	k.c.MustStartDev()
 }
+func (k Wave) ResolveCSSURLs(css string) string {
+	return k.c.ResolveCSSURLs(css)
+}
 func (k Wave) GetCriticalCSS() template.CSS {
 	return template.CSS(k.c.GetCriticalCSS())
 }
 func (k Wave) GetStyleSheetURL() string {
 	return k.c.GetStyleSheetURL()
 }
+func (k Wave) GetStyleSheetPathBuildtime() (string, error) {
+	return k.c.GetStyleSheetPathBuildtime()
+}
+func (k Wave) GetStyleSheetURLFor(name string) string {
+	return k.c.GetStyleSheetURLFor(name)
+}
+func (k Wave) GetStyleSheetLinkElementFor(name string) template.HTML {
+	return k.c.GetStyleSheetLinkElementFor(name)
+}
+func (k Wave) GetCSSModuleClass(file, class string) string {
+	return k.c.GetCSSModuleClass(file, class)
+}
+func (k Wave) GetCSSModuleClasses(file string) map[string]string {
+	return k.c.GetCSSModuleClasses(file)
+}
 func (k Wave) GetRefreshScript() template.HTML {
 	return template.HTML(k.c.GetRefreshScript())
 }
 func (k Wave) GetRefreshScriptSha256Hash() string {
 	return k.c.GetRefreshScriptSha256Hash()
 }
+func (k Wave) GetRefreshServerOrigins() []string {
+	return k.c.GetRefreshServerOrigins()
+}
 func (k Wave) GetCriticalCSSElementID() string {
 	return ki.CriticalCSSElementID
 }
@@ -150,6 +192,12 @@ func (k Wave) GetPublicStaticDir() string {
 func (k Wave) GetPublicPathPrefix() string {
 	return k.c.GetPublicPathPrefix()
 }
+func (k Wave) GetPublicURLBase() string {
+	return k.c.GetPublicURLBase()
+}
+func (k Wave) GetAssetManifestPath() string {
+	return k.c.GetAssetManifestPath()
+}
 func (k Wave) ViteProdBuild() error {
 	return k.c.ViteProdBuild()
 }
@@ -180,6 +228,9 @@ func (k Wave) GetRiverTSGenOutPath() string {
 func (k Wave) GetRiverBuildtimePublicURLFuncName() string {
 	return k.c.GetRiverBuildtimePublicURLFuncName()
 }
+func (k Wave) GetRiverExtractCriticalCSS() bool {
+	return k.c.GetRiverExtractCriticalCSS()
+}
 func (k Wave) GetConfigFile() string {
 	return k.c.GetConfigFile()
 }
@@ -200,3 +251,6 @@ func (k Wave) GetStaticPublicOutDir() string {
 func (k Wave) FaviconRedirect() middleware.Middleware {
 	return k.c.FaviconRedirect()
 }
+func (k Wave) ReportPanicsToDevOverlay() middleware.Middleware {
+	return k.c.ReportPanicsToDevOverlay()
+}